@@ -6,6 +6,7 @@ import (
 	// Frameworks
 	gopi "github.com/djthorpe/gopi"
 	rpc "github.com/djthorpe/gopi-rpc"
+	mihome "github.com/djthorpe/sensors/sys/mihome"
 
 	// Modules
 	_ "github.com/djthorpe/gopi-hw/sys/gpio"
@@ -17,8 +18,8 @@ import (
 	_ "github.com/djthorpe/sensors/protocol/ook"
 	_ "github.com/djthorpe/sensors/protocol/openthings"
 	_ "github.com/djthorpe/sensors/sys/ener314rt"
-	_ "github.com/djthorpe/sensors/sys/mihome"
 	_ "github.com/djthorpe/sensors/sys/rfm69"
+	_ "github.com/djthorpe/sensors/sys/schedule"
 
 	// RPC Services
 	_ "github.com/djthorpe/sensors/rpc/grpc/mihome"
@@ -27,8 +28,14 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 
 func main() {
+	// Receive from a capture file instead of the radio when replaying
+	service := "rpc/mihome:service"
+	if mihome.IsReplay(os.Args[1:]) {
+		service = "rpc/mihome:service/replay"
+	}
+
 	// Create the configuration
-	config := gopi.NewAppConfig("rpc/mihome:service", "sensors/protocol/ook", "sensors/protocol/openthings", "discovery")
+	config := gopi.NewAppConfig(service, "sensors/protocol/ook", "sensors/protocol/openthings", "discovery")
 
 	// Set subtype
 	config.AppFlags.SetParam(gopi.PARAM_SERVICE_SUBTYPE, "mihome")
@@ -18,6 +18,7 @@ import (
 	// Frameworks
 	"github.com/djthorpe/gopi"
 	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/mihome"
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -33,6 +34,7 @@ var (
 	}
 	command_queue = make(chan CommandFunc, 100)
 	regexp_sensor = regexp.MustCompile("^(\\w+):([0-9A-Fa-f]+:[0-9A-Fa-f]+)$")
+	mihome_module = "sensors/mihome"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -156,7 +158,7 @@ func ActionClicker(app *gopi.AppInstance, sensor sensors.Sensor, message sensors
 
 // Switch a sensor on
 func CommandOn(app *gopi.AppInstance, sensor sensors.Sensor) error {
-	if mihome := app.ModuleInstance("sensors/mihome").(sensors.MiHome); mihome == nil {
+	if mihome := app.ModuleInstance(mihome_module).(sensors.MiHome); mihome == nil {
 		return fmt.Errorf("Missing mihome device")
 	} else if err := mihome.RequestSwitchOn(sensors.MiHomeProduct(sensor.Product()), sensor.Sensor()); err != nil {
 		return err
@@ -166,7 +168,7 @@ func CommandOn(app *gopi.AppInstance, sensor sensors.Sensor) error {
 
 // Switch a sensor off
 func CommandOff(app *gopi.AppInstance, sensor sensors.Sensor) error {
-	if mihome := app.ModuleInstance("sensors/mihome").(sensors.MiHome); mihome == nil {
+	if mihome := app.ModuleInstance(mihome_module).(sensors.MiHome); mihome == nil {
 		return fmt.Errorf("Missing mihome device")
 	} else if err := mihome.RequestSwitchOff(sensors.MiHomeProduct(sensor.Product()), sensor.Sensor()); err != nil {
 		return err
@@ -323,7 +325,7 @@ func ProcessEvent(app *gopi.AppInstance, evt gopi.Event) error {
 
 func Receive(app *gopi.AppInstance, start chan<- struct{}, stop <-chan struct{}) error {
 	// Reset the mihome device
-	mihome := app.ModuleInstance("sensors/mihome").(sensors.MiHome)
+	mihome := app.ModuleInstance(mihome_module).(sensors.MiHome)
 	if mihome == nil {
 		return gopi.ErrAppError
 	}
@@ -371,8 +373,13 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 ////////////////////////////////////////////////////////////////////////////////
 
 func main() {
+	// Receive from a capture file instead of the radio when replaying
+	if mihome.IsReplay(os.Args[1:]) {
+		mihome_module = "sensors/mihome/replay"
+	}
+
 	// Create the configuration
-	config := gopi.NewAppConfig("sensors/mihome", "sensors/protocol/ook", "sensors/protocol/openthings", "sensors/db")
+	config := gopi.NewAppConfig(mihome_module, "sensors/protocol/ook", "sensors/protocol/openthings", "sensors/db")
 
 	// Run the command line tool
 	os.Exit(gopi.CommandLineTool2(config, Main, Receive))
@@ -10,8 +10,10 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
 
@@ -21,15 +23,27 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
+const (
+	// RSSI_FLOOR is the lowest RSSI value shown in the Scan bar graph
+	RSSI_FLOOR = -120
+)
+
 var (
 	command_map = map[string]func(app *gopi.AppInstance, device sensors.RFM69) error{
 		"TriggerAFC":      TriggerAFC,
+		"ClearAFC":        ClearAFC,
+		"ReadFEI":         ReadFEI,
+		"CalibrateRCOsc":  CalibrateRCOsc,
 		"ClearFIFO":       ClearFIFO,
 		"ReadFIFO":        ReadFIFO,
 		"ReadPayload":     ReadPayload,
 		"WritePayload":    WritePayload,
 		"Status":          Status,
 		"ReadTemperature": ReadTemperature,
+		"Save":            Save,
+		"Load":            Load,
+		"Diff":            Diff,
+		"Scan":            Scan,
 	}
 )
 
@@ -51,6 +65,44 @@ func TriggerAFC(app *gopi.AppInstance, device sensors.RFM69) error {
 	return device.TriggerAFC()
 }
 
+func ClearAFC(app *gopi.AppInstance, device sensors.RFM69) error {
+	return device.ClearAFC()
+}
+
+func CalibrateRCOsc(app *gopi.AppInstance, device sensors.RFM69) error {
+	// Put into Standby mode
+	if device.Mode() != sensors.RFM_MODE_STDBY {
+		if err := device.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+			return err
+		}
+	}
+	return device.CalibrateRCOsc()
+}
+
+func ReadFEI(app *gopi.AppInstance, device sensors.RFM69) error {
+	// Put into RX mode
+	if device.Mode() != sensors.RFM_MODE_RX {
+		if err := device.SetMode(sensors.RFM_MODE_RX); err != nil {
+			return err
+		}
+	}
+
+	if hertz, err := device.ReadFEIHertz(); err != nil {
+		return err
+	} else {
+		// Output register information
+		table := tablewriter.NewWriter(os.Stdout)
+
+		table.SetHeader([]string{"Parameter", "Value"})
+		table.Append([]string{"fei", fmt.Sprintf("%.0f Hz", hertz)})
+
+		table.Render()
+	}
+
+	// Success
+	return nil
+}
+
 func ClearFIFO(app *gopi.AppInstance, device sensors.RFM69) error {
 	return device.ClearFIFO()
 }
@@ -117,7 +169,7 @@ func ReadPayload(app *gopi.AppInstance, device sensors.RFM69) error {
 	// Read payload
 	timeout, _ := app.AppFlags.GetDuration("timeout")
 	ctx, _ := context.WithTimeout(context.Background(), timeout)
-	if data, crc_ok, err := device.ReadPayload(ctx); err != nil {
+	if data, crc_ok, signal, err := device.ReadPayload(ctx); err != nil {
 		return err
 	} else if data == nil {
 		return fmt.Errorf("Timeout waiting for payload")
@@ -128,6 +180,10 @@ func ReadPayload(app *gopi.AppInstance, device sensors.RFM69) error {
 		table.SetHeader([]string{"Payload", "Value"})
 		table.Append([]string{"payload", fmt.Sprintf("%v", strings.ToUpper(hex.EncodeToString(data)))})
 		table.Append([]string{"crc_ok", fmt.Sprintf("%v", crc_ok)})
+		table.Append([]string{"rssi", fmt.Sprintf("%.1fdBm", signal.RSSI)})
+		table.Append([]string{"afc", fmt.Sprintf("%.0fHz", signal.AFC)})
+		table.Append([]string{"fei", fmt.Sprintf("%.0fHz", signal.FEI)})
+		table.Append([]string{"lna_gain", fmt.Sprint(signal.LNAGain)})
 
 		table.Render()
 	}
@@ -179,6 +235,7 @@ func Status(app *gopi.AppInstance, device sensors.RFM69) error {
 	table.Append([]string{"bitrate", bitrateToString(device.Bitrate())})
 	table.Append([]string{"freq_carrier", freqToString(device.FreqCarrier())})
 	table.Append([]string{"freq_dev", freqToString(device.FreqDeviation())})
+	table.Append([]string{"power", fmt.Sprintf("%v dBm", device.OutputPower())})
 
 	// Automatic Frequency Correction
 	table.Append([]string{"afc", fmt.Sprintf("%v Hz", device.AFC())})
@@ -229,9 +286,126 @@ func Status(app *gopi.AppInstance, device sensors.RFM69) error {
 		table.Append([]string{"aes_key", fmt.Sprintf("%v", strings.ToUpper(hex.EncodeToString(device.AESKey())))})
 	}
 
-	// FIFO
+	// FIFO and TX Start Condition
 	table.Append([]string{"fifo_threshold", fmt.Sprintf("%v bytes", device.FIFOThreshold())})
+	table.Append([]string{"fifo_fill", fmt.Sprint(device.FIFOFillCondition())})
+	table.Append([]string{"tx_start", txStartToString(device.TXStart())})
+
+	// OOK Parameters
+	table.Append([]string{"ook_type", ookThresholdTypeToString(device.OOKThresholdType())})
+	table.Append([]string{"ook_step", ookThresholdStepToString(device.OOKThresholdStep())})
+	table.Append([]string{"ook_dec", ookThresholdDecToString(device.OOKThresholdDecrement())})
 
 	table.Render()
 	return nil
 }
+
+func Save(app *gopi.AppInstance, device sensors.RFM69) error {
+	// Write profile to file, or to stdout when no file is provided
+	if data, err := writeProfile(device.Profile()); err != nil {
+		return err
+	} else if name, _ := app.AppFlags.GetString("profile"); name == "" {
+		os.Stdout.Write(data)
+	} else if _, exists := profile_map[name]; exists {
+		return fmt.Errorf("Cannot overwrite built-in profile: %v", name)
+	} else if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		return err
+	}
+
+	// Success
+	return nil
+}
+
+func Load(app *gopi.AppInstance, device sensors.RFM69) error {
+	// Put into Standby mode
+	if device.Mode() != sensors.RFM_MODE_STDBY {
+		if err := device.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+			return err
+		}
+	}
+
+	// Read and apply profile
+	name, _ := app.AppFlags.GetString("profile")
+	if profile, err := readProfile(name); err != nil {
+		return err
+	} else if err := device.ApplyProfile(profile); err != nil {
+		return err
+	}
+
+	// Success
+	return nil
+}
+
+func Diff(app *gopi.AppInstance, device sensors.RFM69) error {
+	name, _ := app.AppFlags.GetString("profile")
+	if profile, err := readProfile(name); err != nil {
+		return err
+	} else if names, device_values, profile_values, err := diffProfile(device.Profile(), profile); err != nil {
+		return err
+	} else if len(names) == 0 {
+		fmt.Printf("No differences from profile %v\n", name)
+	} else {
+		// Output differences
+		table := tablewriter.NewWriter(os.Stdout)
+
+		table.SetHeader([]string{"Parameter", "Device", "Profile"})
+		for _, name := range names {
+			table.Append([]string{name, fmt.Sprint(device_values[name]), fmt.Sprint(profile_values[name])})
+		}
+
+		table.Render()
+	}
+
+	// Success
+	return nil
+}
+
+func Scan(app *gopi.AppInstance, device sensors.RFM69) error {
+	start, _ := app.AppFlags.GetFloat64("scan_start")
+	stop, _ := app.AppFlags.GetFloat64("scan_stop")
+	step, _ := app.AppFlags.GetFloat64("scan_step")
+	dwell, _ := app.AppFlags.GetDuration("scan_dwell")
+
+	// Scan the band
+	results, err := device.Scan(context.Background(), uint(start*1000), uint(stop*1000), uint(step*1000), dwell)
+	if err != nil {
+		return err
+	}
+
+	// Output as CSV
+	if as_csv, _ := app.AppFlags.GetBool("csv"); as_csv {
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write([]string{"frequency_hz", "max_dbm", "average_dbm", "samples"})
+		for _, result := range results {
+			writer.Write([]string{
+				fmt.Sprint(result.Frequency),
+				fmt.Sprintf("%.1f", result.Max),
+				fmt.Sprintf("%.1f", result.Average),
+				fmt.Sprint(result.Samples),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	// Output as a table, with a bar of one character per 2dB above the floor
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Frequency", "Max", "Average", "Samples", ""})
+	for _, result := range results {
+		bar := int((result.Max - RSSI_FLOOR) / 2)
+		if bar < 0 {
+			bar = 0
+		}
+		table.Append([]string{
+			fmt.Sprintf("%.3fMHz", float64(result.Frequency)/1e6),
+			fmt.Sprintf("%.1fdBm", result.Max),
+			fmt.Sprintf("%.1fdBm", result.Average),
+			fmt.Sprint(result.Samples),
+			strings.Repeat("#", bar),
+		})
+	}
+	table.Render()
+
+	// Success
+	return nil
+}
@@ -250,6 +250,76 @@ func setParametersFIFO(app *gopi.AppInstance, device sensors.RFM69) error {
 		}
 	}
 
+	if value, exists := app.AppFlags.GetBool("fifo_fill"); exists {
+		if err := device.SetFIFOFillCondition(value); err != nil {
+			return err
+		}
+	}
+
+	if value, exists := app.AppFlags.GetString("tx_start"); exists {
+		if tx_start, err := stringToTXStart(value); err != nil {
+			return err
+		} else if err := device.SetTXStart(tx_start); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+func setParametersPower(app *gopi.AppInstance, device sensors.RFM69) error {
+	if value, exists := app.AppFlags.GetInt("power"); exists {
+		if value < -128 || value > 127 {
+			return fmt.Errorf("Invalid power value: %v", value)
+		} else if err := device.SetOutputPower(int8(value)); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+func setParametersOOK(app *gopi.AppInstance, device sensors.RFM69) error {
+	threshold_type := device.OOKThresholdType()
+	threshold_step := device.OOKThresholdStep()
+	threshold_dec := device.OOKThresholdDecrement()
+	changed := false
+
+	if value, exists := app.AppFlags.GetString("ook_type"); exists {
+		if threshold_type_, err := stringToOOKThresholdType(value); err != nil {
+			return err
+		} else {
+			threshold_type = threshold_type_
+			changed = true
+		}
+	}
+
+	if value, exists := app.AppFlags.GetString("ook_step"); exists {
+		if threshold_step_, err := stringToOOKThresholdStep(value); err != nil {
+			return err
+		} else {
+			threshold_step = threshold_step_
+			changed = true
+		}
+	}
+
+	if value, exists := app.AppFlags.GetString("ook_dec"); exists {
+		if threshold_dec_, err := stringToOOKThresholdDec(value); err != nil {
+			return err
+		} else {
+			threshold_dec = threshold_dec_
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := device.SetOOK(threshold_type, threshold_step, threshold_dec); err != nil {
+			return err
+		}
+	}
+
 	// Success
 	return nil
 }
@@ -285,6 +355,12 @@ func setParameters(app *gopi.AppInstance, device sensors.RFM69) error {
 	if err := setParametersFIFO(app, device); err != nil {
 		return err
 	}
+	if err := setParametersOOK(app, device); err != nil {
+		return err
+	}
+	if err := setParametersPower(app, device); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -347,6 +423,7 @@ func main() {
 	config.AppFlags.FlagFloat64("bitrate", 0, "Bitrate (kbps)")
 	config.AppFlags.FlagFloat64("freq_carrier", 0, "Carrier Frequency (kbps)")
 	config.AppFlags.FlagFloat64("freq_dev", 0, "Frequency Deviation (kbps)")
+	config.AppFlags.FlagInt("power", 0, "TX Output Power (dBm)")
 	config.AppFlags.FlagString("node_addr", "", "Node Address (byte)")
 	config.AppFlags.FlagString("broadcast_addr", "", "Broadcast Address (byte)")
 	config.AppFlags.FlagUint("payload_size", 0, "Payload Size (bytes)")
@@ -361,9 +438,20 @@ func main() {
 	config.AppFlags.FlagString("afc_mode", "", "AFC Mode (off, on, autoclear), ")
 	config.AppFlags.FlagString("afc_routine", "", "AFC Routine (standard, improved)")
 	config.AppFlags.FlagUint("fifo_threshold", 0, "FIFO Threshold (bytes)")
+	config.AppFlags.FlagBool("fifo_fill", false, "Fill FIFO regardless of sync address")
+	config.AppFlags.FlagString("tx_start", "", "TX Start Condition (fifolevel, fifonotempty)")
+	config.AppFlags.FlagString("ook_type", "", "OOK Threshold Type (fixed, peak, average)")
+	config.AppFlags.FlagString("ook_step", "", "OOK Threshold Step in peak mode (0.5,1.0,1.5,2.0,3.0,4.0,5.0,6.0 dB)")
+	config.AppFlags.FlagString("ook_dec", "", "OOK Threshold Decrements per chip in peak mode (0.125,0.25,0.5,1,2,4,8,16)")
 	config.AppFlags.FlagDuration("timeout", 5*time.Second, "FIFO and Payload read timeout")
 	config.AppFlags.FlagFloat64("temp_calibration", 0, "Temperature Calibration Offset")
 	config.AppFlags.FlagString("data", "", "Payload")
+	config.AppFlags.FlagString("profile", "", "Profile file or built-in profile name for Save, Load and Diff")
+	config.AppFlags.FlagFloat64("scan_start", 433050, "Scan start frequency (kHz)")
+	config.AppFlags.FlagFloat64("scan_stop", 434790, "Scan stop frequency (kHz)")
+	config.AppFlags.FlagFloat64("scan_step", 25, "Scan step (kHz)")
+	config.AppFlags.FlagDuration("scan_dwell", 10*time.Millisecond, "Scan dwell time at each frequency")
+	config.AppFlags.FlagBool("csv", false, "Output Scan results as CSV")
 
 	config.AppFlags.SetUsageFunc(func(flags *gopi.Flags) {
 		fmt.Fprintf(os.Stderr, "Usage: %v <flags> (<command>)\n\n", flags.Name())
@@ -371,6 +459,10 @@ func main() {
 		for _, command := range ListCommands() {
 			fmt.Fprintf(os.Stderr, "  %v\n", command)
 		}
+		fmt.Fprintf(os.Stderr, "\nBuilt-in Profiles:\n")
+		for _, profile := range listProfiles() {
+			fmt.Fprintf(os.Stderr, "  %v\n", profile)
+		}
 		fmt.Fprintf(os.Stderr, "\nFlags:\n")
 		flags.PrintDefaults()
 	})
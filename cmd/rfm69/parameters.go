@@ -67,6 +67,39 @@ var (
 		"standard": sensors.RFM_AFCROUTINE_STANDARD,
 		"improved": sensors.RFM_AFCROUTINE_IMPROVED,
 	}
+
+	tx_start_map = map[string]sensors.RFMTXStart{
+		"fifolevel":    sensors.RFM_TXSTART_FIFOLEVEL,
+		"fifonotempty": sensors.RFM_TXSTART_FIFONOTEMPTY,
+	}
+
+	ook_threshold_type_map = map[string]sensors.RFMOOKThresholdType{
+		"fixed":   sensors.RFM_OOK_THRESHOLD_FIXED,
+		"peak":    sensors.RFM_OOK_THRESHOLD_PEAK,
+		"average": sensors.RFM_OOK_THRESHOLD_AVERAGE,
+	}
+
+	ook_threshold_step_map = map[string]sensors.RFMOOKThresholdStep{
+		"0.5": sensors.RFM_OOK_THRESHOLD_STEP_0P5,
+		"1.0": sensors.RFM_OOK_THRESHOLD_STEP_1P0,
+		"1.5": sensors.RFM_OOK_THRESHOLD_STEP_1P5,
+		"2.0": sensors.RFM_OOK_THRESHOLD_STEP_2P0,
+		"3.0": sensors.RFM_OOK_THRESHOLD_STEP_3P0,
+		"4.0": sensors.RFM_OOK_THRESHOLD_STEP_4P0,
+		"5.0": sensors.RFM_OOK_THRESHOLD_STEP_5P0,
+		"6.0": sensors.RFM_OOK_THRESHOLD_STEP_6P0,
+	}
+
+	ook_threshold_dec_map = map[string]sensors.RFMOOKThresholdDecrement{
+		"0.125": sensors.RFM_OOK_THRESHOLD_DEC_0P125,
+		"0.25":  sensors.RFM_OOK_THRESHOLD_DEC_0P25,
+		"0.5":   sensors.RFM_OOK_THRESHOLD_DEC_0P5,
+		"1":     sensors.RFM_OOK_THRESHOLD_DEC_1,
+		"2":     sensors.RFM_OOK_THRESHOLD_DEC_2,
+		"4":     sensors.RFM_OOK_THRESHOLD_DEC_4,
+		"8":     sensors.RFM_OOK_THRESHOLD_DEC_8,
+		"16":    sensors.RFM_OOK_THRESHOLD_DEC_16,
+	}
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -264,3 +297,77 @@ func stringToAFCRoutine(value string) (sensors.RFMAFCRoutine, error) {
 		return routine, nil
 	}
 }
+
+/////////////////////////////////////////////////////////////////////
+// TX START
+
+func txStartToString(value sensors.RFMTXStart) string {
+	for k, v := range tx_start_map {
+		if value == v {
+			return k
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func stringToTXStart(value string) (sensors.RFMTXStart, error) {
+	if tx_start, ok := tx_start_map[value]; ok == false {
+		return 0, fmt.Errorf("Invalid tx_start flag: %v", value)
+	} else {
+		return tx_start, nil
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// OOK
+
+func ookThresholdTypeToString(value sensors.RFMOOKThresholdType) string {
+	for k, v := range ook_threshold_type_map {
+		if value == v {
+			return k
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func ookThresholdStepToString(value sensors.RFMOOKThresholdStep) string {
+	for k, v := range ook_threshold_step_map {
+		if value == v {
+			return k + " dB"
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func ookThresholdDecToString(value sensors.RFMOOKThresholdDecrement) string {
+	for k, v := range ook_threshold_dec_map {
+		if value == v {
+			return k + " per chip"
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func stringToOOKThresholdType(value string) (sensors.RFMOOKThresholdType, error) {
+	if threshold_type, ok := ook_threshold_type_map[value]; ok == false {
+		return 0, fmt.Errorf("Invalid ook_type flag: %v", value)
+	} else {
+		return threshold_type, nil
+	}
+}
+
+func stringToOOKThresholdStep(value string) (sensors.RFMOOKThresholdStep, error) {
+	if threshold_step, ok := ook_threshold_step_map[value]; ok == false {
+		return 0, fmt.Errorf("Invalid ook_step flag: %v", value)
+	} else {
+		return threshold_step, nil
+	}
+}
+
+func stringToOOKThresholdDec(value string) (sensors.RFMOOKThresholdDecrement, error) {
+	if threshold_dec, ok := ook_threshold_dec_map[value]; ok == false {
+		return 0, fmt.Errorf("Invalid ook_dec flag: %v", value)
+	} else {
+		return threshold_dec, nil
+	}
+}
@@ -0,0 +1,96 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2018
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	// Frameworks
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/ener314rt"
+)
+
+/////////////////////////////////////////////////////////////////////
+// BUILT-IN PROFILES
+
+var (
+	profile_map = map[string]sensors.RFMProfile{
+		ener314rt.PROFILE_MONITOR.Name: ener314rt.PROFILE_MONITOR,
+		ener314rt.PROFILE_CONTROL.Name: ener314rt.PROFILE_CONTROL,
+	}
+)
+
+/////////////////////////////////////////////////////////////////////
+// READ AND WRITE PROFILES
+
+// readProfile returns a built-in profile by name, or else reads
+// a profile from a JSON file
+func readProfile(name string) (sensors.RFMProfile, error) {
+	var profile sensors.RFMProfile
+	if name == "" {
+		return profile, fmt.Errorf("Missing -profile argument")
+	} else if builtin, exists := profile_map[name]; exists {
+		return builtin, nil
+	} else if data, err := ioutil.ReadFile(name); err != nil {
+		return profile, err
+	} else if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("%v: %v", name, err)
+	} else {
+		return profile, nil
+	}
+}
+
+// writeProfile returns a profile as indented JSON
+func writeProfile(profile sensors.RFMProfile) ([]byte, error) {
+	if data, err := json.MarshalIndent(profile, "", "  "); err != nil {
+		return nil, err
+	} else {
+		return append(data, '\n'), nil
+	}
+}
+
+// diffProfile returns the names of values which are different
+// between two profiles, ignoring values which are not set in
+// the second profile, and the values of each profile by name
+func diffProfile(a, b sensors.RFMProfile) ([]string, map[string]interface{}, map[string]interface{}, error) {
+	values_a, values_b := make(map[string]interface{}), make(map[string]interface{})
+	if data, err := json.Marshal(a); err != nil {
+		return nil, nil, nil, err
+	} else if err := json.Unmarshal(data, &values_a); err != nil {
+		return nil, nil, nil, err
+	} else if data, err := json.Marshal(b); err != nil {
+		return nil, nil, nil, err
+	} else if err := json.Unmarshal(data, &values_b); err != nil {
+		return nil, nil, nil, err
+	}
+	names := make([]string, 0)
+	for name, value_b := range values_b {
+		if name == "name" {
+			continue
+		} else if reflect.DeepEqual(values_a[name], value_b) == false {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, values_a, values_b, nil
+}
+
+// listProfiles returns the names of the built-in profiles
+func listProfiles() []string {
+	names := make([]string, 0, len(profile_map))
+	for name := range profile_map {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
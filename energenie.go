@@ -26,8 +26,105 @@ type (
 	MiHomeProduct    byte
 	MiHomeValveState byte
 	MiHomePowerMode  byte
+	MiHomePriority   uint
+	MiHomeOverride   uint
 )
 
+// MiHomePayload is a payload received by the radio, with the signal
+// metadata when measured by the radio or nil otherwise
+type MiHomePayload struct {
+	Data   []byte
+	Signal *RFMSignal
+}
+
+// MiHomeStatistics is the time spent receiving in a mode, and the number
+// of payloads received and decoded in that mode
+type MiHomeStatistics struct {
+	Duration time.Duration
+	Payloads uint
+	Decoded  uint
+}
+
+// MiHomeTXOptions are the priority, number of repeats and deadline
+// for transmitting a message
+type MiHomeTXOptions struct {
+	Priority MiHomePriority
+	Repeat   uint      // Number of times to repeat, or zero for the default
+	Deadline time.Time // Time after which the message is discarded, or zero for the default
+}
+
+// MiHomeDevice is the state of a device, which is the last value of
+// each parameter received from the device
+type MiHomeDevice struct {
+	Product  MiHomeProduct
+	Sensor   uint32
+	LastSeen time.Time
+	Signal   *RFMSignal // Signal metadata for the last message, or nil
+	Values   map[OTParameter]MiHomeValue
+}
+
+// MiHomeValue is the last value received for a parameter, with the time
+// it was last received and the time it last changed
+type MiHomeValue struct {
+	Record  OTRecord
+	Updated time.Time
+	Changed time.Time
+}
+
+// MiHomeChangeEvent is emitted when a value received from a device
+// differs from the previous value, or is the first value received
+type MiHomeChangeEvent interface {
+	gopi.Event
+
+	// Return the device
+	Product() MiHomeProduct
+	Sensor() uint32
+
+	// Return the parameter, the value and the previous value,
+	// which is nil for the first value received
+	Parameter() OTParameter
+	Value() OTRecord
+	Previous() OTRecord
+}
+
+// MiHomeSchedule is a weekly timetable of target temperatures for one
+// or more eTRVs, and overrides of the timetable
+type MiHomeSchedule struct {
+	Name      string
+	Sensors   []uint32
+	Periods   []MiHomePeriod
+	Overrides []MiHomeOverridePeriod
+}
+
+// MiHomePeriod sets the target temperature from a time of day on a day
+// of the week until the next period starts
+type MiHomePeriod struct {
+	Day     time.Weekday
+	Start   time.Duration // Time of day from midnight
+	Celcius float64
+}
+
+// MiHomeOverridePeriod sets the target temperature between two times,
+// instead of the timetable
+type MiHomeOverridePeriod struct {
+	Type    MiHomeOverride
+	Start   time.Time
+	End     time.Time
+	Celcius float64
+}
+
+// MiHomeTXEvent is emitted when a queued message has been transmitted,
+// or with an error when it could not be transmitted
+type MiHomeTXEvent interface {
+	gopi.Event
+
+	// Return the message
+	Message() Message
+
+	// Return the error, or nil if the message was transmitted
+	Err() error
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // ENER314 AND ENER314RT
 
@@ -48,7 +145,7 @@ type ENER314RT interface {
 
 	// Receive payloads with radio until context deadline exceeded or cancel,
 	// this blocks sending
-	Receive(ctx context.Context, mode MiHomeMode, payload chan<- []byte) error
+	Receive(ctx context.Context, mode MiHomeMode, payload chan<- MiHomePayload) error
 
 	// Send a raw payload with radio
 	Send(payload []byte, repeat uint, mode MiHomeMode) error
@@ -56,6 +153,10 @@ type ENER314RT interface {
 	// Measure device temperature
 	MeasureTemperature(offset float32) (float32, error)
 
+	// Set transmit power in dBm, which is retained when the
+	// radio device is reset
+	SetOutputPower(dbm int8) error
+
 	// Reset radio device
 	ResetRadio() error
 }
@@ -76,13 +177,33 @@ type MiHome interface {
 	// Return registered protocols
 	Protos() []Proto
 
+	// Return the time spent receiving in each mode, and the number
+	// of payloads received and decoded
+	Statistics() map[MiHomeMode]MiHomeStatistics
+
 	// Measure Device Temperature
 	MeasureTemperature() (float32, error)
 
+	// Return the state of all devices messages have been received
+	// from, or the state of one device or nil if no messages have
+	// been received from it
+	Devices() []*MiHomeDevice
+	Device(MiHomeProduct, uint32) *MiHomeDevice
+
+	// Queue a message for transmission, returning once queued. A
+	// MiHomeTXEvent is emitted once transmitted or discarded. The
+	// request methods below also queue messages
+	SendMessage(Message, MiHomeTXOptions) error
+
 	// Request Switch state for both monitor and control devices
 	RequestSwitchOn(MiHomeProduct, uint32) error
 	RequestSwitchOff(MiHomeProduct, uint32) error
 
+	// Request switch state for a device which reports its switch state,
+	// and wait for the report. Returns ErrDeviceTimeout if there is no
+	// report or ErrUnexpectedResponse if the report has a different state
+	RequestSwitchConfirmed(context.Context, MiHomeProduct, uint32, bool) error
+
 	// Send a join message after a report is received
 	SendJoin(MiHomeProduct, uint32) error
 
@@ -97,6 +218,9 @@ type MiHome interface {
 	RequestReportInterval(MiHomeProduct, uint32, time.Duration) error
 	RequestValveState(MiHomeProduct, uint32, MiHomeValveState) error
 	RequestLowPowerMode(MiHomeProduct, uint32, bool) error
+
+	// Send the current time to a device which supports it
+	RequestTimeSync(MiHomeProduct, uint32) error
 }
 
 // MiHome Client Stub
@@ -114,6 +238,11 @@ type MiHomeClient interface {
 	On(MiHomeProduct, uint32) error
 	Off(MiHomeProduct, uint32) error
 
+	// Send 'On' and 'Off' signals and wait for the device
+	// to report the switch state
+	OnConfirmed(MiHomeProduct, uint32) error
+	OffConfirmed(MiHomeProduct, uint32) error
+
 	// Send a join message after a join report is received
 	SendJoin(MiHomeProduct, uint32) error
 
@@ -122,6 +251,7 @@ type MiHomeClient interface {
 	RequestIdentify(MiHomeProduct, uint32) error
 	RequestExercise(MiHomeProduct, uint32) error
 	RequestBatteryLevel(MiHomeProduct, uint32) error
+	RequestTimeSync(MiHomeProduct, uint32) error
 
 	// Set parameters
 	SendTargetTemperature(MiHomeProduct, uint32, float64) error
@@ -129,10 +259,39 @@ type MiHomeClient interface {
 	SendValveState(MiHomeProduct, uint32, MiHomeValveState) error
 	SendPowerMode(MiHomeProduct, uint32, MiHomePowerMode) error
 
+	// Manage heating schedules
+	ListSchedules() ([]*MiHomeSchedule, error)
+	SetSchedule(*MiHomeSchedule) error
+	DeleteSchedule(string) error
+	SetOverride(string, MiHomeOverridePeriod) error
+	ClearOverrides(string) error
+
 	// Receive messages
 	StreamMessages(ctx context.Context) error
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// MIHOME SCHEDULER
+
+type MiHomeScheduler interface {
+	gopi.Driver
+
+	// Return all schedules, ordered by name
+	Schedules() []*MiHomeSchedule
+
+	// Add or replace a schedule, or remove a schedule by name. A sensor
+	// can only be in one schedule
+	SetSchedule(*MiHomeSchedule) error
+	DeleteSchedule(string) error
+
+	// Add an override to a schedule by name, or remove the overrides
+	SetOverride(string, MiHomeOverridePeriod) error
+	ClearOverrides(string) error
+
+	// Return the target temperature for each sensor at a time
+	Targets(time.Time) map[uint32]float64
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CONSTANTS
 
@@ -140,9 +299,23 @@ const (
 	MIHOME_MODE_NONE    MiHomeMode = iota
 	MIHOME_MODE_MONITOR            // FSK
 	MIHOME_MODE_CONTROL            // OOK
+	MIHOME_MODE_BOTH               // Alternate between FSK and OOK when receiving
 	MIHOME_MODE_MAX     = MIHOME_MODE_CONTROL
 )
 
+const (
+	MIHOME_OVERRIDE_NONE    MiHomeOverride = iota
+	MIHOME_OVERRIDE_HOLIDAY                // Used while away, unless there is a boost
+	MIHOME_OVERRIDE_BOOST                  // Used for a short time
+	MIHOME_OVERRIDE_MAX     = MIHOME_OVERRIDE_BOOST
+)
+
+const (
+	MIHOME_PRIORITY_LOW MiHomePriority = iota
+	MIHOME_PRIORITY_NORMAL
+	MIHOME_PRIORITY_HIGH
+)
+
 const (
 	// Monitor Products (FSK)
 	MIHOME_PRODUCT_NONE    MiHomeProduct = 0x00
@@ -257,11 +430,39 @@ func (m MiHomeMode) String() string {
 		return "MIHOME_MODE_MONITOR"
 	case MIHOME_MODE_CONTROL:
 		return "MIHOME_MODE_CONTROL"
+	case MIHOME_MODE_BOTH:
+		return "MIHOME_MODE_BOTH"
 	default:
 		return "[?? Invalid MiHomeMode value]"
 	}
 }
 
+func (o MiHomeOverride) String() string {
+	switch o {
+	case MIHOME_OVERRIDE_NONE:
+		return "MIHOME_OVERRIDE_NONE"
+	case MIHOME_OVERRIDE_HOLIDAY:
+		return "MIHOME_OVERRIDE_HOLIDAY"
+	case MIHOME_OVERRIDE_BOOST:
+		return "MIHOME_OVERRIDE_BOOST"
+	default:
+		return "[?? Invalid MiHomeOverride value]"
+	}
+}
+
+func (p MiHomePriority) String() string {
+	switch p {
+	case MIHOME_PRIORITY_LOW:
+		return "MIHOME_PRIORITY_LOW"
+	case MIHOME_PRIORITY_NORMAL:
+		return "MIHOME_PRIORITY_NORMAL"
+	case MIHOME_PRIORITY_HIGH:
+		return "MIHOME_PRIORITY_HIGH"
+	default:
+		return "[?? Invalid MiHomePriority value]"
+	}
+}
+
 func (p MiHomePowerMode) String() string {
 	switch p {
 	case MIHOME_POWER_NONE:
@@ -11,6 +11,8 @@ package sensors
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	// Frameworks
@@ -21,9 +23,11 @@ import (
 // TYPES
 
 type (
-	OTManufacturer uint8
-	OTParameter    uint8
-	OTDataType     uint8
+	OTManufacturer     uint8
+	OTParameter        uint8
+	OTDataType         uint8
+	LightwaveRFCommand uint8
+	OregonSensor       uint16
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -59,6 +63,13 @@ type Message interface {
 	// IsDuplicate returns true if one message is equivalent of another,
 	// regardless of timestamp, to help with de-duplication
 	IsDuplicate(Message) bool
+
+	// Return the signal metadata for a received message, or nil
+	// if the message was not received by a radio
+	Signal() *RFMSignal
+
+	// Set the signal metadata when a message is received
+	SetSignal(*RFMSignal)
 }
 
 type Database interface {
@@ -111,6 +122,67 @@ type OOKMessage interface {
 	State() bool  // false = off or true = on
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// PROTOCOLS  - FIXED CODE
+
+type FixedCodeProto interface {
+	Proto
+
+	// Create a new message
+	New(code uint32, button uint, data []byte) (FixedCodeMessage, error)
+}
+
+type FixedCodeMessage interface {
+	Message
+
+	Code() uint32 // Code without the button bits
+	Bits() uint   // Number of bits in the frame, including the button bits
+	Button() uint // Button bits at the end of the frame
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROTOCOLS  - LIGHTWAVERF
+
+type LightwaveRFProto interface {
+	Proto
+
+	// Create a new message, where level is the dim level (0-31) for
+	// LIGHTWAVERF_COMMAND_DIM or mood number for LIGHTWAVERF_COMMAND_MOOD
+	New(id uint32, room, device uint, command LightwaveRFCommand, level uint) (LightwaveRFMessage, error)
+}
+
+type LightwaveRFMessage interface {
+	Message
+
+	ID() uint32                  // 20-bit transmitter address
+	Room() uint                  // Room 0-15
+	Device() uint                // Device 0-15 in the room
+	Command() LightwaveRFCommand // Off, on, dim or mood
+	Level() uint                 // Dim level or mood number
+	Param() uint8                // Parameter as sent over the air
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROTOCOLS  - OREGON SCIENTIFIC
+
+type OregonProto interface {
+	Proto
+
+	// Create a new message, where humidity is ignored for
+	// sensors which only measure temperature
+	New(sensor OregonSensor, channel uint, code uint8, temperature float64, humidity uint, battery_low bool) (OregonMessage, error)
+}
+
+type OregonMessage interface {
+	Message
+
+	SensorType() OregonSensor // Sensor type
+	Channel() uint            // Channel 1-15
+	RollingCode() uint8       // Code which changes when the batteries are replaced
+	BatteryLow() bool         // Battery low flag
+	Records() []OTRecord      // Temperature and humidity readings
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PROTOCOLS  - OPENTHINGS
 
@@ -129,6 +201,12 @@ type OTProto interface {
 	NewNull(OTParameter, bool) (OTRecord, error)
 	NewUint8(OTParameter, uint8, bool) (OTRecord, error)
 	NewUint16(OTParameter, uint16, bool) (OTRecord, error)
+	NewEnum(OTParameter, uint64, bool) (OTRecord, error)
+	NewFloat32(OTParameter, float32, bool) (OTRecord, error)
+	NewTime(OTParameter, time.Time, bool) (OTRecord, error)
+
+	// Create a message from its JSON representation
+	DecodeJSON(data []byte) (OTMessage, error)
 }
 
 type OTMessage interface {
@@ -142,6 +220,10 @@ type OTMessage interface {
 	// Records returns an array of records for the message
 	Records() []OTRecord
 
+	// IsPartial returns true when the message was decoded leniently
+	// and contains opaque records
+	IsPartial() bool
+
 	// Append a record
 	Append(...OTRecord) OTMessage
 }
@@ -156,6 +238,11 @@ type OTRecord interface {
 	// IsReport returns the report bit for the record
 	IsReport() bool
 
+	// IsOpaque returns true when the record was decoded leniently and
+	// the parameter is unknown, or when the record is corrupted in which
+	// case the name is OT_PARAM_NONE and the value is the raw data
+	IsOpaque() bool
+
 	// Data returns the record encoded as data
 	Data() ([]byte, error)
 
@@ -165,7 +252,7 @@ type OTRecord interface {
 	// BoolValue returns the boolean value, when type is UDEC_0
 	BoolValue() (bool, error)
 
-	// StringValue returns the value for all types except FLOAT and ENUM
+	// StringValue returns the value for all types
 	StringValue() (string, error)
 
 	// UintValue returns the value for UDEC_0 types
@@ -174,9 +261,20 @@ type OTRecord interface {
 	// IntValue returns the value for DEC_0 types
 	IntValue() (int64, error)
 
-	// FloatValue returns the value for all UDEC and DEC types
+	// FloatValue returns the value for all UDEC, DEC and FLOAT types
 	FloatValue() (float64, error)
 
+	// EnumValue returns the value for ENUM types
+	EnumValue() (uint64, error)
+
+	// Float32Value returns the value for FLOAT types which are
+	// four bytes in length
+	Float32Value() (float32, error)
+
+	// TimeValue returns the value for UDEC_0 types as the number of
+	// seconds since the Unix epoch
+	TimeValue() (time.Time, error)
+
 	// Compares one record against another and returns true if identical
 	IsDuplicate(OTRecord) bool
 }
@@ -184,6 +282,17 @@ type OTRecord interface {
 ////////////////////////////////////////////////////////////////////////////////
 // PROTOCOLS - PROTOBUF
 
+type ProtobufProto interface {
+	Proto
+
+	// Create a new message from a node, which is sent to an address
+	New(protocol string, product uint8, sensor uint32, address uint8) (ProtoMessage, error)
+
+	// Create a reading, where the value is a float, signed or
+	// unsigned integer, bool or string
+	NewReading(name OTParameter, value interface{}) (OTRecord, error)
+}
+
 type ProtoMessage interface {
 	Message
 
@@ -191,6 +300,22 @@ type ProtoMessage interface {
 	Protocol() string
 	Product() uint8
 	Sensor() uint32
+
+	// Return the destination address
+	Address() uint8
+
+	// Append readings and return the message
+	Append(...OTRecord) ProtoMessage
+
+	// Return the readings
+	Records() []OTRecord
+}
+
+type ProtobufSender interface {
+	gopi.Driver
+
+	// Send readings from a sensor, for example "bme280"
+	Send(protocol string, readings map[OTParameter]interface{}) error
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -279,8 +404,30 @@ const (
 	OT_DATATYPE_DEC_8   OTDataType = 0x09
 	OT_DATATYPE_DEC_16  OTDataType = 0x0A
 	OT_DATATYPE_DEC_24  OTDataType = 0x0B
-	OT_DATATYPE_ENUM    OTDataType = 0x0C // Not supported
-	OT_DATATYPE_FLOAT   OTDataType = 0x0F // Not supported
+	OT_DATATYPE_ENUM    OTDataType = 0x0C
+	OT_DATATYPE_FLOAT   OTDataType = 0x0F
+)
+
+const (
+	// LightwaveRFCommand
+	LIGHTWAVERF_COMMAND_OFF LightwaveRFCommand = iota
+	LIGHTWAVERF_COMMAND_ON
+	LIGHTWAVERF_COMMAND_DIM
+	LIGHTWAVERF_COMMAND_MOOD
+	LIGHTWAVERF_COMMAND_MAX = LIGHTWAVERF_COMMAND_MOOD
+)
+
+const (
+	// OregonSensor is the sensor ID sent at the start of each message
+	OREGON_SENSOR_NONE     OregonSensor = 0x0000
+	OREGON_SENSOR_THGR122N OregonSensor = 0x1D20 // Temperature and humidity, also THGN123N
+	OREGON_SENSOR_THGR228N OregonSensor = 0x1A2D // Temperature and humidity, also THGN122N
+	OREGON_SENSOR_THGR328N OregonSensor = 0xCA2C // Temperature and humidity
+	OREGON_SENSOR_THGR810  OregonSensor = 0xF824 // Temperature and humidity
+	OREGON_SENSOR_THGN801  OregonSensor = 0xF8B4 // Temperature and humidity
+	OREGON_SENSOR_THN132N  OregonSensor = 0xEC40 // Temperature, also THR238NF
+	OREGON_SENSOR_THWR288A OregonSensor = 0xEA4C // Temperature
+	OREGON_SENSOR_THWR800  OregonSensor = 0xC844 // Temperature
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -448,3 +595,112 @@ func (t OTDataType) String() string {
 		return "[?? Invalid OTDataType value]"
 	}
 }
+
+func (c LightwaveRFCommand) String() string {
+	switch c {
+	case LIGHTWAVERF_COMMAND_OFF:
+		return "LIGHTWAVERF_COMMAND_OFF"
+	case LIGHTWAVERF_COMMAND_ON:
+		return "LIGHTWAVERF_COMMAND_ON"
+	case LIGHTWAVERF_COMMAND_DIM:
+		return "LIGHTWAVERF_COMMAND_DIM"
+	case LIGHTWAVERF_COMMAND_MOOD:
+		return "LIGHTWAVERF_COMMAND_MOOD"
+	default:
+		return "[?? Invalid LightwaveRFCommand value]"
+	}
+}
+
+func (s OregonSensor) String() string {
+	switch s {
+	case OREGON_SENSOR_NONE:
+		return "OREGON_SENSOR_NONE"
+	case OREGON_SENSOR_THGR122N:
+		return "OREGON_SENSOR_THGR122N"
+	case OREGON_SENSOR_THGR228N:
+		return "OREGON_SENSOR_THGR228N"
+	case OREGON_SENSOR_THGR328N:
+		return "OREGON_SENSOR_THGR328N"
+	case OREGON_SENSOR_THGR810:
+		return "OREGON_SENSOR_THGR810"
+	case OREGON_SENSOR_THGN801:
+		return "OREGON_SENSOR_THGN801"
+	case OREGON_SENSOR_THN132N:
+		return "OREGON_SENSOR_THN132N"
+	case OREGON_SENSOR_THWR288A:
+		return "OREGON_SENSOR_THWR288A"
+	case OREGON_SENSOR_THWR800:
+		return "OREGON_SENSOR_THWR800"
+	default:
+		return "[?? Invalid OregonSensor value]"
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MARSHAL AND UNMARSHAL TEXT
+
+// marshalOTText returns the name of a value, or the value in hexadecimal
+// when it has no name, since decoded messages can contain any value
+func marshalOTText(value fmt.Stringer, number uint8) ([]byte, error) {
+	if name := value.String(); strings.HasPrefix(name, "[??") {
+		return []byte(fmt.Sprintf("0x%02X", number)), nil
+	} else {
+		return []byte(name), nil
+	}
+}
+
+// unmarshalOTText returns the value with a name or a value in
+// hexadecimal, or an error if no value has the name
+func unmarshalOTText(text []byte, value func(uint8) fmt.Stringer) (uint8, error) {
+	name := string(text)
+	if strings.HasPrefix(name, "0x") {
+		if v, err := strconv.ParseUint(name[2:], 16, 8); err == nil {
+			return uint8(v), nil
+		}
+	}
+	for v := 0; v <= 0xFF; v++ {
+		if value(uint8(v)).String() == name {
+			return uint8(v), nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid value: %v", name)
+}
+
+func (m OTManufacturer) MarshalText() ([]byte, error) {
+	return marshalOTText(m, uint8(m))
+}
+
+func (m *OTManufacturer) UnmarshalText(text []byte) error {
+	if value, err := unmarshalOTText(text, func(v uint8) fmt.Stringer { return OTManufacturer(v) }); err != nil {
+		return err
+	} else {
+		*m = OTManufacturer(value)
+		return nil
+	}
+}
+
+func (p OTParameter) MarshalText() ([]byte, error) {
+	return marshalOTText(p, uint8(p))
+}
+
+func (p *OTParameter) UnmarshalText(text []byte) error {
+	if value, err := unmarshalOTText(text, func(v uint8) fmt.Stringer { return OTParameter(v) }); err != nil {
+		return err
+	} else {
+		*p = OTParameter(value)
+		return nil
+	}
+}
+
+func (t OTDataType) MarshalText() ([]byte, error) {
+	return marshalOTText(t, uint8(t))
+}
+
+func (t *OTDataType) UnmarshalText(text []byte) error {
+	if value, err := unmarshalOTText(text, func(v uint8) fmt.Stringer { return OTDataType(v) }); err != nil {
+		return err
+	} else {
+		*t = OTDataType(value)
+		return nil
+	}
+}
@@ -0,0 +1,261 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package fixedcode
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/protocol/internal/chip"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// FixedCode is the configuration for PT2262 and EV1527 fixed-code
+// frames. Zero values are replaced with the defaults
+type FixedCode struct {
+	Bits       uint          // Number of bits in a frame, including the button bits
+	ButtonBits uint          // Number of button bits at the end of a frame
+	Pulse      time.Duration // Short pulse width
+	Ratio      uint          // Long pulse width as a multiple of the short pulse width
+	Bitrate    uint          // Radio bitrate in control mode
+}
+
+type fixedcode struct {
+	log         gopi.Logger
+	bits        uint
+	button_bits uint
+	ratio       uint
+	chips       uint // Number of chips (radio bits) in a short pulse
+}
+
+type message struct {
+	code   uint32
+	bits   uint
+	button uint
+	source sensors.Proto
+	data   []byte
+	ts     time.Time
+	signal *sensors.RFMSignal
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Defaults for EV1527 with the ENER314-RT control mode bitrate
+	FIXEDCODE_BITS        = 24
+	FIXEDCODE_BUTTON_BITS = 4
+	FIXEDCODE_PULSE       = 400 * time.Microsecond
+	FIXEDCODE_RATIO       = 3
+	FIXEDCODE_BITRATE     = 4800
+)
+
+const (
+	// Maximum number of bits in a frame
+	FIXEDCODE_BITS_MAX = 32
+
+	// Maximum long to short pulse ratio
+	FIXEDCODE_RATIO_MAX = 15
+
+	// Sync is one short pulse followed by a gap of 31 short pulses
+	FIXEDCODE_SYNC_GAP = 31
+
+	// Maximum size of an encoded frame in bytes, which fits in the
+	// radio FIFO
+	FIXEDCODE_PAYLOAD_MAX = 64
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config FixedCode) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.protocol.FixedCode>Open{ bits=%v button_bits=%v pulse=%v ratio=%v bitrate=%v }", config.Bits, config.ButtonBits, config.Pulse, config.Ratio, config.Bitrate)
+
+	this := new(fixedcode)
+	this.log = log
+	this.bits = config.Bits
+	this.button_bits = config.ButtonBits
+	this.ratio = config.Ratio
+
+	// Set defaults
+	if this.bits == 0 {
+		this.bits = FIXEDCODE_BITS
+	}
+	if this.ratio == 0 {
+		this.ratio = FIXEDCODE_RATIO
+	}
+	pulse, bitrate := config.Pulse, config.Bitrate
+	if pulse == 0 {
+		pulse = FIXEDCODE_PULSE
+	}
+	if bitrate == 0 {
+		bitrate = FIXEDCODE_BITRATE
+	}
+
+	// The short pulse is rounded to a whole number of chips, and
+	// is at least one chip
+	this.chips = uint((pulse*time.Duration(bitrate) + time.Second/2) / time.Second)
+	if this.chips == 0 {
+		this.chips = 1
+	}
+
+	// Check parameters
+	if this.bits > FIXEDCODE_BITS_MAX || this.button_bits >= this.bits {
+		return nil, gopi.ErrBadParameter
+	} else if this.ratio < 2 || this.ratio > FIXEDCODE_RATIO_MAX {
+		return nil, gopi.ErrBadParameter
+	} else if this.payloadSize() > FIXEDCODE_PAYLOAD_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *fixedcode) Close() error {
+	this.log.Debug("<sensors.protocol.FixedCode>Close{ }")
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// NAME AND MODE
+
+func (this *fixedcode) String() string {
+	return fmt.Sprintf("<sensors.protocol>{ name='%v' mode=%v bits=%v button_bits=%v ratio=%v chips=%v }", this.Name(), this.Mode(), this.bits, this.button_bits, this.ratio, this.chips)
+}
+
+func (this *fixedcode) Name() string {
+	return "fixedcode"
+}
+
+func (this *fixedcode) Mode() sensors.MiHomeMode {
+	return sensors.MIHOME_MODE_CONTROL
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE AND DECODE
+
+/*
+ The payload is a sync pulse (one short pulse high then 31 short pulses low)
+ followed by the bits of the frame, most significant bit first. A zero bit
+ is a short pulse high then a long pulse low, and a one bit is a long pulse
+ high then a short pulse low. Each short pulse is a number of chips, or
+ radio bits, which depends on the pulse width and bitrate
+*/
+
+func (this *fixedcode) Encode(msg sensors.Message) []byte {
+	this.log.Debug2("<sensors.protocol.FixedCode>Encode{ msg=%v }", msg)
+
+	// Ensure message is of type FixedCodeMessage with the right number of bits
+	msg_, ok := msg.(sensors.FixedCodeMessage)
+	if ok == false || msg_.Bits() != this.bits {
+		return nil
+	}
+
+	// Sync
+	chips := make([]bool, 0, this.payloadSize()*8)
+	chips = this.appendPulse(chips, true, 1)
+	chips = this.appendPulse(chips, false, FIXEDCODE_SYNC_GAP)
+
+	// Bits
+	value := this.value(msg_.Code(), msg_.Button())
+	for i := int(this.bits) - 1; i >= 0; i-- {
+		if value&(1<<uint(i)) == 0 {
+			chips = this.appendPulse(chips, true, 1)
+			chips = this.appendPulse(chips, false, this.ratio)
+		} else {
+			chips = this.appendPulse(chips, true, this.ratio)
+			chips = this.appendPulse(chips, false, 1)
+		}
+	}
+
+	// Pack the chips into bytes and return the payload
+	return chip.Pack(chips)
+}
+
+func (this *fixedcode) Decode(payload []byte, ts time.Time) (sensors.Message, error) {
+	this.log.Debug2("<sensors.protocol.FixedCode>Decode{ payload=%v ts=%v }", strings.ToUpper(hex.EncodeToString(payload)), ts)
+
+	// Check payload size
+	if len(payload) == 0 || len(payload) > FIXEDCODE_PAYLOAD_MAX {
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Find the first sync, which is a high pulse followed by a gap
+	// of at least half the sync gap
+	runs := chip.Runs(payload)
+	gap := this.chips * FIXEDCODE_SYNC_GAP / 2
+	i := 0
+	for ; i < len(runs)-1; i++ {
+		if runs[i] > 0 && runs[i+1] < 0 && uint(-runs[i+1]) >= gap {
+			break
+		}
+	}
+	if i >= len(runs)-1 {
+		this.log.Debug("<sensors.protocol.FixedCode>Decode: Missing sync")
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Decode the bits, which are a high pulse followed by a low pulse. The
+	// low pulse can be missing for the last bit at the end of the payload
+	value := uint32(0)
+	for n := uint(0); n < this.bits; n++ {
+		i += 2
+		if i >= len(runs) || runs[i] < 0 {
+			this.log.Debug("<sensors.protocol.FixedCode>Decode: Frame too short")
+			return nil, sensors.ErrMessageCorruption
+		} else if uint(runs[i]) > this.ratio*this.chips*2 {
+			this.log.Debug("<sensors.protocol.FixedCode>Decode: Pulse too long")
+			return nil, sensors.ErrMessageCorruption
+		} else if n < this.bits-1 && (i+1 >= len(runs) || uint(-runs[i+1]) >= gap) {
+			this.log.Debug("<sensors.protocol.FixedCode>Decode: Frame too short")
+			return nil, sensors.ErrMessageCorruption
+		}
+		value <<= 1
+		if uint(runs[i])*2 > (this.ratio+1)*this.chips {
+			value |= 1
+		}
+	}
+
+	// Return the message
+	mask := uint32(1)<<this.button_bits - 1
+	return this.NewWithTimestamp(value>>this.button_bits, uint(value&mask), payload, ts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// payloadSize returns the size of an encoded frame in bytes
+func (this *fixedcode) payloadSize() uint {
+	chips := (1 + FIXEDCODE_SYNC_GAP + this.bits*(this.ratio+1)) * this.chips
+	return (chips + 7) / 8
+}
+
+// appendPulse appends a number of short pulses at a level
+func (this *fixedcode) appendPulse(chips []bool, level bool, pulses uint) []bool {
+	for i := uint(0); i < pulses*this.chips; i++ {
+		chips = append(chips, level)
+	}
+	return chips
+}
+
+// value returns the frame value from the code and button
+func (this *fixedcode) value(code uint32, button uint) uint32 {
+	return code<<this.button_bits | uint32(button)
+}
@@ -0,0 +1,47 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package fixedcode
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register module
+	gopi.RegisterModule(gopi.Module{
+		Name: "sensors/protocol/fixedcode",
+		Type: gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("fixedcode.bits", FIXEDCODE_BITS, "Number of bits in a fixed-code frame")
+			config.AppFlags.FlagUint("fixedcode.button_bits", FIXEDCODE_BUTTON_BITS, "Number of button bits at the end of a fixed-code frame")
+			config.AppFlags.FlagDuration("fixedcode.pulse", FIXEDCODE_PULSE, "Fixed-code short pulse width")
+			config.AppFlags.FlagUint("fixedcode.ratio", FIXEDCODE_RATIO, "Fixed-code long to short pulse ratio")
+			config.AppFlags.FlagUint("fixedcode.bitrate", FIXEDCODE_BITRATE, "Radio bitrate in control mode")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			bits, _ := app.AppFlags.GetUint("fixedcode.bits")
+			button_bits, _ := app.AppFlags.GetUint("fixedcode.button_bits")
+			pulse, _ := app.AppFlags.GetDuration("fixedcode.pulse")
+			ratio, _ := app.AppFlags.GetUint("fixedcode.ratio")
+			bitrate, _ := app.AppFlags.GetUint("fixedcode.bitrate")
+			return gopi.Open(FixedCode{
+				Bits:       bits,
+				ButtonBits: button_bits,
+				Pulse:      pulse,
+				Ratio:      ratio,
+				Bitrate:    bitrate,
+			}, app.Logger)
+		},
+	})
+}
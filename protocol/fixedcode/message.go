@@ -0,0 +1,120 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package fixedcode
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CREATE MESSAGE
+
+func (this *fixedcode) New(code uint32, button uint, data []byte) (sensors.FixedCodeMessage, error) {
+	return this.NewWithTimestamp(code, button, data, time.Time{})
+}
+
+func (this *fixedcode) NewWithTimestamp(code uint32, button uint, data []byte, ts time.Time) (sensors.FixedCodeMessage, error) {
+	this.log.Debug2("<sensors.protocol.FixedCode>New{ code=0x%X button=0x%X data=%v ts=%v }", code, button, strings.ToUpper(hex.EncodeToString(data)), ts)
+
+	// Code and button need to fit into the frame
+	if uint64(code) >= uint64(1)<<(this.bits-this.button_bits) {
+		return nil, gopi.ErrBadParameter
+	}
+	if uint64(button) >= uint64(1)<<this.button_bits {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Set up message
+	m := new(message)
+	m.code = code
+	m.bits = this.bits
+	m.button = button
+	m.source = this
+	m.data = data
+	m.ts = ts
+
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *message) String() string {
+	if this.ts.IsZero() {
+		return fmt.Sprintf("<sensors.Message>{ name='%v' code=0x%X bits=%v button=0x%X data=%v }", this.Name(), this.code, this.bits, this.button, strings.ToUpper(hex.EncodeToString(this.data)))
+	} else {
+		return fmt.Sprintf("<sensors.Message>{ name='%v' code=0x%X bits=%v button=0x%X data=%v ts=%v }", this.Name(), this.code, this.bits, this.button, strings.ToUpper(hex.EncodeToString(this.data)), this.ts.Format(time.Kitchen))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT FixedCodeMessage INTERFACE
+
+func (this *message) Code() uint32 {
+	return this.code
+}
+
+func (this *message) Bits() uint {
+	return this.bits
+}
+
+func (this *message) Button() uint {
+	return this.button
+}
+
+func (this *message) Timestamp() time.Time {
+	return this.ts
+}
+
+func (this *message) Data() []byte {
+	return this.data
+}
+
+func (this *message) Signal() *sensors.RFMSignal {
+	return this.signal
+}
+
+func (this *message) SetSignal(signal *sensors.RFMSignal) {
+	this.signal = signal
+}
+
+func (this *message) IsDuplicate(other sensors.Message) bool {
+	if this.Name() != other.Name() {
+		return false
+	}
+	if other_, ok := other.(sensors.FixedCodeMessage); ok == false {
+		return false
+	} else if this.Code() != other_.Code() {
+		return false
+	} else if this.Bits() != other_.Bits() {
+		return false
+	} else if this.Button() != other_.Button() {
+		return false
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT gopi.Event INTERFACE
+
+func (this *message) Name() string {
+	return this.source.Name()
+}
+
+func (this *message) Source() gopi.Driver {
+	return this.source
+}
@@ -0,0 +1,139 @@
+package protocol_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/sensors/protocol/fixedcode"
+)
+
+func Test_FixedCode_000(t *testing.T) {
+	// Create a fixed-code module
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/fixedcode")); err != nil {
+		t.Fatal(err)
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/fixedcode").(sensors.FixedCodeProto); ok == false {
+		t.Fatal("FixedCode does not comply to FixedCodeProto interface")
+	} else if proto.Mode() != sensors.MIHOME_MODE_CONTROL {
+		t.Error("Unexpected mode", proto.Mode())
+	} else {
+		t.Log(proto)
+	}
+}
+
+func Test_FixedCode_001(t *testing.T) {
+	if proto := FixedCode(); proto == nil {
+		t.Fatal("Missing FixedCode module")
+	} else if msg, err := proto.New(0xABCDE, 0x5, nil); err != nil {
+		t.Fatal(err)
+	} else if msg.Code() != 0xABCDE || msg.Button() != 0x5 || msg.Bits() != 24 {
+		t.Error("Unexpected message", msg)
+	} else if _, err := proto.New(0x100000, 0, nil); err == nil {
+		t.Error("Expected parameter error due to bad code")
+	} else if _, err := proto.New(0x12345, 0x10, nil); err == nil {
+		t.Error("Expected parameter error due to bad button")
+	}
+}
+
+func Test_FixedCode_002(t *testing.T) {
+	if proto := FixedCode(); proto == nil {
+		t.Fatal("Missing FixedCode module")
+	} else if msg, err := proto.New(0x00000, 0xF, nil); err != nil {
+		t.Fatal(err)
+	} else if payload := proto.Encode(msg); len(payload) != 32 {
+		t.Error("Unexpected payload size", len(payload))
+	} else if hex := strings.ToUpper(hex.EncodeToString(payload)); hex != "C000000000000000C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0FCFCFCFC" {
+		t.Error("Unexpected payload", hex)
+	}
+}
+
+func Test_FixedCode_003(t *testing.T) {
+	if proto := FixedCode(); proto == nil {
+		t.Fatal("Missing FixedCode module")
+	} else {
+		for code := uint32(0); code <= uint32(0xFFFFF); code += uint32(0x1357) {
+			if msg_in, err := proto.New(code, uint(code%16), nil); err != nil {
+				t.Fatal(err)
+			} else if msg_out, err := proto.Decode(proto.Encode(msg_in), time.Now()); err != nil {
+				t.Fatal(err)
+			} else if Equals(msg_in, msg_out) == false {
+				t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+			}
+		}
+	}
+}
+
+func Test_FixedCode_004(t *testing.T) {
+	// Decode with noise before the frame and a repeated frame after it
+	if proto := FixedCode(); proto == nil {
+		t.Fatal("Missing FixedCode module")
+	} else if msg_in, err := proto.New(0x12345, 0x8, nil); err != nil {
+		t.Fatal(err)
+	} else {
+		payload := proto.Encode(msg_in)
+		noisy := append([]byte{0x0F, 0x00}, payload...)
+		noisy = append(noisy, payload[:8]...)
+		if msg_out, err := proto.Decode(noisy, time.Now()); err != nil {
+			t.Error(err)
+		} else if Equals(msg_in, msg_out) == false {
+			t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+		} else {
+			t.Log(msg_out)
+		}
+		if _, err := proto.Decode(payload[:20], time.Now()); err == nil {
+			t.Error("Expected error for truncated frame")
+		}
+		if _, err := proto.Decode(payload[8:], time.Now()); err == nil {
+			t.Error("Expected error for missing sync")
+		}
+	}
+}
+
+func Test_FixedCode_005(t *testing.T) {
+	// PT2262 style timing with a 12-bit code and 8 data bits
+	if driver, err := gopi.Open(fixedcode.FixedCode{Bits: 20, ButtonBits: 8, Pulse: 208 * time.Microsecond}, Logger()); err != nil {
+		t.Fatal(err)
+	} else if proto, ok := driver.(sensors.FixedCodeProto); ok == false {
+		t.Fatal("FixedCode does not comply to FixedCodeProto interface")
+	} else if msg_in, err := proto.New(0xABC, 0xD5, nil); err != nil {
+		t.Fatal(err)
+	} else if payload := proto.Encode(msg_in); len(payload) != 14 {
+		t.Error("Unexpected payload size", len(payload))
+	} else if msg_out, err := proto.Decode(payload, time.Now()); err != nil {
+		t.Error(err)
+	} else if Equals(msg_in, msg_out) == false {
+		t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+	} else if _, err := gopi.Open(fixedcode.FixedCode{Bits: 33}, Logger()); err == nil {
+		t.Error("Expected parameter error due to bad bits")
+	} else if _, err := gopi.Open(fixedcode.FixedCode{Ratio: 1}, Logger()); err == nil {
+		t.Error("Expected parameter error due to bad ratio")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FIXED CODE
+
+func FixedCode() sensors.FixedCodeProto {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/fixedcode")); err != nil {
+		return nil
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/fixedcode").(sensors.FixedCodeProto); ok == false {
+		return nil
+	} else {
+		return proto
+	}
+}
+
+func Logger() gopi.Logger {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		return nil
+	} else {
+		return app.Logger
+	}
+}
@@ -0,0 +1,128 @@
+//go:build go1.18
+// +build go1.18
+
+package protocol_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/sensors"
+)
+
+func Fuzz_OT_Decode(f *testing.F) {
+	// Decode with and without CRC checks, so that records are decoded
+	// from corrupted payloads
+	protos := []sensors.OTProto{
+		OTProto(), OTProtoLenient(), OTProtoIgnoreCRC(false), OTProtoIgnoreCRC(true),
+	}
+	for _, proto := range protos {
+		if proto == nil {
+			f.Fatal("Missing OTProto module")
+		}
+	}
+	proto := protos[0]
+
+	// Seed with received messages and a message with every data type
+	for _, str := range received_good {
+		if payload, err := hex.DecodeString(str); err != nil {
+			f.Fatal(err)
+		} else {
+			f.Add(payload)
+		}
+	}
+	if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, 0x03, 0x12345); err != nil {
+		f.Fatal(err)
+	} else {
+		r1, _ := proto.NewFloat(sensors.OT_PARAM_TEMPERATURE, sensors.OT_DATATYPE_DEC_8, 21.5, true)
+		r2, _ := proto.NewInt(sensors.OT_PARAM_REAL_POWER, -100, true)
+		r3, _ := proto.NewString(sensors.OT_PARAM_DEBUG_OUTPUT, "debug", false)
+		r4, _ := proto.NewFloat32(sensors.OT_PARAM_VOLTAGE, 240.5, false)
+		r5, _ := proto.NewNull(sensors.OT_PARAM_JOIN, false)
+		f.Add(proto.Encode(msg.Append(r1, r2, r3, r4, r5)))
+	}
+	f.Add([]byte{})
+	f.Add([]byte{0x0A, 0x04, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		// The payload is not modified, so it can be decoded by the
+		// next protocol
+		data := append([]byte(nil), payload...)
+		for _, proto := range protos {
+			if msg, err := proto.Decode(data, time.Time{}); err == nil {
+				OTExercise(t, proto, msg.(sensors.OTMessage))
+			}
+			if hex.EncodeToString(data) != hex.EncodeToString(payload) {
+				t.Fatal("Payload modified by Decode")
+			}
+		}
+	})
+}
+
+func Fuzz_OOK_Decode(f *testing.F) {
+	ook := OOK()
+	if ook == nil {
+		f.Fatal("Missing OOK module")
+	}
+
+	// Seed with encoded messages
+	for addr := uint32(0); addr <= uint32(0xFFFFF); addr += 0x12345 {
+		for socket := uint(0); socket < uint(5); socket++ {
+			if msg, err := ook.New(addr, socket, addr%2 == 0, nil); err != nil {
+				f.Fatal(err)
+			} else {
+				f.Add(ook.Encode(msg))
+			}
+		}
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		if msg, err := ook.Decode(append([]byte(nil), payload...), time.Time{}); err != nil {
+			return
+		} else if msg_, ok := msg.(sensors.OOKMessage); ok == false {
+			t.Fatal("Expected OOKMessage, got", msg)
+		} else if msg_.Addr() > 0xFFFFF || msg_.Socket() > 4 {
+			t.Error("Unexpected message", msg_)
+		} else if _, err := json.Marshal(msg_); err != nil {
+			t.Error(err)
+		} else {
+			_ = fmt.Sprint(msg_)
+		}
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EXERCISE
+
+// OTExercise calls the methods used on decoded messages, which should
+// never panic, and checks the message can be encoded again
+func OTExercise(t *testing.T, proto sensors.OTProto, msg sensors.OTMessage) {
+	_ = fmt.Sprint(msg)
+	for _, r := range msg.Records() {
+		_ = fmt.Sprint(r)
+		_ = r.Value()
+		_, _ = r.Data()
+		_, _ = r.BoolValue()
+		_, _ = r.IntValue()
+		_, _ = r.UintValue()
+		_, _ = r.FloatValue()
+		_, _ = r.Float32Value()
+		_, _ = r.EnumValue()
+		_, _ = r.StringValue()
+		_, _ = r.TimeValue()
+	}
+	if msg.Sensor() > 0xFFFFFF {
+		t.Error("Unexpected sensor", msg)
+	}
+	if data, err := json.Marshal(msg); err == nil {
+		_, _ = proto.DecodeJSON(data)
+	}
+	if msg.IsPartial() == false {
+		_ = proto.Encode(msg)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// Package chip converts between OOK payloads, where each bit of the
+// payload is a chip at the radio bitrate, and chips or runs of chips
+package chip
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Pack returns chips packed into bytes, most significant bit first
+func Pack(chips []bool) []byte {
+	payload := make([]byte, (len(chips)+7)/8)
+	for i, chip := range chips {
+		if chip {
+			payload[i>>3] |= 0x80 >> uint(i&7)
+		}
+	}
+	return payload
+}
+
+// Runs returns the lengths of runs of chips, which are
+// positive when high and negative when low
+func Runs(payload []byte) []int {
+	runs := make([]int, 0, len(payload))
+	for i := 0; i < len(payload)*8; i++ {
+		high := payload[i>>3]&(0x80>>uint(i&7)) != 0
+		switch {
+		case len(runs) > 0 && high && runs[len(runs)-1] > 0:
+			runs[len(runs)-1]++
+		case len(runs) > 0 && high == false && runs[len(runs)-1] < 0:
+			runs[len(runs)-1]--
+		case high:
+			runs = append(runs, 1)
+		default:
+			runs = append(runs, -1)
+		}
+	}
+	return runs
+}
@@ -0,0 +1,29 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lightwaverf
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register module
+	gopi.RegisterModule(gopi.Module{
+		Name: "sensors/protocol/lightwaverf",
+		Type: gopi.MODULE_TYPE_OTHER,
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			return gopi.Open(LightwaveRF{}, app.Logger)
+		},
+	})
+}
@@ -0,0 +1,297 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lightwaverf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/protocol/internal/chip"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type LightwaveRF struct{}
+
+type lightwaverf struct {
+	log gopi.Logger
+}
+
+type message struct {
+	id      uint32
+	room    uint
+	device  uint
+	command sensors.LightwaveRFCommand
+	level   uint
+	param   uint8
+	source  sensors.Proto
+	data    []byte
+	ts      time.Time
+	signal  *sensors.RFMSignal
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS, GLOBAL VARIABLES
+
+var (
+	// Each nibble is sent as one of these bytes, which all have six one
+	// bits and never more than two zero bits in a row
+	LIGHTWAVERF_NIBBLE = []byte{
+		0xF6, 0xEE, 0xED, 0xEB, 0xDE, 0xDD, 0xDB, 0xBE,
+		0xBD, 0xBB, 0xB7, 0x7E, 0x7D, 0x7B, 0x77, 0x6F,
+	}
+	LIGHTWAVERF_ID_MASK uint32 = 0xFFFFF // Length of the transmitter address is 20 bits
+)
+
+const (
+	// Number of nibbles in a message
+	LIGHTWAVERF_NIBBLES = 10
+
+	// Number of bits in a message, which is a start bit and then
+	// each nibble as a one bit followed by the nibble byte
+	LIGHTWAVERF_BITS = 1 + LIGHTWAVERF_NIBBLES*9
+
+	// A one bit is a pulse of one chip followed by a gap of one chip,
+	// and a zero bit is a gap of five chips
+	LIGHTWAVERF_CHIPS_ONE  = 2
+	LIGHTWAVERF_CHIPS_ZERO = 5
+
+	// Maximum number of chips for a pulse when decoding
+	LIGHTWAVERF_PULSE_MAX = 3
+
+	// Maximum dim level and mood number
+	LIGHTWAVERF_LEVEL_MAX = 0x1F
+)
+
+const (
+	// Commands as sent over the air
+	LIGHTWAVERF_CMD_OFF  byte = 0x00
+	LIGHTWAVERF_CMD_ON   byte = 0x01
+	LIGHTWAVERF_CMD_MOOD byte = 0x02
+
+	// Parameter flag for dim levels and moods
+	LIGHTWAVERF_PARAM_LEVEL byte = 0x80
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config LightwaveRF) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.protocol.LightwaveRF>Open{ }")
+
+	this := new(lightwaverf)
+	this.log = log
+
+	// Return success
+	return this, nil
+}
+
+func (this *lightwaverf) Close() error {
+	this.log.Debug("<sensors.protocol.LightwaveRF>Close{ }")
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// NAME AND MODE
+
+func (this *lightwaverf) String() string {
+	return fmt.Sprintf("<sensors.protocol>{ name='%v' mode=%v }", this.Name(), this.Mode())
+}
+
+func (this *lightwaverf) Name() string {
+	return "lightwaverf"
+}
+
+func (this *lightwaverf) Mode() sensors.MiHomeMode {
+	return sensors.MIHOME_MODE_CONTROL
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE AND DECODE
+
+/*
+ The message is ten nibbles (parameter 2 nibbles, device, command, address
+ 5 nibbles and room) which are sent as a start bit, then each nibble as a one
+ bit followed by the byte for the nibble. At the control mode bitrate the
+ payload is 31 bytes
+*/
+
+func (this *lightwaverf) Encode(msg sensors.Message) []byte {
+	this.log.Debug2("<sensors.protocol.LightwaveRF>Encode{ msg=%v }", msg)
+
+	// Ensure message is of type LightwaveRFMessage
+	msg_, ok := msg.(sensors.LightwaveRFMessage)
+	if ok == false {
+		return nil
+	}
+	command, param, err := encodeCommand(msg_.Command(), msg_.Level(), msg_.Param())
+	if err != nil {
+		return nil
+	}
+
+	// Nibbles
+	id := msg_.ID()
+	nibbles := []byte{
+		param >> 4, param & 0x0F, byte(msg_.Device()), command,
+		byte(id >> 16), byte(id >> 12), byte(id >> 8), byte(id >> 4), byte(id),
+		byte(msg_.Room()),
+	}
+
+	// Start bit then the nibbles
+	chips := make([]bool, 0, LIGHTWAVERF_BITS*LIGHTWAVERF_CHIPS_ZERO)
+	chips = appendBit(chips, true)
+	for _, nibble := range nibbles {
+		chips = appendBit(chips, true)
+		value := LIGHTWAVERF_NIBBLE[nibble&0x0F]
+		for i := 0; i < 8; i++ {
+			chips = appendBit(chips, value&0x80 != 0)
+			value <<= 1
+		}
+	}
+
+	// Pack the chips into bytes and return the payload
+	return chip.Pack(chips)
+}
+
+func (this *lightwaverf) Decode(payload []byte, ts time.Time) (sensors.Message, error) {
+	this.log.Debug2("<sensors.protocol.LightwaveRF>Decode{ payload=%v ts=%v }", strings.ToUpper(hex.EncodeToString(payload)), ts)
+
+	// Check for an empty payload
+	if len(payload) == 0 {
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Decode chips into bits, ignoring any gap before the start bit
+	bits := make([]bool, 0, LIGHTWAVERF_BITS)
+	for _, run := range chip.Runs(payload) {
+		if run > LIGHTWAVERF_PULSE_MAX {
+			return nil, sensors.ErrMessageCorruption
+		} else if run > 0 {
+			bits = append(bits, true)
+		} else if len(bits) > 0 {
+			for zeros := (1 - run) / LIGHTWAVERF_CHIPS_ZERO; zeros > 0; zeros-- {
+				bits = append(bits, false)
+			}
+		}
+		if len(bits) >= LIGHTWAVERF_BITS {
+			break
+		}
+	}
+
+	// Zero bits at the end of the payload may be cut short
+	if len(bits) < LIGHTWAVERF_BITS-2 {
+		this.log.Debug("<sensors.protocol.LightwaveRF>Decode: Message too short")
+		return nil, sensors.ErrMessageCorruption
+	}
+	for len(bits) < LIGHTWAVERF_BITS {
+		bits = append(bits, false)
+	}
+
+	// Decode the nibbles
+	nibbles := make([]byte, LIGHTWAVERF_NIBBLES)
+	for i := range nibbles {
+		offset := 1 + i*9
+		if bits[offset] == false {
+			return nil, sensors.ErrMessageCorruption
+		}
+		value := byte(0)
+		for _, bit := range bits[offset+1 : offset+9] {
+			value <<= 1
+			if bit {
+				value |= 1
+			}
+		}
+		if nibble, err := decodeNibble(value); err != nil {
+			return nil, err
+		} else {
+			nibbles[i] = nibble
+		}
+	}
+
+	// Construct the message
+	param := nibbles[0]<<4 | nibbles[1]
+	id := uint32(0)
+	for _, nibble := range nibbles[4:9] {
+		id = id<<4 | uint32(nibble)
+	}
+	if command, level, err := decodeCommand(nibbles[3], param); err != nil {
+		return nil, err
+	} else if msg, err := this.NewWithTimestamp(id, uint(nibbles[9]), uint(nibbles[2]), command, level, payload, ts); err != nil {
+		return nil, err
+	} else {
+		msg.(*message).param = param
+		return msg, nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// encodeCommand returns the command and parameter to send over the air
+func encodeCommand(command sensors.LightwaveRFCommand, level uint, param uint8) (byte, byte, error) {
+	switch command {
+	case sensors.LIGHTWAVERF_COMMAND_OFF:
+		return LIGHTWAVERF_CMD_OFF, param, nil
+	case sensors.LIGHTWAVERF_COMMAND_ON:
+		return LIGHTWAVERF_CMD_ON, param, nil
+	case sensors.LIGHTWAVERF_COMMAND_DIM:
+		return LIGHTWAVERF_CMD_ON, LIGHTWAVERF_PARAM_LEVEL | byte(level), nil
+	case sensors.LIGHTWAVERF_COMMAND_MOOD:
+		return LIGHTWAVERF_CMD_MOOD, LIGHTWAVERF_PARAM_LEVEL | byte(level), nil
+	default:
+		return 0, 0, gopi.ErrBadParameter
+	}
+}
+
+// decodeCommand returns the command and level from the command
+// and parameter sent over the air
+func decodeCommand(command, param byte) (sensors.LightwaveRFCommand, uint, error) {
+	is_level := param&0xE0 == LIGHTWAVERF_PARAM_LEVEL
+	switch {
+	case command == LIGHTWAVERF_CMD_OFF:
+		return sensors.LIGHTWAVERF_COMMAND_OFF, 0, nil
+	case command == LIGHTWAVERF_CMD_ON && is_level:
+		return sensors.LIGHTWAVERF_COMMAND_DIM, uint(param & LIGHTWAVERF_LEVEL_MAX), nil
+	case command == LIGHTWAVERF_CMD_ON:
+		return sensors.LIGHTWAVERF_COMMAND_ON, 0, nil
+	case command == LIGHTWAVERF_CMD_MOOD:
+		return sensors.LIGHTWAVERF_COMMAND_MOOD, uint(param & LIGHTWAVERF_LEVEL_MAX), nil
+	default:
+		return 0, 0, sensors.ErrMessageCorruption
+	}
+}
+
+func decodeNibble(value byte) (byte, error) {
+	for nibble, v := range LIGHTWAVERF_NIBBLE {
+		if v == value {
+			return byte(nibble), nil
+		}
+	}
+	return 0, sensors.ErrMessageCorruption
+}
+
+// appendBit appends the chips for a one or zero bit
+func appendBit(chips []bool, bit bool) []bool {
+	if bit {
+		return append(chips, true, false)
+	}
+	for i := 0; i < LIGHTWAVERF_CHIPS_ZERO; i++ {
+		chips = append(chips, false)
+	}
+	return chips
+}
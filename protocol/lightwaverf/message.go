@@ -0,0 +1,147 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lightwaverf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CREATE MESSAGE
+
+func (this *lightwaverf) New(id uint32, room, device uint, command sensors.LightwaveRFCommand, level uint) (sensors.LightwaveRFMessage, error) {
+	return this.NewWithTimestamp(id, room, device, command, level, nil, time.Time{})
+}
+
+func (this *lightwaverf) NewWithTimestamp(id uint32, room, device uint, command sensors.LightwaveRFCommand, level uint, data []byte, ts time.Time) (sensors.LightwaveRFMessage, error) {
+	this.log.Debug2("<sensors.protocol.LightwaveRF>New{ id=%05X room=%v device=%v command=%v level=%v data=%v ts=%v }", id, room, device, command, level, strings.ToUpper(hex.EncodeToString(data)), ts)
+
+	// Address is 20-bits
+	if id&LIGHTWAVERF_ID_MASK != id {
+		return nil, gopi.ErrBadParameter
+	}
+	// Room and device are 0-15
+	if room > 0x0F || device > 0x0F {
+		return nil, gopi.ErrBadParameter
+	}
+	// Level is 0-31 and only for dim and mood commands
+	if command > sensors.LIGHTWAVERF_COMMAND_MAX || level > LIGHTWAVERF_LEVEL_MAX {
+		return nil, gopi.ErrBadParameter
+	} else if level != 0 && command != sensors.LIGHTWAVERF_COMMAND_DIM && command != sensors.LIGHTWAVERF_COMMAND_MOOD {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Set up message
+	m := new(message)
+	m.id = id
+	m.room = room
+	m.device = device
+	m.command = command
+	m.level = level
+	m.source = this
+	m.data = data
+	m.ts = ts
+
+	// Set the parameter for dim and mood commands
+	if command == sensors.LIGHTWAVERF_COMMAND_DIM || command == sensors.LIGHTWAVERF_COMMAND_MOOD {
+		m.param = LIGHTWAVERF_PARAM_LEVEL | uint8(level)
+	}
+
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *message) String() string {
+	command := strings.TrimPrefix(fmt.Sprint(this.command), "LIGHTWAVERF_COMMAND_")
+	if this.ts.IsZero() {
+		return fmt.Sprintf("<sensors.Message>{ name='%v' id=0x%05X room=%v device=%v command=%v level=%v param=0x%02X data=%v }", this.Name(), this.id, this.room, this.device, command, this.level, this.param, strings.ToUpper(hex.EncodeToString(this.data)))
+	} else {
+		return fmt.Sprintf("<sensors.Message>{ name='%v' id=0x%05X room=%v device=%v command=%v level=%v param=0x%02X data=%v ts=%v }", this.Name(), this.id, this.room, this.device, command, this.level, this.param, strings.ToUpper(hex.EncodeToString(this.data)), this.ts.Format(time.Kitchen))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT LightwaveRFMessage INTERFACE
+
+func (this *message) ID() uint32 {
+	return this.id & LIGHTWAVERF_ID_MASK
+}
+
+func (this *message) Room() uint {
+	return this.room
+}
+
+func (this *message) Device() uint {
+	return this.device
+}
+
+func (this *message) Command() sensors.LightwaveRFCommand {
+	return this.command
+}
+
+func (this *message) Level() uint {
+	return this.level
+}
+
+func (this *message) Param() uint8 {
+	return this.param
+}
+
+func (this *message) Timestamp() time.Time {
+	return this.ts
+}
+
+func (this *message) Data() []byte {
+	return this.data
+}
+
+func (this *message) Signal() *sensors.RFMSignal {
+	return this.signal
+}
+
+func (this *message) SetSignal(signal *sensors.RFMSignal) {
+	this.signal = signal
+}
+
+func (this *message) IsDuplicate(other sensors.Message) bool {
+	if this.Name() != other.Name() {
+		return false
+	}
+	if other_, ok := other.(sensors.LightwaveRFMessage); ok == false {
+		return false
+	} else if this.ID() != other_.ID() || this.Room() != other_.Room() || this.Device() != other_.Device() {
+		return false
+	} else if this.Command() != other_.Command() || this.Level() != other_.Level() {
+		return false
+	} else if this.Param() != other_.Param() {
+		return false
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT gopi.Event INTERFACE
+
+func (this *message) Name() string {
+	return this.source.Name()
+}
+
+func (this *message) Source() gopi.Driver {
+	return this.source
+}
@@ -0,0 +1,128 @@
+package protocol_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+	_ "github.com/djthorpe/sensors/protocol/lightwaverf"
+)
+
+func Test_LightwaveRF_000(t *testing.T) {
+	// Create a LightwaveRF module
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/lightwaverf")); err != nil {
+		t.Fatal(err)
+	} else if _, ok := app.ModuleInstance("sensors/protocol/lightwaverf").(sensors.LightwaveRFProto); ok == false {
+		t.Fatal("LightwaveRF does not comply to LightwaveRFProto interface")
+	}
+}
+
+func Test_LightwaveRF_001(t *testing.T) {
+	if lwrf := LightwaveRF(); lwrf == nil {
+		t.Fatal("Missing LightwaveRF module")
+	} else if _, err := lwrf.New(0x12345, 1, 2, sensors.LIGHTWAVERF_COMMAND_ON, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_LightwaveRF_002(t *testing.T) {
+	if lwrf := LightwaveRF(); lwrf == nil {
+		t.Fatal("Missing LightwaveRF module")
+	} else if _, err := lwrf.New(0x112345, 1, 2, sensors.LIGHTWAVERF_COMMAND_ON, 0); err == nil {
+		t.Fatal("Expected parameter error due to bad id")
+	} else if _, err := lwrf.New(0x12345, 16, 2, sensors.LIGHTWAVERF_COMMAND_ON, 0); err == nil {
+		t.Fatal("Expected parameter error due to bad room")
+	} else if _, err := lwrf.New(0x12345, 1, 16, sensors.LIGHTWAVERF_COMMAND_ON, 0); err == nil {
+		t.Fatal("Expected parameter error due to bad device")
+	} else if _, err := lwrf.New(0x12345, 1, 2, sensors.LIGHTWAVERF_COMMAND_DIM, 32); err == nil {
+		t.Fatal("Expected parameter error due to bad level")
+	} else if _, err := lwrf.New(0x12345, 1, 2, sensors.LIGHTWAVERF_COMMAND_OFF, 1); err == nil {
+		t.Fatal("Expected parameter error due to level for off command")
+	} else if _, err := lwrf.New(0x12345, 1, 2, sensors.LIGHTWAVERF_COMMAND_MAX+1, 0); err == nil {
+		t.Fatal("Expected parameter error due to bad command")
+	}
+}
+
+func Test_LightwaveRF_003(t *testing.T) {
+	if lwrf := LightwaveRF(); lwrf == nil {
+		t.Fatal("Missing LightwaveRF module")
+	} else if msg, err := lwrf.New(0x789AB, 3, 4, sensors.LIGHTWAVERF_COMMAND_DIM, 16); err != nil {
+		t.Fatal(err)
+	} else if msg.Param() != 0x90 {
+		t.Error("Unexpected param", msg)
+	} else if payload := lwrf.Encode(msg); len(payload) != 31 {
+		t.Error("Unexpected payload size", len(payload))
+	} else {
+		t.Logf("message=%v", msg)
+		t.Logf("  payload=%v", strings.ToUpper(hex.EncodeToString(payload)))
+	}
+}
+
+func Test_LightwaveRF_004(t *testing.T) {
+	if lwrf := LightwaveRF(); lwrf == nil {
+		t.Fatal("Missing LightwaveRF module")
+	} else {
+		for id := uint32(0); id < uint32(0xFFFFF); id += uint32(0x1245) {
+			command := sensors.LightwaveRFCommand(id % uint32(sensors.LIGHTWAVERF_COMMAND_MAX+1))
+			level := uint(0)
+			if command == sensors.LIGHTWAVERF_COMMAND_DIM || command == sensors.LIGHTWAVERF_COMMAND_MOOD {
+				level = uint(id % 32)
+			}
+			if msg_in, err := lwrf.New(id, uint(id%16), uint(id>>4)%16, command, level); err != nil {
+				t.Fatal(err)
+			} else if msg_out, err := lwrf.Decode(lwrf.Encode(msg_in), time.Time{}); err != nil {
+				t.Fatal(msg_in, err)
+			} else if Equals(msg_in, msg_out) == false {
+				t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+			}
+		}
+	}
+}
+
+func Test_LightwaveRF_005(t *testing.T) {
+	if lwrf := LightwaveRF(); lwrf == nil {
+		t.Fatal("Missing LightwaveRF module")
+	} else if msg, err := lwrf.New(0x13579, 15, 0, sensors.LIGHTWAVERF_COMMAND_MOOD, 2); err != nil {
+		t.Fatal(err)
+	} else {
+		payload := lwrf.Encode(msg)
+		// Leading gap before the start bit is ignored
+		if msg_out, err := lwrf.Decode(append([]byte{0x00, 0x00}, payload...), time.Now()); err != nil {
+			t.Error(err)
+		} else if Equals(msg, msg_out) == false {
+			t.Errorf("Messages don't match: %v and %v", msg, msg_out)
+		} else if msg_out.(sensors.LightwaveRFMessage).Level() != 2 {
+			t.Error("Unexpected mood", msg_out)
+		}
+		// Truncated message
+		if _, err := lwrf.Decode(payload[:20], time.Now()); err == nil {
+			t.Error("Expected error for truncated message")
+		}
+		// Corrupted nibble
+		corrupt := append([]byte{}, payload...)
+		corrupt[10] ^= 0xFF
+		if _, err := lwrf.Decode(corrupt, time.Now()); err == nil {
+			t.Error("Expected error for corrupted message")
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LIGHTWAVERF
+
+func LightwaveRF() sensors.LightwaveRFProto {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/lightwaverf")); err != nil {
+		return nil
+	} else if lwrf, ok := app.ModuleInstance("sensors/protocol/lightwaverf").(sensors.LightwaveRFProto); ok == false {
+		return nil
+	} else {
+		return lwrf
+	}
+}
@@ -0,0 +1,86 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ook
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// json_message is the JSON representation of a message
+type json_message struct {
+	Name      string     `json:"name,omitempty"`
+	Addr      uint32     `json:"addr"`
+	Socket    uint       `json:"socket"`
+	State     bool       `json:"state"`
+	Timestamp *time.Time `json:"ts,omitempty"`
+	Data      string     `json:"data,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MARSHAL AND UNMARSHAL
+
+func (this *message) MarshalJSON() ([]byte, error) {
+	m := json_message{
+		Addr:   this.Addr(),
+		Socket: this.socket,
+		State:  this.state,
+		Data:   strings.ToUpper(hex.EncodeToString(this.data)),
+	}
+	if this.source != nil {
+		m.Name = this.Name()
+	}
+	if this.ts.IsZero() == false {
+		m.Timestamp = &this.ts
+	}
+	return json.Marshal(m)
+}
+
+func (this *message) UnmarshalJSON(data []byte) error {
+	var m json_message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	// Address is 20-bits, socket is 0-4 and the name needs to match
+	if m.Addr&OOK_ADDR_MASK != m.Addr || m.Socket > 4 {
+		return gopi.ErrBadParameter
+	} else if this.source != nil && m.Name != "" && m.Name != this.Name() {
+		return gopi.ErrBadParameter
+	}
+	payload, err := hex.DecodeString(m.Data)
+	if err != nil {
+		return err
+	} else if len(payload) == 0 {
+		payload = nil
+	}
+
+	// Set message
+	this.addr = m.Addr
+	this.socket = m.Socket
+	this.state = m.State
+	this.data = payload
+	if m.Timestamp != nil {
+		this.ts = *m.Timestamp
+	} else {
+		this.ts = time.Time{}
+	}
+
+	// Success
+	return nil
+}
@@ -85,6 +85,14 @@ func (this *message) Data() []byte {
 	return this.data
 }
 
+func (this *message) Signal() *sensors.RFMSignal {
+	return this.signal
+}
+
+func (this *message) SetSignal(signal *sensors.RFMSignal) {
+	this.signal = signal
+}
+
 func (this *message) IsDuplicate(other sensors.Message) bool {
 	if this.Name() != other.Name() {
 		return false
@@ -37,6 +37,7 @@ type message struct {
 	source sensors.Proto
 	data   []byte
 	ts     time.Time
+	signal *sensors.RFMSignal
 }
 
 ////////////////////////////////////////////////////////////////////////////////
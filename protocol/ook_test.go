@@ -2,6 +2,7 @@ package protocol_test
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"strings"
 	"testing"
 	"time"
@@ -122,6 +123,32 @@ func Test_OOK_006(t *testing.T) {
 	}
 }
 
+func Test_OOK_007_json(t *testing.T) {
+	if ook := OOK(); ook == nil {
+		t.Fatal("Missing OOK module")
+	} else if msg_in, err := ook.New(0x789AB, 3, true, []byte{0x01, 0x02}); err != nil {
+		t.Fatal(err)
+	} else if data, err := json.Marshal(msg_in); err != nil {
+		t.Fatal(err)
+	} else if msg_out, err := ook.New(0, 0, false, nil); err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(data, msg_out); err != nil {
+		t.Error(err)
+	} else if Equals(msg_in, msg_out) == false {
+		t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+	} else if err := json.Unmarshal([]byte(`{ "addr": 1, "socket": 1, "ts": "2018-11-01T12:00:00Z" }`), msg_out); err != nil {
+		t.Error(err)
+	} else if msg_out.Timestamp().Equal(time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)) == false {
+		t.Error("Unexpected timestamp", msg_out.Timestamp())
+	} else if err := json.Unmarshal([]byte(`{ "addr": 1048576, "socket": 1 }`), msg_out); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := json.Unmarshal([]byte(`{ "addr": 1, "socket": 5 }`), msg_out); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else {
+		t.Log(string(data))
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // OOK
 
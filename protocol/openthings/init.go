@@ -26,9 +26,11 @@ func init() {
 		Config: func(config *gopi.AppConfig) {
 			config.AppFlags.FlagUint("ot.encryption_id", 0, "OpenThings Encryption ID")
 			config.AppFlags.FlagBool("ot.ignore_crc", false, "Ignore CRC checking")
+			config.AppFlags.FlagBool("ot.lenient", false, "Keep unknown and corrupted records when decoding")
 		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
 			ignore_crc, _ := app.AppFlags.GetBool("ot.ignore_crc")
+			lenient, _ := app.AppFlags.GetBool("ot.lenient")
 			encryption_id, _ := app.AppFlags.GetUint("ot.encryption_id")
 			if encryption_id > 0xFF {
 				return nil, errors.New("Invalid -ot.encryption_id flag")
@@ -36,6 +38,7 @@ func init() {
 			return gopi.Open(OpenThings{
 				EncryptionID: uint8(encryption_id),
 				IgnoreCRC:    ignore_crc,
+				Lenient:      lenient,
 			}, app.Logger)
 		},
 	})
@@ -0,0 +1,243 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package openthings
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// json_message is the JSON representation of a message, where the
+// data is the payload the message was decoded from
+type json_message struct {
+	Name         string                 `json:"name,omitempty"`
+	Manufacturer sensors.OTManufacturer `json:"manufacturer"`
+	Product      uint8                  `json:"product"`
+	Sensor       uint32                 `json:"sensor"`
+	Records      []*record              `json:"records"`
+	Partial      bool                   `json:"partial,omitempty"`
+	Timestamp    *time.Time             `json:"ts,omitempty"`
+	Data         string                 `json:"data,omitempty"`
+}
+
+// json_record is the JSON representation of a record. The value is
+// a number, string or null depending on the type, and the data is the
+// value as encoded, which takes precedence over the value when present
+type json_record struct {
+	Name   sensors.OTParameter `json:"name"`
+	Type   sensors.OTDataType  `json:"type"`
+	Value  json.RawMessage     `json:"value,omitempty"`
+	Report bool                `json:"report"`
+	Opaque bool                `json:"opaque,omitempty"`
+	Data   *string             `json:"data,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODE JSON
+
+func (this *openthings) DecodeJSON(data []byte) (sensors.OTMessage, error) {
+	this.log.Debug2("<protocol.openthings>DecodeJSON{ data=%v }", strconv.Quote(string(data)))
+
+	message := new(message)
+	message.source = this
+	if err := json.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+
+	// Return message
+	return message, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MESSAGE
+
+func (this *message) MarshalJSON() ([]byte, error) {
+	m := json_message{
+		Manufacturer: this.manufacturer,
+		Product:      this.product,
+		Sensor:       this.sensor,
+		Records:      make([]*record, 0, len(this.records)),
+		Partial:      this.partial,
+		Data:         strings.ToUpper(hex.EncodeToString(this.data)),
+	}
+	if this.source != nil {
+		m.Name = this.Name()
+	}
+	for _, r := range this.records {
+		if r_, ok := r.(*record); ok == false {
+			return nil, gopi.ErrBadParameter
+		} else {
+			m.Records = append(m.Records, r_)
+		}
+	}
+	if this.ts.IsZero() == false {
+		m.Timestamp = &this.ts
+	}
+	return json.Marshal(m)
+}
+
+func (this *message) UnmarshalJSON(data []byte) error {
+	var m json_message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	// Sensor is 24-bits, and the name needs to match
+	if m.Sensor&0xFFFFFF != m.Sensor {
+		return gopi.ErrBadParameter
+	} else if this.source != nil && m.Name != "" && m.Name != this.Name() {
+		return gopi.ErrBadParameter
+	}
+	payload, err := hex.DecodeString(m.Data)
+	if err != nil {
+		return err
+	} else if len(payload) == 0 {
+		payload = nil
+	}
+
+	// Set message
+	this.manufacturer = m.Manufacturer
+	this.product = m.Product
+	this.sensor = m.Sensor
+	this.partial = m.Partial
+	this.data = payload
+	this.records = make([]sensors.OTRecord, 0, len(m.Records))
+	for _, r := range m.Records {
+		this.records = append(this.records, r)
+	}
+	if m.Timestamp != nil {
+		this.ts = *m.Timestamp
+	} else {
+		this.ts = time.Time{}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RECORD
+
+func (this *record) MarshalJSON() ([]byte, error) {
+	r := json_record{
+		Name:   this._Name,
+		Type:   this._Type,
+		Report: this.report,
+		Opaque: this.opaque,
+	}
+	data := strings.ToUpper(hex.EncodeToString(this._Data))
+	r.Data = &data
+
+	// Null records have a null value, and opaque records with no
+	// name have data but no value
+	if this._Size == 0 {
+		r.Value = json.RawMessage("null")
+	} else if this.opaque == false || this._Name != sensors.OT_PARAM_NONE {
+		if value, err := json.Marshal(this.Value()); err != nil {
+			return nil, err
+		} else {
+			r.Value = value
+		}
+	}
+	return json.Marshal(r)
+}
+
+func (this *record) UnmarshalJSON(data []byte) error {
+	var r json_record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+
+	// Create the record from the encoded data or the value
+	if r.Data != nil {
+		if value, err := hex.DecodeString(*r.Data); err != nil {
+			return err
+		} else if len(value) > 0x0F && r.Opaque == false {
+			return gopi.ErrBadParameter
+		} else {
+			*this = record{
+				_Name:  r.Name,
+				_Type:  r.Type,
+				_Size:  uint8(len(value)),
+				_Data:  value,
+				report: r.Report,
+				opaque: r.Opaque,
+			}
+		}
+	} else if r.Opaque {
+		return gopi.ErrBadParameter
+	} else if other, err := record_for_value(r.Name, r.Type, r.Value, r.Report); err != nil {
+		return err
+	} else {
+		*this = *other
+	}
+
+	// Success
+	return nil
+}
+
+// record_for_value returns a record for a JSON value, which is a number
+// for numeric types, a string for STRING or null for a record with no data
+func record_for_value(name sensors.OTParameter, typ sensors.OTDataType, value json.RawMessage, report bool) (*record, error) {
+	var ot openthings
+	var r sensors.OTRecord
+	var err error
+
+	if len(value) == 0 || string(value) == "null" {
+		r, err = ot.NewNull(name, report)
+	} else if typ == sensors.OT_DATATYPE_STRING {
+		var v string
+		if err := json.Unmarshal(value, &v); err != nil {
+			return nil, err
+		}
+		r, err = ot.NewString(name, v, report)
+	} else {
+		var v json.Number
+		if err := json.Unmarshal(value, &v); err != nil {
+			return nil, err
+		}
+		switch typ {
+		case sensors.OT_DATATYPE_UDEC_0, sensors.OT_DATATYPE_ENUM:
+			if v_, err_ := strconv.ParseUint(v.String(), 10, 64); err_ != nil {
+				return nil, err_
+			} else if typ == sensors.OT_DATATYPE_ENUM {
+				r, err = ot.NewEnum(name, v_, report)
+			} else {
+				r, err = ot.NewUint(name, v_, report)
+			}
+		case sensors.OT_DATATYPE_DEC_0:
+			if v_, err_ := strconv.ParseInt(v.String(), 10, 64); err_ != nil {
+				return nil, err_
+			} else {
+				r, err = ot.NewInt(name, v_, report)
+			}
+		default:
+			if v_, err_ := v.Float64(); err_ != nil {
+				return nil, err_
+			} else {
+				r, err = ot.NewFloat(name, typ, v_, report)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.(*record), nil
+}
@@ -50,10 +50,22 @@ func (this *message) Data() []byte {
 	return this.data
 }
 
+func (this *message) Signal() *sensors.RFMSignal {
+	return this.signal
+}
+
+func (this *message) SetSignal(signal *sensors.RFMSignal) {
+	this.signal = signal
+}
+
 func (this *message) Records() []sensors.OTRecord {
 	return this.records
 }
 
+func (this *message) IsPartial() bool {
+	return this.partial
+}
+
 func (this *message) IsDuplicate(other sensors.Message) bool {
 	if this == other {
 		return true
@@ -118,6 +130,9 @@ func (this *message) String() string {
 	if this.ts.IsZero() == false {
 		params = append(params, fmt.Sprintf("ts=%v", this.Timestamp().Format(time.Kitchen)))
 	}
+	if this.partial {
+		params = append(params, "[partial]")
+	}
 	return fmt.Sprintf("<protocol.openthings.Message>{ %v }", strings.Join(params, " "))
 }
 
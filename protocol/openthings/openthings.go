@@ -29,12 +29,18 @@ type OpenThings struct {
 	EncryptionID uint8
 	IgnoreCRC    bool
 	Seed         int64
+
+	// Lenient decoding keeps the records which can be decoded when
+	// a message contains unknown parameters or corrupted records,
+	// and flags the message as partial
+	Lenient bool
 }
 
 type openthings struct {
 	log           gopi.Logger
 	encryption_id uint8
 	ignore_crc    bool
+	lenient       bool
 }
 
 type message struct {
@@ -46,6 +52,8 @@ type message struct {
 	ts           time.Time
 	pip          uint16
 	data         []byte
+	signal       *sensors.RFMSignal
+	partial      bool
 }
 
 type record struct {
@@ -54,6 +62,7 @@ type record struct {
 	_Type  sensors.OTDataType
 	_Size  uint8
 	_Data  []byte
+	opaque bool
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -73,6 +82,7 @@ func (config OpenThings) Open(log gopi.Logger) (gopi.Driver, error) {
 	this := new(openthings)
 	this.log = log
 	this.ignore_crc = config.IgnoreCRC
+	this.lenient = config.Lenient
 
 	if config.EncryptionID != 0 {
 		this.encryption_id = config.EncryptionID
@@ -84,7 +94,7 @@ func (config OpenThings) Open(log gopi.Logger) (gopi.Driver, error) {
 		config.Seed = time.Now().UnixNano()
 	}
 
-	log.Debug("<protocol.openthings.Open>{ EncryptionID=0x%02X IgnoreCRC=%v Seed=%v }", this.encryption_id, config.IgnoreCRC, config.Seed)
+	log.Debug("<protocol.openthings.Open>{ EncryptionID=0x%02X IgnoreCRC=%v Lenient=%v Seed=%v }", this.encryption_id, config.IgnoreCRC, config.Lenient, config.Seed)
 
 	// Set random seed
 	rand.Seed(config.Seed)
@@ -114,7 +124,7 @@ func (this *openthings) Mode() sensors.MiHomeMode {
 }
 
 func (this *openthings) String() string {
-	return fmt.Sprintf("<sensors.protocol>{ name='%v' mode=%v encryption_id=0x%02X ignore_crc=%v }", this.Name(), this.Mode(), this.encryption_id, this.ignore_crc)
+	return fmt.Sprintf("<sensors.protocol>{ name='%v' mode=%v encryption_id=0x%02X ignore_crc=%v lenient=%v }", this.Name(), this.Mode(), this.encryption_id, this.ignore_crc, this.lenient)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -199,8 +209,9 @@ func (this *openthings) Encode(msg sensors.Message) []byte {
 func (this *openthings) Decode(payload []byte, ts time.Time) (sensors.Message, error) {
 	this.log.Debug2("<protocol.openthings>Decode>{ payload=%v ts=%v }", strings.ToUpper(hex.EncodeToString(payload)), ts)
 
-	// Check minimum message size
-	if len(payload) < OT_MESSAGE_HEADER_SIZE+OT_MESSAGE_FOOTER_SIZE {
+	// Check minimum message size, which ensures the decrypted part of the
+	// payload has the sensor, the zero byte and the CRC
+	if len(payload) < OT_PAYLOAD_MINSIZE {
 		this.log.Debug("<protocol.openthings>Decode: Payload size too short")
 		return nil, sensors.ErrMessageCorruption
 	}
@@ -217,10 +228,11 @@ func (this *openthings) Decode(payload []byte, ts time.Time) (sensors.Message, e
 		return nil, sensors.ErrMessageCorruption
 	}
 
-	// Decrypt packet, check for zero-byte
-	pip := binary.BigEndian.Uint16(payload[3:])
-	decrypted := this.decrypt_message(payload[5:], pip)
-	if zero_byte := decrypted[len(decrypted)-3]; zero_byte != 0x00 {
+	// Decrypt a copy of the packet, so that the payload can be decoded
+	// by other protocols if this one fails, and check for zero-byte
+	pip := binary.BigEndian.Uint16(payload[3:5])
+	decrypted := this.decrypt_message(append([]byte(nil), payload[5:]...), pip)
+	if zero_byte := decrypted[len(decrypted)-3]; zero_byte != 0x00 && this.lenient == false {
 		this.log.Debug("<protocol.openthings>Decode: Missing zero byte before CRC, byte is 0x%02X", zero_byte)
 		return nil, sensors.ErrMessageCorruption
 	}
@@ -233,7 +245,7 @@ func (this *openthings) Decode(payload []byte, ts time.Time) (sensors.Message, e
 	msg.ts = ts
 	msg.sensor = binary.BigEndian.Uint32(decrypted[0:]) & 0xFFFFFF00 >> 8
 	msg.pip = pip
-	msg.data = payload
+	msg.data = append(append(make([]byte, 0, len(payload)), payload[:5]...), decrypted...)
 
 	// Payload CRC value
 	crc := binary.BigEndian.Uint16(decrypted[len(decrypted)-2:])
@@ -245,7 +257,9 @@ func (this *openthings) Decode(payload []byte, ts time.Time) (sensors.Message, e
 	}
 
 	// Decode records
-	if parameters, err := this.decode_parameters(decrypted[3 : len(decrypted)-2]); err != nil {
+	if this.lenient {
+		msg.records, msg.partial = this.decode_parameters_lenient(decrypted[3 : len(decrypted)-2])
+	} else if parameters, err := this.decode_parameters(decrypted[3 : len(decrypted)-2]); err != nil {
 		return nil, err
 	} else {
 		msg.records = parameters
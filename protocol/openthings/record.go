@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
@@ -33,7 +34,7 @@ const (
 
 func (this *openthings) decode_parameters(data []byte) ([]sensors.OTRecord, error) {
 	this.log.Debug2("<protocol.openthings>DecodeParameters{ data=%v }", strings.ToUpper(hex.EncodeToString(data)))
-	if data[len(data)-1] != byte(0x00) {
+	if len(data) == 0 || data[len(data)-1] != byte(0x00) {
 		this.log.Warn("<protocol.openthings>DecodeParameters: Parameters does not end with a zero byte")
 		return nil, sensors.ErrMessageCorruption
 	}
@@ -55,17 +56,11 @@ func (this *openthings) decode_parameters(data []byte) ([]sensors.OTRecord, erro
 			r._Type = sensors.OTDataType((v >> 4) & 0x0F)
 			r._Size = v & 0x0F
 
-			// Check for unsupported data types
-			if r._Type == sensors.OT_DATATYPE_ENUM || r._Type == sensors.OT_DATATYPE_FLOAT {
-				this.log.Warn("<protocol.openthings>DecodeParameters: Type %v is not yet supported", r._Type)
-				return nil, gopi.ErrNotImplemented
-			}
-
 			// For non-zero data sizes, make the data structure for storing data or else
 			// move back into the start-of-record mode
 			if r._Size > 0 {
-				// Sanity check size
-				if r._Size > uint8(len(data)-i-2) {
+				// Sanity check size, which cannot include the terminator
+				if int(r._Size) > len(data)-i-2 {
 					return nil, sensors.ErrMessageCorruption
 				}
 				r._Data = make([]byte, 0, r._Size)
@@ -94,6 +89,55 @@ func (this *openthings) decode_parameters(data []byte) ([]sensors.OTRecord, erro
 	return parameters, nil
 }
 
+// decode_parameters_lenient decodes records until the terminator or the
+// end of the data. Records with unknown parameters are marked as opaque,
+// and when a record is corrupted the remaining data is returned as an
+// opaque record with no name. Returns true if the records are partial
+func (this *openthings) decode_parameters_lenient(data []byte) ([]sensors.OTRecord, bool) {
+	this.log.Debug2("<protocol.openthings>DecodeParametersLenient{ data=%v }", strings.ToUpper(hex.EncodeToString(data)))
+	parameters := make([]sensors.OTRecord, 0, 2)
+	partial := false
+
+	for i := 0; i < len(data); {
+		// Terminator
+		if data[i] == 0x00 {
+			if i != len(data)-1 {
+				this.log.Debug("<protocol.openthings>DecodeParametersLenient: Data after records terminator")
+				parameters = append(parameters, &record{_Data: data[i+1:], opaque: true})
+				partial = true
+			}
+			return parameters, partial
+		}
+
+		// Record is corrupted when the header or data is incomplete
+		if i+1 >= len(data) || i+2+int(data[i+1]&0x0F) > len(data) {
+			this.log.Debug("<protocol.openthings>DecodeParametersLenient: Incomplete record at offset %v", i)
+			parameters = append(parameters, &record{_Data: data[i:], opaque: true})
+			return parameters, true
+		}
+
+		// Decode record, which is opaque when the parameter is unknown
+		r := &record{
+			_Name:  sensors.OTParameter(data[i] & 0x7F),
+			report: (data[i] & 0x80) != 0x00,
+			_Type:  sensors.OTDataType((data[i+1] >> 4) & 0x0F),
+			_Size:  data[i+1] & 0x0F,
+		}
+		r._Data = append(make([]byte, 0, r._Size), data[i+2:i+2+int(r._Size)]...)
+		if is_parameter(r._Name) == false {
+			this.log.Debug("<protocol.openthings>DecodeParametersLenient: Unknown parameter 0x%02X", uint8(r._Name))
+			r.opaque = true
+			partial = true
+		}
+		parameters = append(parameters, r)
+		i += 2 + int(r._Size)
+	}
+
+	// Missing terminator
+	this.log.Debug("<protocol.openthings>DecodeParametersLenient: Missing records terminator")
+	return parameters, true
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CREATE RECORDS
 
@@ -180,6 +224,12 @@ func (this *openthings) NewFloat(name sensors.OTParameter, typ sensors.OTDataTyp
 			r.(*record)._Type = typ
 			return r, nil
 		}
+	case sensors.OT_DATATYPE_FLOAT:
+		if value < -math.MaxFloat32 || value > math.MaxFloat32 {
+			return nil, gopi.ErrBadParameter
+		} else {
+			return this.NewFloat32(name, float32(value), report)
+		}
 	default:
 		return nil, gopi.ErrBadParameter
 	}
@@ -334,17 +384,79 @@ func (this *openthings) NewUint16(name sensors.OTParameter, value uint16, report
 	return record, nil
 }
 
+func (this *openthings) NewEnum(name sensors.OTParameter, value uint64, report bool) (sensors.OTRecord, error) {
+	if r, err := this.NewUint(name, value, report); err != nil {
+		return nil, err
+	} else {
+		r.(*record)._Type = sensors.OT_DATATYPE_ENUM
+		return r, nil
+	}
+}
+
+func (this *openthings) NewFloat32(name sensors.OTParameter, value float32, report bool) (sensors.OTRecord, error) {
+	// Check incoming parameters
+	if name == sensors.OT_PARAM_NONE || name > sensors.OT_PARAM_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Create the record
+	record := new(record)
+	record._Name = name
+	record._Type = sensors.OT_DATATYPE_FLOAT
+	record.report = report
+	record._Data = make([]byte, 4)
+	binary.BigEndian.PutUint32(record._Data, math.Float32bits(value))
+	record._Size = 4
+
+	// Success
+	return record, nil
+}
+
+// NewTime returns a record with the number of seconds since the Unix
+// epoch as a 32-bit unsigned value, which is how OT_PARAM_TIME_AND_DATE
+// is encoded
+func (this *openthings) NewTime(name sensors.OTParameter, value time.Time, report bool) (sensors.OTRecord, error) {
+	// Check incoming parameters
+	if name == sensors.OT_PARAM_NONE || name > sensors.OT_PARAM_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+	seconds := value.Unix()
+	if value.IsZero() || seconds < 0 || seconds > math.MaxUint32 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Create the record
+	record := new(record)
+	record._Name = name
+	record._Type = sensors.OT_DATATYPE_UDEC_0
+	record.report = report
+	record._Data = make([]byte, 4)
+	binary.BigEndian.PutUint32(record._Data, uint32(seconds))
+	record._Size = 4
+
+	// Success
+	return record, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
 func (this *record) String() string {
 	name := strings.TrimPrefix(fmt.Sprint(this._Name), "OT_PARAM_")
+	if is_parameter(this._Name) == false {
+		name = fmt.Sprintf("PARAM_0x%02X", uint8(this._Name))
+	}
 	typ := strings.TrimPrefix(fmt.Sprint(this._Type), "OT_DATATYPE_")
 	req := ""
 	if this.report {
-		req = " [report]"
+		req += " [report]"
+	}
+	if this.opaque {
+		req += " [opaque]"
 	}
-	if value, err := this.StringValue(); err == nil {
+	if this.opaque && this._Name == sensors.OT_PARAM_NONE {
+		return fmt.Sprintf("OPAQUE<Data=%v>", strings.ToUpper(hex.EncodeToString(this._Data)))
+	} else if value, err := this.StringValue(); err == nil {
 		return fmt.Sprintf("%v<%v=%v%v>", name, typ, value, req)
 	} else {
 		return fmt.Sprintf("%v<Type=%v Size=%v Data=%v%v>", name, typ, this._Size, strings.ToUpper(hex.EncodeToString(this._Data)), req)
@@ -366,6 +478,10 @@ func (this *record) IsReport() bool {
 	return this.report
 }
 
+func (this *record) IsOpaque() bool {
+	return this.opaque
+}
+
 func (this *record) IsDuplicate(other sensors.OTRecord) bool {
 	if this == other {
 		return true
@@ -377,6 +493,8 @@ func (this *record) IsDuplicate(other sensors.OTRecord) bool {
 		return false
 	} else if this._Size != other_._Size {
 		return false
+	} else if this.opaque != other_.opaque {
+		return false
 	} else if this.report != other_.report {
 		return false
 	} else {
@@ -393,14 +511,20 @@ func (this *record) IsDuplicate(other sensors.OTRecord) bool {
 }
 
 func (this *record) Data() ([]byte, error) {
+	// Opaque records with no name are returned as-is, and other opaque
+	// records can have any parameter
+	if this.opaque && this._Name == sensors.OT_PARAM_NONE {
+		return append([]byte(nil), this._Data...), nil
+	}
+
 	// Sanity check the record
-	if this._Name == sensors.OT_PARAM_NONE || this._Name > sensors.OT_PARAM_MAX {
+	if this._Name == sensors.OT_PARAM_NONE || (this._Name > sensors.OT_PARAM_MAX && this.opaque == false) {
 		return nil, gopi.ErrBadParameter
 	}
 	if this._Size > 0x0F {
 		return nil, gopi.ErrBadParameter
 	}
-	if this._Type > sensors.OT_DATATYPE_DEC_24 {
+	if this._Type > sensors.OT_DATATYPE_DEC_24 && this._Type != sensors.OT_DATATYPE_ENUM && this._Type != sensors.OT_DATATYPE_FLOAT {
 		return nil, gopi.ErrBadParameter
 	}
 
@@ -429,6 +553,9 @@ func (this *record) Data() ([]byte, error) {
 // OTRecord DECODE VALUES
 
 func (this *record) Value() interface{} {
+	if this.opaque && this._Name == sensors.OT_PARAM_NONE {
+		return append([]byte(nil), this._Data...)
+	}
 	switch this._Type {
 	case sensors.OT_DATATYPE_UDEC_0:
 		if value, err := this.UintValue(); err != nil {
@@ -454,12 +581,18 @@ func (this *record) Value() interface{} {
 		} else {
 			return value
 		}
-	case sensors.OT_DATATYPE_DEC_8, sensors.OT_DATATYPE_DEC_16, sensors.OT_DATATYPE_DEC_24:
+	case sensors.OT_DATATYPE_DEC_8, sensors.OT_DATATYPE_DEC_16, sensors.OT_DATATYPE_DEC_24, sensors.OT_DATATYPE_FLOAT:
 		if value, err := this.FloatValue(); err != nil {
 			return nil
 		} else {
 			return value
 		}
+	case sensors.OT_DATATYPE_ENUM:
+		if value, err := this.EnumValue(); err != nil {
+			return nil
+		} else {
+			return value
+		}
 	default:
 		if value, err := this.Data(); err != nil {
 			return nil
@@ -473,7 +606,7 @@ func (this *record) unsignedDecimalValue() (uint64, error) {
 	if len(this._Data) != int(this._Size) {
 		return 0, gopi.ErrBadParameter
 	}
-	if this._Type == sensors.OT_DATATYPE_UDEC_0 || this._Type == sensors.OT_DATATYPE_UDEC_4 || this._Type == sensors.OT_DATATYPE_UDEC_8 || this._Type == sensors.OT_DATATYPE_UDEC_12 || this._Type == sensors.OT_DATATYPE_UDEC_16 || this._Type == sensors.OT_DATATYPE_UDEC_20 || this._Type == sensors.OT_DATATYPE_UDEC_24 {
+	if this._Type == sensors.OT_DATATYPE_UDEC_0 || this._Type == sensors.OT_DATATYPE_UDEC_4 || this._Type == sensors.OT_DATATYPE_UDEC_8 || this._Type == sensors.OT_DATATYPE_UDEC_12 || this._Type == sensors.OT_DATATYPE_UDEC_16 || this._Type == sensors.OT_DATATYPE_UDEC_20 || this._Type == sensors.OT_DATATYPE_UDEC_24 || this._Type == sensors.OT_DATATYPE_ENUM {
 		switch this._Size {
 		case 0: // null
 			return 0, nil
@@ -516,6 +649,22 @@ func (this *record) signedDecimalValue() (int64, error) {
 	return 0, gopi.ErrNotImplemented
 }
 
+func (this *record) floatingPointValue() (float64, error) {
+	if len(this._Data) != int(this._Size) {
+		return 0, gopi.ErrBadParameter
+	}
+	if this._Type == sensors.OT_DATATYPE_FLOAT {
+		switch this._Size {
+		case 4: // float32
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(this._Data))), nil
+		case 8: // float64
+			return math.Float64frombits(binary.BigEndian.Uint64(this._Data)), nil
+		}
+	}
+	// We don't support converting this value to a floating point value
+	return 0, gopi.ErrNotImplemented
+}
+
 func (this *record) BoolValue() (bool, error) {
 	switch this._Type {
 	case sensors.OT_DATATYPE_UDEC_0:
@@ -629,12 +778,59 @@ func (this *record) FloatValue() (float64, error) {
 		} else {
 			return float64(value) / float64(1<<24), nil
 		}
+	case sensors.OT_DATATYPE_FLOAT:
+		if value, err := this.floatingPointValue(); err != nil {
+			return 0, err
+		} else {
+			return value, nil
+		}
 	default:
 		return 0, gopi.ErrNotImplemented
 	}
 
 }
 
+func (this *record) EnumValue() (uint64, error) {
+	switch this._Type {
+	case sensors.OT_DATATYPE_ENUM:
+		if value, err := this.unsignedDecimalValue(); err != nil {
+			return 0, err
+		} else {
+			return value, nil
+		}
+	default:
+		return 0, gopi.ErrNotImplemented
+	}
+}
+
+func (this *record) Float32Value() (float32, error) {
+	switch {
+	case this._Type == sensors.OT_DATATYPE_FLOAT && this._Size == 4:
+		if value, err := this.floatingPointValue(); err != nil {
+			return 0, err
+		} else {
+			return float32(value), nil
+		}
+	default:
+		return 0, gopi.ErrNotImplemented
+	}
+}
+
+func (this *record) TimeValue() (time.Time, error) {
+	switch this._Type {
+	case sensors.OT_DATATYPE_UDEC_0:
+		if value, err := this.unsignedDecimalValue(); err != nil {
+			return time.Time{}, err
+		} else if this._Size == 0 || value > math.MaxUint32 {
+			return time.Time{}, gopi.ErrBadParameter
+		} else {
+			return time.Unix(int64(value), 0).UTC(), nil
+		}
+	default:
+		return time.Time{}, gopi.ErrNotImplemented
+	}
+}
+
 func (this *record) StringValue() (string, error) {
 	switch this._Type {
 	case sensors.OT_DATATYPE_UDEC_0:
@@ -677,7 +873,31 @@ func (this *record) StringValue() (string, error) {
 		}
 	case sensors.OT_DATATYPE_STRING:
 		return string(this._Data), nil // Assume string is in UTF-8
+	case sensors.OT_DATATYPE_ENUM:
+		if v, err := this.EnumValue(); err != nil {
+			return "", err
+		} else {
+			return fmt.Sprint(v), nil
+		}
+	case sensors.OT_DATATYPE_FLOAT:
+		if v, err := this.FloatValue(); err != nil {
+			return "", err
+		} else {
+			return fmt.Sprint(v), nil
+		}
 	default:
 		return "", gopi.ErrNotImplemented
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// is_parameter returns true if the parameter is a known parameter
+func is_parameter(name sensors.OTParameter) bool {
+	if name == sensors.OT_PARAM_NONE || name > sensors.OT_PARAM_MAX {
+		return false
+	} else {
+		return strings.HasPrefix(fmt.Sprint(name), "[??") == false
+	}
+}
@@ -2,6 +2,8 @@ package protocol_test
 
 import (
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"math"
 	"math/rand"
 	"strings"
@@ -907,6 +909,353 @@ func Test_OT_033_decode_encode(t *testing.T) {
 	}
 }
 
+func Test_OT_034_enum(t *testing.T) {
+	if proto := OTProto(); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else {
+		if _, err := proto.NewEnum(sensors.OT_PARAM_NONE, 0, false); err == nil {
+			t.Error("Expected bad parameter")
+		} else if record, err := proto.NewEnum(sensors.OT_PARAM_ALARM, 3, false); err != nil {
+			t.Error(err)
+		} else if record.Name() != sensors.OT_PARAM_ALARM {
+			t.Error("Expected name=OT_PARAM_ALARM")
+		} else if record.Type() != sensors.OT_DATATYPE_ENUM {
+			t.Error("Expected type=OT_DATATYPE_ENUM")
+		} else if value, err := record.EnumValue(); err != nil {
+			t.Error(err)
+		} else if value != 3 {
+			t.Error("Expected value=3")
+		} else if _, err := record.UintValue(); err != gopi.ErrNotImplemented {
+			t.Error("Expected ErrNotImplemented, got", err)
+		} else if value, err := record.StringValue(); err != nil {
+			t.Error(err)
+		} else if value != "3" {
+			t.Error("Expected value=3, got", value)
+		} else if data, err := record.Data(); err != nil {
+			t.Error(err)
+		} else if data_str := strings.ToUpper(hex.EncodeToString(data)); data_str != "21C103" {
+			t.Error("Expected data=21C103, got", data_str)
+		} else {
+			t.Log("ENUM=", record)
+		}
+	}
+}
+
+func Test_OT_035_float32(t *testing.T) {
+	if proto := OTProto(); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else {
+		if _, err := proto.NewFloat32(sensors.OT_PARAM_NONE, 0, false); err == nil {
+			t.Error("Expected bad parameter")
+		} else if record, err := proto.NewFloat32(sensors.OT_PARAM_TEMPERATURE, 21.5, true); err != nil {
+			t.Error(err)
+		} else if record.Type() != sensors.OT_DATATYPE_FLOAT {
+			t.Error("Expected type=OT_DATATYPE_FLOAT")
+		} else if value, err := record.Float32Value(); err != nil {
+			t.Error(err)
+		} else if value != 21.5 {
+			t.Error("Expected value=21.5, got", value)
+		} else if value, err := record.FloatValue(); err != nil {
+			t.Error(err)
+		} else if value != 21.5 {
+			t.Error("Expected value=21.5, got", value)
+		} else if data, err := record.Data(); err != nil {
+			t.Error(err)
+		} else if data_str := strings.ToUpper(hex.EncodeToString(data)); data_str != "F4F441AC0000" {
+			t.Error("Expected data=F4F441AC0000, got", data_str)
+		} else {
+			t.Log("FLOAT=", record)
+		}
+
+		// NewFloat with the FLOAT type creates a four-byte float
+		if record, err := proto.NewFloat(sensors.OT_PARAM_TEMPERATURE, sensors.OT_DATATYPE_FLOAT, -0.25, false); err != nil {
+			t.Error(err)
+		} else if data, err := record.Data(); err != nil {
+			t.Error(err)
+		} else if data_str := strings.ToUpper(hex.EncodeToString(data)); data_str != "74F4BE800000" {
+			t.Error("Expected data=74F4BE800000, got", data_str)
+		} else if _, err := proto.NewFloat(sensors.OT_PARAM_TEMPERATURE, sensors.OT_DATATYPE_FLOAT, math.MaxFloat64, false); err == nil {
+			t.Error("Expected bad parameter")
+		}
+	}
+}
+
+func Test_OT_036_encode_decode_enum_float(t *testing.T) {
+	if proto := OTProto(); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, 0xFF, 0x12345); err != nil {
+		t.Fatal(err)
+	} else {
+		if enum, err := proto.NewEnum(sensors.OT_PARAM_ALARM, 0x1234, false); err != nil {
+			t.Error(err)
+		} else {
+			msg.Append(enum)
+		}
+		if float, err := proto.NewFloat32(sensors.OT_PARAM_TEMPERATURE, -12.75, true); err != nil {
+			t.Error(err)
+		} else {
+			msg.Append(float)
+		}
+		if encoded := proto.Encode(msg); len(encoded) == 0 {
+			t.Error("Expected encoded value")
+		} else if decoded, err := proto.Decode(encoded, time.Now()); err != nil {
+			t.Error(err)
+		} else if msg.IsDuplicate(decoded) == false {
+			t.Error("Messages not identical", msg, " and ", decoded)
+		} else if records := decoded.(sensors.OTMessage).Records(); len(records) != 2 {
+			t.Error("Expected two records, got", records)
+		} else if value, ok := records[0].Value().(uint64); ok == false || value != 0x1234 {
+			t.Error("Unexpected enum value", records[0])
+		} else if value, ok := records[1].Value().(float64); ok == false || value != -12.75 {
+			t.Error("Unexpected float value", records[1])
+		} else {
+			t.Log(msg, "=>", strings.ToUpper(hex.EncodeToString(encoded)), "=>", decoded)
+		}
+	}
+}
+
+func Test_OT_037_lenient(t *testing.T) {
+	if proto := OTProto(); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else if lenient := OTProtoLenient(); lenient == nil {
+		t.Fatal("Missing OTProto module")
+	} else if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, 0xFF, 0x12345); err != nil {
+		t.Fatal(err)
+	} else {
+		// Parameter 0x22 is not a known parameter
+		if unknown, err := proto.NewUint(sensors.OTParameter(0x22), 0x42, false); err != nil {
+			t.Error(err)
+		} else {
+			msg.Append(unknown)
+		}
+		if temperature, err := proto.NewFloat(sensors.OT_PARAM_TEMPERATURE, sensors.OT_DATATYPE_DEC_8, 21.5, true); err != nil {
+			t.Error(err)
+		} else {
+			msg.Append(temperature)
+		}
+		encoded := proto.Encode(msg)
+		if len(encoded) == 0 {
+			t.Fatal("Expected encoded value")
+		}
+		// Decoding decrypts in place, so decode copies of the payload
+		if decoded, err := proto.Decode(append([]byte(nil), encoded...), time.Now()); err != nil {
+			t.Error(err)
+		} else if decoded.(sensors.OTMessage).IsPartial() {
+			t.Error("Expected strict message not to be partial", decoded)
+		}
+		if decoded, err := lenient.Decode(append([]byte(nil), encoded...), time.Now()); err != nil {
+			t.Error(err)
+		} else if decoded.(sensors.OTMessage).IsPartial() == false {
+			t.Error("Expected lenient message to be partial", decoded)
+		} else if records := decoded.(sensors.OTMessage).Records(); len(records) != 2 {
+			t.Error("Expected two records, got", records)
+		} else if records[0].IsOpaque() == false || records[0].Name() != sensors.OTParameter(0x22) {
+			t.Error("Expected opaque record, got", records[0])
+		} else if strings.Contains(fmt.Sprint(records[0]), "PARAM_0x22") == false {
+			t.Error("Unexpected opaque record string", records[0])
+		} else if data, err := records[0].Data(); err != nil || len(data) != 3 {
+			t.Error("Unexpected opaque record data", data, err)
+		} else if records[1].IsOpaque() || records[1].Name() != sensors.OT_PARAM_TEMPERATURE {
+			t.Error("Expected temperature record, got", records[1])
+		} else {
+			t.Log(msg, "=>", decoded)
+		}
+	}
+}
+
+func Test_OT_038_json(t *testing.T) {
+	if proto := OTProto(); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, 0x03, 0x12345); err != nil {
+		t.Fatal(err)
+	} else {
+		if temperature, err := proto.NewFloat(sensors.OT_PARAM_TEMPERATURE, sensors.OT_DATATYPE_DEC_8, 21.5, true); err != nil {
+			t.Error(err)
+		} else if power, err := proto.NewInt(sensors.OT_PARAM_REAL_POWER, -100, true); err != nil {
+			t.Error(err)
+		} else if voltage, err := proto.NewUint(sensors.OT_PARAM_VOLTAGE, 240, false); err != nil {
+			t.Error(err)
+		} else if name, err := proto.NewString(sensors.OT_PARAM_DEBUG_OUTPUT, "debug", true); err != nil {
+			t.Error(err)
+		} else if null, err := proto.NewNull(sensors.OT_PARAM_JOIN, false); err != nil {
+			t.Error(err)
+		} else {
+			msg.Append(temperature, power, voltage, name, null)
+		}
+		if data, err := json.Marshal(msg); err != nil {
+			t.Fatal(err)
+		} else if strings.Contains(string(data), `"manufacturer":"OT_MANUFACTURER_ENERGENIE"`) == false {
+			t.Error("Unexpected manufacturer", string(data))
+		} else if strings.Contains(string(data), `"name":"OT_PARAM_TEMPERATURE","type":"OT_DATATYPE_DEC_8","value":21.5,"report":true`) == false {
+			t.Error("Unexpected temperature", string(data))
+		} else if decoded, err := proto.DecodeJSON(data); err != nil {
+			t.Error(err)
+		} else if Equals(msg, decoded) == false {
+			t.Error("Messages don't match", msg, decoded)
+		} else if records := decoded.Records(); records[0].IsReport() == false || records[2].IsReport() {
+			t.Error("Unexpected report flags", records)
+		} else if bytes_in, bytes_out := proto.Encode(msg), proto.Encode(decoded); len(bytes_in) != len(bytes_out) {
+			t.Error("Unexpected encoded length", bytes_in, bytes_out)
+		} else {
+			t.Log(string(data), "=>", decoded)
+		}
+	}
+}
+
+func Test_OT_039_decode_json(t *testing.T) {
+	proto := OTProto()
+	if proto == nil {
+		t.Fatal("Missing OTProto module")
+	}
+	if msg, err := proto.DecodeJSON([]byte(`{
+		"manufacturer": "OT_MANUFACTURER_ENERGENIE",
+		"product": 3,
+		"sensor": 74565,
+		"ts": "2018-11-01T12:00:00Z",
+		"records": [
+			{ "name": "OT_PARAM_TEMPERATURE", "type": "OT_DATATYPE_DEC_8", "value": 19.25, "report": true },
+			{ "name": "0x22", "type": "OT_DATATYPE_UDEC_0", "value": 66, "report": false },
+			{ "name": "OT_PARAM_JOIN", "type": "OT_DATATYPE_UDEC_0", "value": null, "report": false }
+		]
+	}`)); err != nil {
+		t.Fatal(err)
+	} else if msg.Manufacturer() != sensors.OT_MANUFACTURER_ENERGENIE || msg.Product() != 3 || msg.Sensor() != 0x12345 {
+		t.Error("Unexpected message", msg)
+	} else if msg.Timestamp().Equal(time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)) == false {
+		t.Error("Unexpected timestamp", msg.Timestamp())
+	} else if records := msg.Records(); len(records) != 3 {
+		t.Error("Expected three records, got", records)
+	} else if records[0].Name() != sensors.OT_PARAM_TEMPERATURE || records[0].IsReport() == false {
+		t.Error("Unexpected record", records[0])
+	} else if value, err := records[0].FloatValue(); err != nil || value != 19.25 {
+		t.Error("Unexpected value", value, err)
+	} else if records[1].Name() != sensors.OTParameter(0x22) {
+		t.Error("Unexpected record", records[1])
+	} else if value, err := records[1].UintValue(); err != nil || value != 66 {
+		t.Error("Unexpected value", value, err)
+	} else if data, err := records[2].Data(); err != nil || len(data) != 2 {
+		t.Error("Unexpected null record", data, err)
+	}
+
+	// Invalid JSON
+	for _, data := range []string{
+		`{ "manufacturer": "OT_MANUFACTURER_UNKNOWN", "product": 3, "sensor": 1, "records": [] }`,
+		`{ "manufacturer": "OT_MANUFACTURER_ENERGENIE", "product": 3, "sensor": 16777216, "records": [] }`,
+		`{ "manufacturer": "OT_MANUFACTURER_ENERGENIE", "product": 3, "sensor": 1, "records": [ { "name": "OT_PARAM_UNKNOWN", "type": "OT_DATATYPE_UDEC_0", "value": 1 } ] }`,
+		`{ "manufacturer": "OT_MANUFACTURER_ENERGENIE", "product": 3, "sensor": 1, "records": [ { "name": "OT_PARAM_TEMPERATURE", "type": "OT_DATATYPE_UDEC_0", "value": -1 } ] }`,
+		`{ "manufacturer": "OT_MANUFACTURER_ENERGENIE", "product": 3, "sensor": 1, "records": [ { "name": "OT_PARAM_TEMPERATURE", "type": "OT_DATATYPE_UDEC_0", "data": "XX" } ] }`,
+	} {
+		if msg, err := proto.DecodeJSON([]byte(data)); err == nil {
+			t.Error("Expected error for", data, "got", msg)
+		}
+	}
+}
+
+func Test_OT_040_json_opaque(t *testing.T) {
+	if proto := OTProto(); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else if lenient := OTProtoLenient(); lenient == nil {
+		t.Fatal("Missing OTProto module")
+	} else if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, 0xFF, 0x12345); err != nil {
+		t.Fatal(err)
+	} else if unknown, err := proto.NewUint(sensors.OTParameter(0x22), 0x42, false); err != nil {
+		t.Fatal(err)
+	} else if decoded, err := lenient.Decode(proto.Encode(msg.Append(unknown)), time.Now()); err != nil {
+		t.Fatal(err)
+	} else if data, err := json.Marshal(decoded); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(data), `"opaque":true`) == false {
+		t.Error("Expected opaque record", string(data))
+	} else if msg2, err := lenient.DecodeJSON(data); err != nil {
+		t.Error(err)
+	} else if msg2.IsPartial() == false || msg2.Records()[0].IsOpaque() == false {
+		t.Error("Expected partial message with opaque record", msg2)
+	} else if data2, err := json.Marshal(msg2); err != nil {
+		t.Error(err)
+	} else if string(data) != string(data2) {
+		t.Errorf("JSON doesn't match: %v and %v", string(data), string(data2))
+	}
+}
+
+func Test_OT_041_corrupted(t *testing.T) {
+	if proto := OTProtoIgnoreCRC(false); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else if lenient := OTProtoIgnoreCRC(true); lenient == nil {
+		t.Fatal("Missing OTProto module")
+	} else if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, 0x03, 0x12345); err != nil {
+		t.Fatal(err)
+	} else if temperature, err := proto.NewFloat(sensors.OT_PARAM_TEMPERATURE, sensors.OT_DATATYPE_DEC_8, 21.5, true); err != nil {
+		t.Fatal(err)
+	} else {
+		// Encryption is a stream cipher, so flipping bits in the encrypted
+		// payload flips the same bits once decrypted. Set the size nibble
+		// of the record to 15 bytes, which is longer than the payload
+		payload := proto.Encode(msg.Append(temperature))
+		payload[9] ^= 0x0F
+		data := append([]byte(nil), payload...)
+		if _, err := proto.Decode(data, time.Now()); err != sensors.ErrMessageCorruption {
+			t.Error("Expected ErrMessageCorruption, got", err)
+		} else if hex.EncodeToString(data) != hex.EncodeToString(payload) {
+			t.Error("Payload modified by Decode")
+		} else if decoded, err := lenient.Decode(data, time.Now()); err != nil {
+			t.Error(err)
+		} else if decoded.(sensors.OTMessage).IsPartial() == false {
+			t.Error("Expected partial message, got", decoded)
+		}
+
+		// Truncated payloads
+		for size := 0; size < len(payload); size++ {
+			truncated := append([]byte(nil), payload[:size]...)
+			if size > 0 {
+				truncated[0] = byte(size - 1)
+			}
+			if _, err := proto.Decode(truncated, time.Now()); err == nil {
+				t.Error("Expected error for size", size)
+			}
+			lenient.Decode(truncated, time.Now())
+		}
+	}
+}
+
+func Test_OT_042_time(t *testing.T) {
+	if proto := OTProto(); proto == nil {
+		t.Fatal("Missing OTProto module")
+	} else if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, 0x03, 0x12345); err != nil {
+		t.Fatal(err)
+	} else {
+		ts := time.Date(2019, 1, 5, 10, 0, 0, 0, time.UTC)
+		if record, err := proto.NewTime(sensors.OT_PARAM_TIME_AND_DATE, ts.Add(500*time.Millisecond), true); err != nil {
+			t.Error(err)
+		} else if record.Type() != sensors.OT_DATATYPE_UDEC_0 {
+			t.Error("Unexpected type", record.Type())
+		} else if data, err := record.Data(); err != nil || hex.EncodeToString(data) != "d4045c308020" {
+			t.Error("Unexpected data", hex.EncodeToString(data), err)
+		} else if decoded, err := proto.Decode(proto.Encode(msg.Append(record)), time.Now()); err != nil {
+			t.Error(err)
+		} else if value, err := decoded.(sensors.OTMessage).Records()[0].TimeValue(); err != nil {
+			t.Error(err)
+		} else if value.Equal(ts) == false {
+			t.Error("Unexpected time", value)
+		}
+		for _, ts := range []time.Time{
+			time.Time{}, time.Unix(-1, 0), time.Unix(math.MaxUint32+1, 0),
+		} {
+			if _, err := proto.NewTime(sensors.OT_PARAM_TIME_AND_DATE, ts, true); err != gopi.ErrBadParameter {
+				t.Error("Expected ErrBadParameter for", ts, "got", err)
+			}
+		}
+		if record, err := proto.NewNull(sensors.OT_PARAM_TIME_AND_DATE, true); err != nil {
+			t.Error(err)
+		} else if _, err := record.TimeValue(); err == nil {
+			t.Error("Expected error for null record")
+		} else if record, err := proto.NewString(sensors.OT_PARAM_TIME_AND_DATE, "now", true); err != nil {
+			t.Error(err)
+		} else if _, err := record.TimeValue(); err != gopi.ErrNotImplemented {
+			t.Error("Expected ErrNotImplemented, got", err)
+		}
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // OT
 
@@ -924,3 +1273,28 @@ func OTProto() sensors.OTProto {
 		return proto
 	}
 }
+
+func OTProtoLenient() sensors.OTProto {
+	config := gopi.NewAppConfig("sensors/protocol/openthings")
+	config.AppFlags.SetBool("ot.lenient", true)
+	if app, err := gopi.NewAppInstance(config); err != nil {
+		return nil
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/openthings").(sensors.OTProto); ok == false {
+		return nil
+	} else {
+		return proto
+	}
+}
+
+func OTProtoIgnoreCRC(lenient bool) sensors.OTProto {
+	config := gopi.NewAppConfig("sensors/protocol/openthings")
+	config.AppFlags.SetBool("ot.ignore_crc", true)
+	config.AppFlags.SetBool("ot.lenient", lenient)
+	if app, err := gopi.NewAppInstance(config); err != nil {
+		return nil
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/openthings").(sensors.OTProto); ok == false {
+		return nil
+	} else {
+		return proto
+	}
+}
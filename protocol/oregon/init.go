@@ -0,0 +1,35 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package oregon
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register module
+	gopi.RegisterModule(gopi.Module{
+		Name: "sensors/protocol/oregon",
+		Type: gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("oregon.bitrate", OREGON_BITRATE, "Radio bitrate in control mode")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			bitrate, _ := app.AppFlags.GetUint("oregon.bitrate")
+			return gopi.Open(Oregon{
+				Bitrate: bitrate,
+			}, app.Logger)
+		},
+	})
+}
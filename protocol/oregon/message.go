@@ -0,0 +1,166 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package oregon
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CREATE MESSAGE
+
+func (this *oregon) New(sensor sensors.OregonSensor, channel uint, code uint8, temperature float64, humidity uint, battery_low bool) (sensors.OregonMessage, error) {
+	return this.NewWithTimestamp(sensor, channel, code, temperature, humidity, battery_low, nil, time.Time{})
+}
+
+func (this *oregon) NewWithTimestamp(sensor sensors.OregonSensor, channel uint, code uint8, temperature float64, humidity uint, battery_low bool, data []byte, ts time.Time) (sensors.OregonMessage, error) {
+	this.log.Debug2("<sensors.protocol.Oregon>New{ sensor=%v channel=%v code=0x%02X temperature=%v humidity=%v battery_low=%v data=%v ts=%v }", sensor, channel, code, temperature, humidity, battery_low, strings.ToUpper(hex.EncodeToString(data)), ts)
+
+	// Sensor needs to be known
+	info, exists := oregon_sensors[sensor]
+	if exists == false {
+		return nil, gopi.ErrBadParameter
+	}
+	// Channel is 1-3 for v2.1 and 1-15 for v3
+	if channel == 0 || (info.version == 2 && channel > 3) || channel > 0x0F {
+		return nil, gopi.ErrBadParameter
+	}
+	// Temperature and humidity need to fit into BCD
+	if math.Abs(temperature) > OREGON_TEMPERATURE_MAX || humidity > OREGON_HUMIDITY_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Set up message
+	m := new(message)
+	m.sensor = sensor
+	m.channel = channel
+	m.code = code
+	m.battery_low = battery_low
+	m.source = this
+	m.data = data
+	m.ts = ts
+
+	// Temperature is rounded to the nearest tenth of a degree
+	if record, err := this.ot.NewFloat32(sensors.OT_PARAM_TEMPERATURE, float32(math.Round(temperature*10)/10), true); err != nil {
+		return nil, err
+	} else {
+		m.records = append(m.records, record)
+	}
+	if info.humidity {
+		if record, err := this.ot.NewUint8(sensors.OT_PARAM_RELATIVE_HUMIDITY, uint8(humidity), true); err != nil {
+			return nil, err
+		} else {
+			m.records = append(m.records, record)
+		}
+	}
+
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *message) String() string {
+	sensor := strings.TrimPrefix(fmt.Sprint(this.sensor), "OREGON_SENSOR_")
+	params := fmt.Sprintf("name='%v' sensor=%v channel=%v code=0x%02X", this.Name(), sensor, this.channel, this.code)
+	for _, record := range this.records {
+		params += fmt.Sprint(" ", record)
+	}
+	if this.battery_low {
+		params += " battery_low=true"
+	}
+	params += " data=" + strings.ToUpper(hex.EncodeToString(this.data))
+	if this.ts.IsZero() == false {
+		params += " ts=" + this.ts.Format(time.Kitchen)
+	}
+	return fmt.Sprintf("<sensors.Message>{ %v }", params)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT OregonMessage INTERFACE
+
+func (this *message) SensorType() sensors.OregonSensor {
+	return this.sensor
+}
+
+func (this *message) Channel() uint {
+	return this.channel
+}
+
+func (this *message) RollingCode() uint8 {
+	return this.code
+}
+
+func (this *message) BatteryLow() bool {
+	return this.battery_low
+}
+
+func (this *message) Records() []sensors.OTRecord {
+	return this.records
+}
+
+func (this *message) Timestamp() time.Time {
+	return this.ts
+}
+
+func (this *message) Data() []byte {
+	return this.data
+}
+
+func (this *message) Signal() *sensors.RFMSignal {
+	return this.signal
+}
+
+func (this *message) SetSignal(signal *sensors.RFMSignal) {
+	this.signal = signal
+}
+
+func (this *message) IsDuplicate(other sensors.Message) bool {
+	if this.Name() != other.Name() {
+		return false
+	}
+	other_, ok := other.(sensors.OregonMessage)
+	if ok == false {
+		return false
+	} else if this.SensorType() != other_.SensorType() || this.Channel() != other_.Channel() || this.RollingCode() != other_.RollingCode() {
+		return false
+	} else if this.BatteryLow() != other_.BatteryLow() {
+		return false
+	}
+	records := other_.Records()
+	if len(this.records) != len(records) {
+		return false
+	}
+	for i, record := range this.records {
+		if record.IsDuplicate(records[i]) == false {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT gopi.Event INTERFACE
+
+func (this *message) Name() string {
+	return this.source.Name()
+}
+
+func (this *message) Source() gopi.Driver {
+	return this.source
+}
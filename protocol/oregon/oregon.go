@@ -0,0 +1,472 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package oregon
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/protocol/internal/chip"
+	"github.com/djthorpe/sensors/protocol/openthings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type Oregon struct {
+	Bitrate uint // Radio bitrate in control mode
+}
+
+type oregon struct {
+	log  gopi.Logger
+	ot   sensors.OTProto // Used to create records
+	half float64         // Number of chips in half a Manchester bit
+}
+
+type message struct {
+	sensor      sensors.OregonSensor
+	channel     uint
+	code        uint8
+	battery_low bool
+	records     []sensors.OTRecord
+	source      sensors.Proto
+	data        []byte
+	ts          time.Time
+	signal      *sensors.RFMSignal
+}
+
+type sensor_info struct {
+	version  uint // Protocol version, which is 2 for v2.1 or 3 for v3
+	humidity bool // True if the sensor measures humidity
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS, GLOBAL VARIABLES
+
+const (
+	// Default radio bitrate in control mode
+	OREGON_BITRATE = 4800
+
+	// Manchester bit rate
+	OREGON_CLOCK = 1024
+
+	// Number of one bits in the preamble for v2.1 and v3, and the minimum
+	// number of preamble bits when decoding
+	OREGON_PREAMBLE_V2  = 16
+	OREGON_PREAMBLE_V3  = 24
+	OREGON_PREAMBLE_MIN = 8
+
+	// Sync nibble after the preamble
+	OREGON_SYNC = 0x0A
+
+	// Number of nibbles in a message before the checksum
+	OREGON_NIBBLES_TEMPERATURE = 12
+	OREGON_NIBBLES_HUMIDITY    = 15
+
+	// Flags nibble
+	OREGON_FLAG_BATTERY_LOW = 0x04
+
+	// Maximum absolute temperature and humidity, which are sent as BCD
+	OREGON_TEMPERATURE_MAX = 99.9
+	OREGON_HUMIDITY_MAX    = 99
+
+	// Maximum size of an encoded payload in bytes
+	OREGON_PAYLOAD_MAX = 0xFF
+)
+
+var (
+	oregon_sensors = map[sensors.OregonSensor]sensor_info{
+		sensors.OREGON_SENSOR_THGR122N: {2, true},
+		sensors.OREGON_SENSOR_THGR228N: {2, true},
+		sensors.OREGON_SENSOR_THGR328N: {2, true},
+		sensors.OREGON_SENSOR_THN132N:  {2, false},
+		sensors.OREGON_SENSOR_THWR288A: {2, false},
+		sensors.OREGON_SENSOR_THGR810:  {3, true},
+		sensors.OREGON_SENSOR_THGN801:  {3, true},
+		sensors.OREGON_SENSOR_THWR800:  {3, false},
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Oregon) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.protocol.Oregon>Open{ bitrate=%v }", config.Bitrate)
+
+	this := new(oregon)
+	this.log = log
+
+	// Half a Manchester bit needs to be at least one chip
+	bitrate := config.Bitrate
+	if bitrate == 0 {
+		bitrate = OREGON_BITRATE
+	}
+	if this.half = float64(bitrate) / float64(OREGON_CLOCK*2); this.half < 1 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Records are created by the OpenThings protocol
+	if ot, err := gopi.Open(openthings.OpenThings{}, log); err != nil {
+		return nil, err
+	} else {
+		this.ot = ot.(sensors.OTProto)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *oregon) Close() error {
+	this.log.Debug("<sensors.protocol.Oregon>Close{ }")
+
+	// Close the OpenThings protocol
+	if err := this.ot.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.ot = nil
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// NAME AND MODE
+
+func (this *oregon) String() string {
+	return fmt.Sprintf("<sensors.protocol>{ name='%v' mode=%v half=%.2f }", this.Name(), this.Mode(), this.half)
+}
+
+func (this *oregon) Name() string {
+	return "oregon"
+}
+
+func (this *oregon) Mode() sensors.MiHomeMode {
+	return sensors.MIHOME_MODE_CONTROL
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE AND DECODE
+
+/*
+ A message is a preamble of one bits, the sync nibble and then the nibbles
+ of the message, each sent least significant bit first. The nibbles are the
+ sensor ID (4 nibbles), channel, rolling code (2 nibbles), flags, temperature
+ (3 BCD nibbles, tenths first), sign and for sensors which measure humidity,
+ the humidity (2 BCD nibbles, units first) and an unused nibble. The checksum
+ is the sum of the nibbles (2 nibbles, low nibble first).
+
+ Bits are Manchester encoded at 1024Hz, where a one is low then high, and
+ a zero is high then low. For v2.1 each bit is sent inverted and then as is.
+*/
+
+func (this *oregon) Encode(msg sensors.Message) []byte {
+	this.log.Debug2("<sensors.protocol.Oregon>Encode{ msg=%v }", msg)
+
+	// Ensure message is of type OregonMessage with a known sensor
+	msg_, ok := msg.(sensors.OregonMessage)
+	if ok == false {
+		return nil
+	}
+	info, exists := oregon_sensors[msg_.SensorType()]
+	if exists == false {
+		return nil
+	}
+	nibbles, err := encodeNibbles(msg_, info)
+	if err != nil {
+		this.log.Debug("<sensors.protocol.Oregon>Encode: %v", err)
+		return nil
+	}
+
+	// Preamble, sync and nibbles
+	bits := make([]bool, 0, OREGON_PREAMBLE_V3+(len(nibbles)+1)*4)
+	preamble := OREGON_PREAMBLE_V3
+	if info.version == 2 {
+		preamble = OREGON_PREAMBLE_V2
+	}
+	for i := 0; i < preamble; i++ {
+		bits = append(bits, true)
+	}
+	for _, nibble := range append([]byte{OREGON_SYNC}, nibbles...) {
+		for i := uint(0); i < 4; i++ {
+			bits = append(bits, nibble&(1<<i) != 0)
+		}
+	}
+
+	// For v2.1 send each bit inverted then as is
+	if info.version == 2 {
+		doubled := make([]bool, 0, len(bits)*2)
+		for _, bit := range bits {
+			doubled = append(doubled, bit == false, bit)
+		}
+		bits = doubled
+	}
+
+	// Manchester encode into chips
+	halves := len(bits) * 2
+	size := (int(float64(halves)*this.half+0.5) + 7) / 8
+	if size > OREGON_PAYLOAD_MAX {
+		return nil
+	}
+	chips := make([]bool, 0, size*8)
+	for i := 0; i < size*8; i++ {
+		// A one is low then high, and a zero is high then low
+		half := int(float64(i) / this.half)
+		chips = append(chips, half < halves && bits[half>>1] == (half&1 == 1))
+	}
+
+	// Pack the chips into bytes and return the payload
+	return chip.Pack(chips)
+}
+
+func (this *oregon) Decode(payload []byte, ts time.Time) (sensors.Message, error) {
+	this.log.Debug2("<sensors.protocol.Oregon>Decode{ payload=%v ts=%v }", strings.ToUpper(hex.EncodeToString(payload)), ts)
+
+	// Check payload size
+	if len(payload) == 0 {
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Try each section of Manchester encoded bits as v3 and then v2.1
+	for _, halves := range this.decodeHalves(payload) {
+		for _, bits := range decodePairs(halves) {
+			if msg, err := this.decodeBits(bits, 3, payload, ts); err == nil {
+				return msg, nil
+			}
+			for _, bits_ := range decodePairs(bits) {
+				if msg, err := this.decodeBits(bits_, 2, payload, ts); err == nil {
+					return msg, nil
+				}
+			}
+		}
+	}
+
+	// No message found
+	return nil, sensors.ErrMessageCorruption
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// decodeHalves returns sections of half bits, where a long gap
+// between sections ends one section and starts another
+func (this *oregon) decodeHalves(payload []byte) [][]bool {
+	sections := make([][]bool, 0, 1)
+	halves := make([]bool, 0, len(payload)*8)
+	for _, run := range chip.Runs(payload) {
+		level := run > 0
+		n := int(math.Abs(float64(run))/this.half + 0.5)
+		if n == 0 {
+			n = 1
+		}
+		if n > 2 {
+			// A gap can contain the last half of one bit and the
+			// first half of the next bit
+			if level == false {
+				sections = append(sections, append(halves, false))
+				halves = []bool{false}
+			} else {
+				sections = append(sections, halves)
+				halves = make([]bool, 0, len(payload)*8)
+			}
+			continue
+		}
+		for ; n > 0; n-- {
+			halves = append(halves, level)
+		}
+	}
+	return append(sections, halves)
+}
+
+// decodeBits finds the preamble and sync and then decodes
+// the nibbles which follow for a protocol version
+func (this *oregon) decodeBits(bits []bool, version uint, payload []byte, ts time.Time) (sensors.Message, error) {
+	// Find the sync nibble after the preamble
+	start, ones := -1, 0
+	for i := 0; i+4 <= len(bits) && start < 0; i++ {
+		if bits[i] {
+			ones++
+		} else if ones >= OREGON_PREAMBLE_MIN && decodeNibble(bits[i:]) == OREGON_SYNC {
+			start = i + 4
+		} else {
+			ones = 0
+		}
+	}
+	if start < 0 {
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Decode the nibbles
+	nibbles := make([]byte, 0, (len(bits)-start)/4)
+	for i := start; i+4 <= len(bits); i += 4 {
+		nibbles = append(nibbles, decodeNibble(bits[i:]))
+	}
+	if len(nibbles) < 4 {
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Look up the sensor
+	sensor := sensors.OregonSensor(uint16(nibbles[0])<<12 | uint16(nibbles[1])<<8 | uint16(nibbles[2])<<4 | uint16(nibbles[3]))
+	info, exists := oregon_sensors[sensor]
+	if exists == false || info.version != version {
+		return nil, sensors.ErrMessageCorruption
+	}
+	count := OREGON_NIBBLES_TEMPERATURE
+	if info.humidity {
+		count = OREGON_NIBBLES_HUMIDITY
+	}
+	if len(nibbles) < count+2 {
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Check the checksum
+	if checksum := checksum(nibbles[:count]); nibbles[count] != checksum&0x0F || nibbles[count+1] != checksum>>4 {
+		this.log.Debug("<sensors.protocol.Oregon>Decode: Checksum mismatch for %v", sensor)
+		return nil, sensors.ErrMessageCRC
+	}
+
+	// Channel is a bit field for v2.1
+	channel := uint(nibbles[4])
+	if version == 2 && channel == 4 {
+		channel = 3
+	}
+
+	// Temperature and humidity are BCD
+	for _, nibble := range nibbles[8:11] {
+		if nibble > 9 {
+			return nil, sensors.ErrMessageCorruption
+		}
+	}
+	temperature := float64(nibbles[10])*10 + float64(nibbles[9]) + float64(nibbles[8])/10
+	if nibbles[11] != 0 {
+		temperature = -temperature
+	}
+	humidity := uint(0)
+	if info.humidity {
+		if nibbles[12] > 9 || nibbles[13] > 9 {
+			return nil, sensors.ErrMessageCorruption
+		}
+		humidity = uint(nibbles[13])*10 + uint(nibbles[12])
+	}
+
+	// Return the message
+	code := nibbles[5]<<4 | nibbles[6]
+	battery_low := nibbles[7]&OREGON_FLAG_BATTERY_LOW != 0
+	return this.NewWithTimestamp(sensor, channel, code, temperature, humidity, battery_low, payload, ts)
+}
+
+// encodeNibbles returns the nibbles for a message including the checksum
+func encodeNibbles(msg sensors.OregonMessage, info sensor_info) ([]byte, error) {
+	temperature, humidity, err := readings(msg.Records())
+	if err != nil {
+		return nil, err
+	}
+	sensor := uint16(msg.SensorType())
+	nibbles := []byte{
+		byte(sensor >> 12), byte(sensor>>8) & 0x0F, byte(sensor>>4) & 0x0F, byte(sensor) & 0x0F,
+		byte(msg.Channel()), msg.RollingCode() >> 4, msg.RollingCode() & 0x0F, 0,
+	}
+
+	// Channel is a bit field for v2.1
+	if info.version == 2 && msg.Channel() == 3 {
+		nibbles[4] = 4
+	}
+	if msg.BatteryLow() {
+		nibbles[7] |= OREGON_FLAG_BATTERY_LOW
+	}
+
+	// Temperature in tenths of a degree
+	tenths := uint(math.Abs(temperature)*10 + 0.5)
+	nibbles = append(nibbles, byte(tenths%10), byte(tenths/10%10), byte(tenths/100%10), 0)
+	if temperature < 0 {
+		nibbles[11] = 0x08
+	}
+
+	// Humidity
+	if info.humidity {
+		nibbles = append(nibbles, byte(humidity%10), byte(humidity/10%10), 0)
+	}
+
+	// Checksum
+	checksum := checksum(nibbles)
+	return append(nibbles, checksum&0x0F, checksum>>4), nil
+}
+
+// readings returns the temperature and humidity from records
+func readings(records []sensors.OTRecord) (float64, uint, error) {
+	temperature, humidity := float64(0), uint(0)
+	for _, record := range records {
+		switch record.Name() {
+		case sensors.OT_PARAM_TEMPERATURE:
+			if value, err := record.FloatValue(); err != nil {
+				return 0, 0, err
+			} else {
+				temperature = value
+			}
+		case sensors.OT_PARAM_RELATIVE_HUMIDITY:
+			if value, err := record.UintValue(); err != nil {
+				return 0, 0, err
+			} else {
+				humidity = uint(value)
+			}
+		}
+	}
+	return temperature, humidity, nil
+}
+
+// checksum returns the sum of nibbles
+func checksum(nibbles []byte) byte {
+	sum := byte(0)
+	for _, nibble := range nibbles {
+		sum += nibble
+	}
+	return sum
+}
+
+// decodePairs returns sections of bits decoded from pairs of values in both
+// alignments, where a one is low then high and a zero is high then low. It is
+// used for Manchester encoding, and for v2.1 where each bit is sent inverted
+// and then as is. A new section is started when a pair is invalid
+func decodePairs(values []bool) [][]bool {
+	sections := make([][]bool, 0, 2)
+	for phase := 0; phase < 2; phase++ {
+		section := make([]bool, 0, len(values)/2)
+		for i := phase; i+1 < len(values); i += 2 {
+			if values[i] != values[i+1] {
+				section = append(section, values[i+1])
+			} else if len(section) > 0 {
+				sections = append(sections, section)
+				section = make([]bool, 0, len(values)/2)
+			}
+		}
+		if len(section) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// decodeNibble returns a nibble from four bits, least significant bit first
+func decodeNibble(bits []bool) byte {
+	nibble := byte(0)
+	for i := uint(0); i < 4; i++ {
+		if bits[i] {
+			nibble |= 1 << i
+		}
+	}
+	return nibble
+}
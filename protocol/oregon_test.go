@@ -0,0 +1,235 @@
+package protocol_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/sensors/protocol/oregon"
+)
+
+func Test_Oregon_000(t *testing.T) {
+	// Create an Oregon module
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/oregon")); err != nil {
+		t.Fatal(err)
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/oregon").(sensors.OregonProto); ok == false {
+		t.Fatal("Oregon does not comply to OregonProto interface")
+	} else {
+		t.Log(proto)
+	}
+}
+
+func Test_Oregon_001(t *testing.T) {
+	if proto := Oregon(); proto == nil {
+		t.Fatal("Missing Oregon module")
+	} else if msg, err := proto.New(sensors.OREGON_SENSOR_THGR122N, 1, 0xAB, 21.34, 56, false); err != nil {
+		t.Fatal(err)
+	} else if records := msg.Records(); len(records) != 2 {
+		t.Error("Expected two records", msg)
+	} else if temperature, err := records[0].Float32Value(); err != nil || temperature != 21.3 {
+		t.Error("Unexpected temperature", records[0], err)
+	} else if humidity, err := records[1].UintValue(); err != nil || humidity != 56 {
+		t.Error("Unexpected humidity", records[1], err)
+	} else {
+		t.Log(msg)
+	}
+}
+
+func Test_Oregon_002(t *testing.T) {
+	if proto := Oregon(); proto == nil {
+		t.Fatal("Missing Oregon module")
+	} else if _, err := proto.New(sensors.OregonSensor(0x1234), 1, 0, 0, 0, false); err == nil {
+		t.Error("Expected parameter error due to unknown sensor")
+	} else if _, err := proto.New(sensors.OREGON_SENSOR_THGR122N, 4, 0, 0, 0, false); err == nil {
+		t.Error("Expected parameter error due to bad channel")
+	} else if _, err := proto.New(sensors.OREGON_SENSOR_THGR810, 4, 0, 0, 0, false); err != nil {
+		t.Error(err)
+	} else if _, err := proto.New(sensors.OREGON_SENSOR_THGR810, 1, 0, 100, 0, false); err == nil {
+		t.Error("Expected parameter error due to bad temperature")
+	} else if _, err := proto.New(sensors.OREGON_SENSOR_THGR810, 1, 0, 0, 100, false); err == nil {
+		t.Error("Expected parameter error due to bad humidity")
+	} else if msg, err := proto.New(sensors.OREGON_SENSOR_THN132N, 1, 0, 0, 50, false); err != nil {
+		t.Error(err)
+	} else if len(msg.Records()) != 1 {
+		t.Error("Expected temperature record only", msg)
+	}
+}
+
+func Test_Oregon_003(t *testing.T) {
+	if proto := Oregon(); proto == nil {
+		t.Fatal("Missing Oregon module")
+	} else {
+		sensor_types := []sensors.OregonSensor{
+			sensors.OREGON_SENSOR_THGR122N, sensors.OREGON_SENSOR_THN132N,
+			sensors.OREGON_SENSOR_THGR810, sensors.OREGON_SENSOR_THWR800,
+		}
+		for i, sensor_type := range sensor_types {
+			for temperature := -40.0; temperature < 60.0; temperature += 7.3 {
+				channel := uint(i%3) + 1
+				if msg_in, err := proto.New(sensor_type, channel, uint8(temperature*3), temperature, uint(temperature+40), temperature < 0); err != nil {
+					t.Fatal(err)
+				} else if payload := proto.Encode(msg_in); len(payload) == 0 {
+					t.Fatal("Unexpected empty payload", msg_in)
+				} else if msg_out, err := proto.Decode(payload, time.Now()); err != nil {
+					t.Fatal(msg_in, err)
+				} else if Equals(msg_in, msg_out) == false {
+					t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+				}
+			}
+		}
+	}
+}
+
+func Test_Oregon_004(t *testing.T) {
+	if proto := Oregon(); proto == nil {
+		t.Fatal("Missing Oregon module")
+	} else if msg_in, err := proto.New(sensors.OREGON_SENSOR_THGR122N, 3, 0x5C, -5.6, 82, true); err != nil {
+		t.Fatal(err)
+	} else {
+		payload := proto.Encode(msg_in)
+		t.Logf("message=%v", msg_in)
+		t.Logf("  payload=%v", strings.ToUpper(hex.EncodeToString(payload)))
+
+		// Gap before and after the message
+		padded := append(append([]byte{0x00, 0x00, 0x00}, payload...), 0x00, 0x00)
+		if msg_out, err := proto.Decode(padded, time.Now()); err != nil {
+			t.Error(err)
+		} else if Equals(msg_in, msg_out) == false {
+			t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+		} else if msg_out.(sensors.OregonMessage).BatteryLow() == false {
+			t.Error("Expected battery low flag", msg_out)
+		}
+
+		// Corrupt the data, which should fail the checksum
+		corrupt := append([]byte{}, payload...)
+		corrupt[len(corrupt)/2] ^= 0xFF
+		if _, err := proto.Decode(corrupt, time.Now()); err == nil {
+			t.Error("Expected error for corrupted message")
+		}
+
+		// Truncated message
+		if _, err := proto.Decode(payload[:len(payload)/2], time.Now()); err == nil {
+			t.Error("Expected error for truncated message")
+		}
+	}
+}
+
+func Test_Oregon_005(t *testing.T) {
+	// Decode at a different bitrate
+	if driver, err := gopi.Open(oregon.Oregon{Bitrate: 9600}, Logger()); err != nil {
+		t.Fatal(err)
+	} else if proto, ok := driver.(sensors.OregonProto); ok == false {
+		t.Fatal("Oregon does not comply to OregonProto interface")
+	} else if msg_in, err := proto.New(sensors.OREGON_SENSOR_THGN801, 7, 0x12, 18.2, 45, false); err != nil {
+		t.Fatal(err)
+	} else if msg_out, err := proto.Decode(proto.Encode(msg_in), time.Now()); err != nil {
+		t.Error(err)
+	} else if Equals(msg_in, msg_out) == false {
+		t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+	} else if _, err := gopi.Open(oregon.Oregon{Bitrate: 1200}, Logger()); err == nil {
+		t.Error("Expected parameter error due to bad bitrate")
+	}
+}
+
+func Test_Oregon_006(t *testing.T) {
+	// Decode messages captured from real sensors, written as bytes
+	// with the first nibble received in the low nibble, starting
+	// with the sync nibble. At 8192bps half a bit is four chips
+	driver, err := gopi.Open(oregon.Oregon{Bitrate: 8192}, Logger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto := driver.(sensors.OregonProto)
+	for _, capture := range []struct {
+		version     uint
+		data        string
+		sensor      sensors.OregonSensor
+		channel     uint
+		code        uint8
+		temperature float32
+		humidity    uint64
+	}{
+		{2, "1A2D40C4512170463EE6", sensors.OREGON_SENSOR_THGR122N, 3, 0x4C, 21.5, 67},
+		{3, "FA28A428202290834B46", sensors.OREGON_SENSOR_THGR810, 10, 0x82, 22.2, 39},
+	} {
+		if data, err := hex.DecodeString(capture.data); err != nil {
+			t.Fatal(err)
+		} else if msg, err := proto.Decode(OregonCapture(data, capture.version, 4), time.Now()); err != nil {
+			t.Error(capture.data, err)
+		} else if msg_, ok := msg.(sensors.OregonMessage); ok == false {
+			t.Error("Unexpected message", msg)
+		} else if msg_.SensorType() != capture.sensor || msg_.Channel() != capture.channel || msg_.RollingCode() != capture.code {
+			t.Error("Unexpected sensor", msg_)
+		} else if msg_.BatteryLow() {
+			t.Error("Unexpected battery low flag", msg_)
+		} else if records := msg_.Records(); len(records) != 2 {
+			t.Error("Expected two records", msg_)
+		} else if temperature, err := records[0].Float32Value(); err != nil || temperature != capture.temperature {
+			t.Error("Unexpected temperature", records[0], err)
+		} else if humidity, err := records[1].UintValue(); err != nil || humidity != capture.humidity {
+			t.Error("Unexpected humidity", records[1], err)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OREGON
+
+func Oregon() sensors.OregonProto {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/oregon")); err != nil {
+		return nil
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/oregon").(sensors.OregonProto); ok == false {
+		return nil
+	} else {
+		return proto
+	}
+}
+
+// OregonCapture returns the radio payload for captured message data, with
+// a gap, the preamble and the data Manchester encoded with a number of chips
+// in half a bit. For v2.1 each bit is sent inverted and then as is
+func OregonCapture(data []byte, version uint, half int) []byte {
+	bits := make([]bool, 0, 24+len(data)*8)
+	preamble := 24
+	if version == 2 {
+		preamble = 16
+	}
+	for i := 0; i < preamble; i++ {
+		bits = append(bits, true)
+	}
+	for _, value := range data {
+		// Low nibble first, least significant bit first
+		for _, nibble := range []byte{value & 0x0F, value >> 4} {
+			for i := uint(0); i < 4; i++ {
+				bits = append(bits, nibble&(1<<i) != 0)
+			}
+		}
+	}
+	if version == 2 {
+		doubled := make([]bool, 0, len(bits)*2)
+		for _, bit := range bits {
+			doubled = append(doubled, bit == false, bit)
+		}
+		bits = doubled
+	}
+
+	// A one is low then high, and a zero is high then low, after a gap
+	payload := make([]byte, 2+(len(bits)*2*half+7)/8)
+	for i, bit := range bits {
+		for j := 0; j < half; j++ {
+			chip := 16 + (i*2+1)*half + j
+			if bit == false {
+				chip -= half
+			}
+			payload[chip>>3] |= 0x80 >> uint(chip&7)
+		}
+	}
+	return payload
+}
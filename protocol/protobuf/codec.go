@@ -0,0 +1,238 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package protobuf
+
+import (
+	"encoding/binary"
+	"math"
+
+	// Frameworks
+	"github.com/djthorpe/sensors"
+)
+
+/*
+ The protocol buffer wire format is encoded and decoded here rather than
+ with generated code, so that sensor nodes only need the schema in
+ rpc/protobuf/radio/radio.proto. Unknown fields are skipped when decoding.
+ The field numbers are checked against the schema in protocol/protobuf_test.go
+*/
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Wire types
+	pb_wire_varint  = 0
+	pb_wire_fixed64 = 1
+	pb_wire_bytes   = 2
+	pb_wire_fixed32 = 5
+)
+
+const (
+	// Message fields
+	pb_message_protocol = 1
+	pb_message_product  = 2
+	pb_message_sensor   = 3
+	pb_message_readings = 4
+)
+
+const (
+	// Reading fields
+	pb_reading_parameter    = 1
+	pb_reading_float_value  = 2
+	pb_reading_int_value    = 3
+	pb_reading_uint_value   = 4
+	pb_reading_string_value = 5
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE
+
+// marshalMessage appends the encoded message to a buffer, or
+// returns nil if a record cannot be encoded
+func marshalMessage(buf []byte, msg sensors.ProtoMessage) []byte {
+	if protocol := msg.Protocol(); protocol != "" {
+		buf = appendBytes(buf, pb_message_protocol, []byte(protocol))
+	}
+	if product := msg.Product(); product != 0 {
+		buf = appendVarint(buf, pb_message_product, uint64(product))
+	}
+	if sensor := msg.Sensor(); sensor != 0 {
+		buf = appendVarint(buf, pb_message_sensor, uint64(sensor))
+	}
+	for _, record := range msg.Records() {
+		if reading := marshalReading(record); reading == nil {
+			return nil
+		} else {
+			buf = appendBytes(buf, pb_message_readings, reading)
+		}
+	}
+	return buf
+}
+
+// marshalReading returns an encoded reading, or nil if the
+// record cannot be encoded
+func marshalReading(record sensors.OTRecord) []byte {
+	buf := appendVarint(nil, pb_reading_parameter, uint64(record.Name()))
+	switch record.Type() {
+	case sensors.OT_DATATYPE_UDEC_0, sensors.OT_DATATYPE_ENUM:
+		if value, err := record.UintValue(); err != nil {
+			return nil
+		} else {
+			return appendVarint(buf, pb_reading_uint_value, value)
+		}
+	case sensors.OT_DATATYPE_DEC_0:
+		if value, err := record.IntValue(); err != nil {
+			return nil
+		} else {
+			return appendVarint(buf, pb_reading_int_value, uint64(value<<1)^uint64(value>>63))
+		}
+	case sensors.OT_DATATYPE_STRING:
+		if value, err := record.StringValue(); err != nil {
+			return nil
+		} else {
+			return appendBytes(buf, pb_reading_string_value, []byte(value))
+		}
+	default:
+		if value, err := record.FloatValue(); err != nil {
+			return nil
+		} else {
+			bits := make([]byte, 4)
+			binary.LittleEndian.PutUint32(bits, math.Float32bits(float32(value)))
+			return append(appendKey(buf, pb_reading_float_value, pb_wire_fixed32), bits...)
+		}
+	}
+}
+
+func appendKey(buf []byte, field, wire uint64) []byte {
+	return appendUvarint(buf, field<<3|wire)
+}
+
+func appendVarint(buf []byte, field, value uint64) []byte {
+	return appendUvarint(appendKey(buf, field, pb_wire_varint), value)
+}
+
+func appendBytes(buf []byte, field uint64, value []byte) []byte {
+	buf = appendUvarint(appendKey(buf, field, pb_wire_bytes), uint64(len(value)))
+	return append(buf, value...)
+}
+
+func appendUvarint(buf []byte, value uint64) []byte {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	return append(buf, tmp[:binary.PutUvarint(tmp, value)]...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODE
+
+// unmarshalMessage decodes the fields of a message
+func (this *protobuf) unmarshalMessage(data []byte, msg *message) error {
+	for len(data) > 0 {
+		field, wire, value, raw, n := decodeField(data)
+		if n <= 0 {
+			return sensors.ErrMessageCorruption
+		}
+		data = data[n:]
+		switch {
+		case field == pb_message_protocol && wire == pb_wire_bytes:
+			msg.protocol = string(raw)
+		case field == pb_message_product && wire == pb_wire_varint:
+			if value > math.MaxUint8 {
+				return sensors.ErrMessageCorruption
+			}
+			msg.product = uint8(value)
+		case field == pb_message_sensor && wire == pb_wire_varint:
+			if value > uint64(PROTOBUF_SENSOR_MASK) {
+				return sensors.ErrMessageCorruption
+			}
+			msg.sensor = uint32(value)
+		case field == pb_message_readings && wire == pb_wire_bytes:
+			if record, err := this.unmarshalReading(raw); err != nil {
+				return err
+			} else {
+				msg.records = append(msg.records, record)
+			}
+		}
+	}
+	return nil
+}
+
+// unmarshalReading decodes a reading into a record
+func (this *protobuf) unmarshalReading(data []byte) (sensors.OTRecord, error) {
+	var name sensors.OTParameter
+	var reading interface{}
+	for len(data) > 0 {
+		field, wire, value, raw, n := decodeField(data)
+		if n <= 0 {
+			return nil, sensors.ErrMessageCorruption
+		}
+		data = data[n:]
+		switch {
+		case field == pb_reading_parameter && wire == pb_wire_varint:
+			if value == 0 || value > uint64(sensors.OT_PARAM_MAX) {
+				return nil, sensors.ErrMessageCorruption
+			}
+			name = sensors.OTParameter(value)
+		case field == pb_reading_float_value && wire == pb_wire_fixed32:
+			reading = math.Float32frombits(uint32(value))
+		case field == pb_reading_int_value && wire == pb_wire_varint:
+			reading = int64(value>>1) ^ -int64(value&1)
+		case field == pb_reading_uint_value && wire == pb_wire_varint:
+			reading = value
+		case field == pb_reading_string_value && wire == pb_wire_bytes:
+			reading = string(raw)
+		}
+	}
+
+	// Create the record
+	if name == sensors.OT_PARAM_NONE {
+		return nil, sensors.ErrMessageCorruption
+	} else if reading == nil {
+		return this.ot.NewNull(name, true)
+	} else {
+		return this.NewReading(name, reading)
+	}
+}
+
+// decodeField returns the field number, wire type and value or raw
+// bytes, and the number of bytes read or zero on error
+func decodeField(data []byte) (uint64, uint64, uint64, []byte, int) {
+	key, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, 0, 0, nil, 0
+	}
+	field, wire := key>>3, key&0x07
+	switch wire {
+	case pb_wire_varint:
+		if value, m := binary.Uvarint(data[n:]); m <= 0 {
+			return 0, 0, 0, nil, 0
+		} else {
+			return field, wire, value, nil, n + m
+		}
+	case pb_wire_fixed64:
+		if len(data) < n+8 {
+			return 0, 0, 0, nil, 0
+		}
+		return field, wire, binary.LittleEndian.Uint64(data[n:]), nil, n + 8
+	case pb_wire_fixed32:
+		if len(data) < n+4 {
+			return 0, 0, 0, nil, 0
+		}
+		return field, wire, uint64(binary.LittleEndian.Uint32(data[n:])), nil, n + 4
+	case pb_wire_bytes:
+		if length, m := binary.Uvarint(data[n:]); m <= 0 || length > uint64(len(data)-n-m) {
+			return 0, 0, 0, nil, 0
+		} else {
+			return field, wire, length, data[n+m : n+m+int(length)], n + m + int(length)
+		}
+	default:
+		return 0, 0, 0, nil, 0
+	}
+}
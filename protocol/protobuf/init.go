@@ -0,0 +1,45 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package protobuf
+
+import (
+	"fmt"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register module
+	gopi.RegisterModule(gopi.Module{
+		Name: "sensors/protocol/protobuf",
+		Type: gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("protobuf.addr", PROTOBUF_ADDRESS, "Node address for received messages, or zero for any address")
+			config.AppFlags.FlagUint("protobuf.broadcast", PROTOBUF_BROADCAST, "Broadcast address for received messages")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			addr, _ := app.AppFlags.GetUint("protobuf.addr")
+			broadcast, _ := app.AppFlags.GetUint("protobuf.broadcast")
+			if addr > 0xFF {
+				return nil, fmt.Errorf("Invalid -protobuf.addr flag")
+			} else if broadcast > 0xFF {
+				return nil, fmt.Errorf("Invalid -protobuf.broadcast flag")
+			}
+			return gopi.Open(Protobuf{
+				Address:   uint8(addr),
+				Broadcast: uint8(broadcast),
+			}, app.Logger)
+		},
+	})
+}
@@ -0,0 +1,138 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package protobuf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CREATE MESSAGE
+
+func (this *protobuf) New(protocol string, product uint8, sensor uint32, address uint8) (sensors.ProtoMessage, error) {
+	this.log.Debug2("<sensors.protocol.Protobuf>New{ protocol=%v product=0x%02X sensor=0x%06X address=0x%02X }", strconv.Quote(protocol), product, sensor, address)
+
+	// Sensor is 24-bits
+	if sensor&PROTOBUF_SENSOR_MASK != sensor {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Set up message
+	m := new(message)
+	m.protocol = protocol
+	m.product = product
+	m.sensor = sensor
+	m.address = address
+	m.source = this
+
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *message) String() string {
+	params := fmt.Sprintf("name='%v' protocol=%v product=0x%02X sensor=0x%06X address=0x%02X", this.Name(), strconv.Quote(this.protocol), this.product, this.sensor, this.address)
+	for _, record := range this.records {
+		params += fmt.Sprint(" ", record)
+	}
+	params += " data=" + strings.ToUpper(hex.EncodeToString(this.data))
+	if this.ts.IsZero() == false {
+		params += " ts=" + this.ts.Format(time.Kitchen)
+	}
+	return fmt.Sprintf("<sensors.Message>{ %v }", params)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT ProtoMessage INTERFACE
+
+func (this *message) Protocol() string {
+	return this.protocol
+}
+
+func (this *message) Product() uint8 {
+	return this.product
+}
+
+func (this *message) Sensor() uint32 {
+	return this.sensor
+}
+
+func (this *message) Address() uint8 {
+	return this.address
+}
+
+func (this *message) Append(records ...sensors.OTRecord) sensors.ProtoMessage {
+	this.records = append(this.records, records...)
+	return this
+}
+
+func (this *message) Records() []sensors.OTRecord {
+	return this.records
+}
+
+func (this *message) Timestamp() time.Time {
+	return this.ts
+}
+
+func (this *message) Data() []byte {
+	return this.data
+}
+
+func (this *message) Signal() *sensors.RFMSignal {
+	return this.signal
+}
+
+func (this *message) SetSignal(signal *sensors.RFMSignal) {
+	this.signal = signal
+}
+
+func (this *message) IsDuplicate(other sensors.Message) bool {
+	if this.Name() != other.Name() {
+		return false
+	}
+	other_, ok := other.(sensors.ProtoMessage)
+	if ok == false {
+		return false
+	} else if this.Protocol() != other_.Protocol() || this.Product() != other_.Product() || this.Sensor() != other_.Sensor() {
+		return false
+	} else if this.Address() != other_.Address() {
+		return false
+	}
+	records := other_.Records()
+	if len(this.records) != len(records) {
+		return false
+	}
+	for i, record := range this.records {
+		if record.IsDuplicate(records[i]) == false {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENT gopi.Event INTERFACE
+
+func (this *message) Name() string {
+	return this.source.Name()
+}
+
+func (this *message) Source() gopi.Driver {
+	return this.source
+}
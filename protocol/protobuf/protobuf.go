@@ -0,0 +1,252 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package protobuf
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/protocol/openthings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type Protobuf struct {
+	Address   uint8 // Node address for received messages, or zero for any address
+	Broadcast uint8 // Broadcast address for received messages
+}
+
+type protobuf struct {
+	log       gopi.Logger
+	ot        sensors.OTProto // Used to create records
+	address   uint8
+	broadcast uint8
+}
+
+type message struct {
+	protocol string
+	product  uint8
+	sensor   uint32
+	address  uint8
+	records  []sensors.OTRecord
+	source   sensors.Proto
+	data     []byte
+	ts       time.Time
+	signal   *sensors.RFMSignal
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Default node and broadcast addresses
+	PROTOBUF_ADDRESS   = 0x01
+	PROTOBUF_BROADCAST = 0xFF
+
+	// Maximum size of a packet after the length byte, which is the
+	// radio payload size in monitor mode and the limit for hardware AES
+	PROTOBUF_PAYLOAD_MAX = 0x40
+
+	// Sensor is 24 bits
+	PROTOBUF_SENSOR_MASK uint32 = 0xFFFFFF
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Protobuf) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.protocol.Protobuf>Open{ address=0x%02X broadcast=0x%02X }", config.Address, config.Broadcast)
+
+	this := new(protobuf)
+	this.log = log
+	this.address = config.Address
+	this.broadcast = config.Broadcast
+
+	// Records are created by the OpenThings protocol
+	if ot, err := gopi.Open(openthings.OpenThings{}, log); err != nil {
+		return nil, err
+	} else {
+		this.ot = ot.(sensors.OTProto)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *protobuf) Close() error {
+	this.log.Debug("<sensors.protocol.Protobuf>Close{ }")
+
+	// Close the OpenThings protocol
+	if err := this.ot.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.ot = nil
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// NAME AND MODE
+
+func (this *protobuf) String() string {
+	return fmt.Sprintf("<sensors.protocol>{ name='%v' mode=%v address=0x%02X broadcast=0x%02X }", this.Name(), this.Mode(), this.address, this.broadcast)
+}
+
+func (this *protobuf) Name() string {
+	return "protobuf"
+}
+
+func (this *protobuf) Mode() sensors.MiHomeMode {
+	return sensors.MIHOME_MODE_MONITOR
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE AND DECODE
+
+/*
+ The packet is the length byte, the destination address, the protocol buffer
+ encoded message (see rpc/protobuf/radio/radio.proto) and a CRC-16 of the
+ address and message
+*/
+
+func (this *protobuf) Encode(msg sensors.Message) []byte {
+	this.log.Debug2("<sensors.protocol.Protobuf>Encode{ msg=%v }", msg)
+
+	// Ensure message is of type ProtoMessage
+	msg_, ok := msg.(sensors.ProtoMessage)
+	if ok == false {
+		return nil
+	}
+
+	// Length, address and message
+	payload := []byte{0x00, msg_.Address()}
+	if payload = marshalMessage(payload, msg_); payload == nil {
+		this.log.Debug("<sensors.protocol.Protobuf>Encode: Unable to encode records")
+		return nil
+	}
+
+	// CRC
+	crc := compute_crc(payload[1:])
+	payload = append(payload, uint8(crc>>8), uint8(crc))
+
+	// Ensure payload fits into a packet
+	if len(payload)-1 > PROTOBUF_PAYLOAD_MAX {
+		this.log.Debug("<sensors.protocol.Protobuf>Encode: Payload is too large")
+		return nil
+	}
+
+	// Set the length
+	payload[0] = uint8(len(payload) - 1)
+
+	// Return the payload
+	return payload
+}
+
+func (this *protobuf) Decode(payload []byte, ts time.Time) (sensors.Message, error) {
+	this.log.Debug2("<sensors.protocol.Protobuf>Decode{ payload=%v ts=%v }", strings.ToUpper(hex.EncodeToString(payload)), ts)
+
+	// Check size byte vs size of message, which has at least
+	// the address and CRC
+	if len(payload) < 4 || int(payload[0]) != len(payload)-1 {
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Check CRC
+	if crc := binary.BigEndian.Uint16(payload[len(payload)-2:]); compute_crc(payload[1:len(payload)-2]) != crc {
+		return nil, sensors.ErrMessageCRC
+	}
+
+	// Check address
+	address := payload[1]
+	if this.address != 0 && address != this.address && address != this.broadcast {
+		this.log.Debug("<sensors.protocol.Protobuf>Decode: Ignoring message for address 0x%02X", address)
+		return nil, sensors.ErrMessageCorruption
+	}
+
+	// Decode the message
+	msg := new(message)
+	msg.address = address
+	msg.source = this
+	msg.data = payload
+	msg.ts = ts
+	if err := this.unmarshalMessage(payload[2:len(payload)-2], msg); err != nil {
+		return nil, err
+	}
+
+	// Return message
+	return msg, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CREATE READINGS
+
+func (this *protobuf) NewReading(name sensors.OTParameter, value interface{}) (sensors.OTRecord, error) {
+	switch value.(type) {
+	case float32:
+		return this.ot.NewFloat32(name, value.(float32), true)
+	case float64:
+		return this.ot.NewFloat32(name, float32(value.(float64)), true)
+	case int:
+		return this.ot.NewInt(name, int64(value.(int)), true)
+	case int8:
+		return this.ot.NewInt(name, int64(value.(int8)), true)
+	case int16:
+		return this.ot.NewInt(name, int64(value.(int16)), true)
+	case int32:
+		return this.ot.NewInt(name, int64(value.(int32)), true)
+	case int64:
+		return this.ot.NewInt(name, value.(int64), true)
+	case uint:
+		return this.ot.NewUint(name, uint64(value.(uint)), true)
+	case uint8:
+		return this.ot.NewUint(name, uint64(value.(uint8)), true)
+	case uint16:
+		return this.ot.NewUint(name, uint64(value.(uint16)), true)
+	case uint32:
+		return this.ot.NewUint(name, uint64(value.(uint32)), true)
+	case uint64:
+		return this.ot.NewUint(name, value.(uint64), true)
+	case bool:
+		return this.ot.NewBool(name, value.(bool), true)
+	case string:
+		return this.ot.NewString(name, value.(string), true)
+	default:
+		return nil, gopi.ErrBadParameter
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// compute_crc returns the CRC-16 (CCITT) for a buffer
+func compute_crc(buf []byte) uint16 {
+	rem := uint16(0)
+	for _, v := range buf {
+		rem = rem ^ (uint16(v) << 8)
+		for bit := 0; bit < 8; bit++ {
+			if rem&(1<<15) != 0 {
+				rem = ((rem << 1) ^ 0x1021)
+			} else {
+				rem = (rem << 1)
+			}
+		}
+	}
+	return rem
+}
@@ -0,0 +1,184 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package protobuf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/ener314rt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Sender is used on a sensor node to transmit readings to a gateway
+type Sender struct {
+	Radio   sensors.RFM69         // Radio used to transmit
+	Proto   sensors.ProtobufProto // Protocol used to encode messages
+	Product uint8                 // Product identifier for the node
+	Sensor  uint32                // Sensor identifier for the node, 24 bits
+	Address uint8                 // Node address
+	Gateway uint8                 // Destination address
+	AESKey  []byte                // Optional 16 byte AES key
+	Repeat  uint                  // Number of times each message is repeated
+}
+
+type sender struct {
+	log     gopi.Logger
+	radio   sensors.RFM69
+	proto   sensors.ProtobufProto
+	product uint8
+	sensor  uint32
+	address uint8
+	gateway uint8
+	repeat  uint
+
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	PROTOBUF_SENDER_REPEAT = 3
+	PROTOBUF_AESKEY_BYTES  = 16
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Sender) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.protocol.Protobuf.Sender>Open{ product=0x%02X sensor=0x%06X address=0x%02X gateway=0x%02X repeat=%v }", config.Product, config.Sensor, config.Address, config.Gateway, config.Repeat)
+
+	// Check parameters
+	if config.Radio == nil || config.Proto == nil {
+		return nil, gopi.ErrBadParameter
+	} else if config.Sensor&PROTOBUF_SENSOR_MASK != config.Sensor {
+		return nil, gopi.ErrBadParameter
+	} else if len(config.AESKey) != 0 && len(config.AESKey) != PROTOBUF_AESKEY_BYTES {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(sender)
+	this.log = log
+	this.radio = config.Radio
+	this.proto = config.Proto
+	this.product = config.Product
+	this.sensor = config.Sensor
+	this.address = config.Address
+	this.gateway = config.Gateway
+	this.repeat = config.Repeat
+	if this.repeat == 0 {
+		this.repeat = PROTOBUF_SENDER_REPEAT
+	}
+
+	// The radio uses the same packet settings as the gateway receives
+	// in monitor mode, with the node address and AES key
+	profile := ener314rt.PROFILE_MONITOR
+	profile.Name = "protobuf-node"
+	profile.NodeAddress = this.address
+	profile.AESKey = hex.EncodeToString(config.AESKey)
+	if err := this.radio.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+		return nil, err
+	} else if err := this.radio.ApplyProfile(profile); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *sender) Close() error {
+	this.log.Debug("<sensors.protocol.Protobuf.Sender>Close{ }")
+
+	// Release resources
+	this.radio = nil
+	this.proto = nil
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *sender) String() string {
+	return fmt.Sprintf("<sensors.protocol.Protobuf.Sender>{ product=0x%02X sensor=0x%06X address=0x%02X gateway=0x%02X repeat=%v }", this.product, this.sensor, this.address, this.gateway, this.repeat)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SEND
+
+func (this *sender) Send(protocol string, readings map[sensors.OTParameter]interface{}) error {
+	this.log.Debug2("<sensors.protocol.Protobuf.Sender>Send{ protocol=%v readings=%v }", strconv.Quote(protocol), readings)
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Check parameters
+	if this.radio == nil || this.proto == nil {
+		return gopi.ErrOutOfOrder
+	} else if len(readings) == 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// Readings are sent in parameter order
+	names := make([]sensors.OTParameter, 0, len(readings))
+	for name := range readings {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+
+	// Create the message
+	msg, err := this.proto.New(protocol, this.product, this.sensor, this.gateway)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if record, err := this.proto.NewReading(name, readings[name]); err != nil {
+			return err
+		} else {
+			msg.Append(record)
+		}
+	}
+
+	// Encode the message
+	payload := this.proto.Encode(msg)
+	if len(payload) == 0 {
+		return gopi.ErrBadParameter
+	}
+	this.log.Debug("<sensors.protocol.Protobuf.Sender>Send{ payload=%v }", strings.ToUpper(hex.EncodeToString(payload)))
+
+	// Transmit and then return to standby
+	if err := this.radio.SetMode(sensors.RFM_MODE_TX); err != nil {
+		return err
+	} else if err := this.radio.SetSequencer(true); err != nil {
+		return err
+	} else if err := this.radio.WritePayload(payload, this.repeat, 100*time.Millisecond); err != nil {
+		return err
+	} else if err := this.radio.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+		return err
+	}
+
+	// Return success
+	return nil
+}
@@ -0,0 +1,407 @@
+package protocol_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	pb "github.com/golang/protobuf/proto"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/sensors/protocol/protobuf"
+)
+
+func Test_Protobuf_000(t *testing.T) {
+	// Create a protobuf module
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/protobuf")); err != nil {
+		t.Fatal(err)
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/protobuf").(sensors.ProtobufProto); ok == false {
+		t.Fatal("Protobuf does not comply to ProtobufProto interface")
+	} else {
+		t.Log(proto)
+	}
+}
+
+func Test_Protobuf_001(t *testing.T) {
+	if proto := Protobuf(); proto == nil {
+		t.Fatal("Missing Protobuf module")
+	} else if _, err := proto.New("bme280", 0x01, 0x1000000, protobuf.PROTOBUF_ADDRESS); err == nil {
+		t.Error("Expected parameter error due to bad sensor")
+	} else if _, err := proto.NewReading(sensors.OT_PARAM_TEMPERATURE, []byte{}); err == nil {
+		t.Error("Expected parameter error due to bad value")
+	} else if msg, err := proto.New("bme280", 0x01, 0x123456, protobuf.PROTOBUF_ADDRESS); err != nil {
+		t.Error(err)
+	} else if msg.Protocol() != "bme280" || msg.Product() != 0x01 || msg.Sensor() != 0x123456 || msg.Address() != protobuf.PROTOBUF_ADDRESS {
+		t.Error("Unexpected message", msg)
+	} else {
+		t.Log(msg)
+	}
+}
+
+func Test_Protobuf_002(t *testing.T) {
+	proto := Protobuf()
+	if proto == nil {
+		t.Fatal("Missing Protobuf module")
+	}
+	msg_in, err := proto.New("bme280", 0x02, 0xABCDEF, protobuf.PROTOBUF_ADDRESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readings := []struct {
+		name  sensors.OTParameter
+		value interface{}
+	}{
+		{sensors.OT_PARAM_TEMPERATURE, float32(21.5)},
+		{sensors.OT_PARAM_RELATIVE_HUMIDITY, uint8(56)},
+		{sensors.OT_PARAM_AIR_PRESSURE, uint(101325)},
+		{sensors.OT_PARAM_FREQUENCY, int(-42)},
+		{sensors.OT_PARAM_DEBUG_OUTPUT, "hello"},
+	}
+	for _, reading := range readings {
+		if record, err := proto.NewReading(reading.name, reading.value); err != nil {
+			t.Fatal(reading.name, err)
+		} else {
+			msg_in.Append(record)
+		}
+	}
+	if payload := proto.Encode(msg_in); len(payload) == 0 {
+		t.Fatal("Unexpected empty payload", msg_in)
+	} else if int(payload[0]) != len(payload)-1 {
+		t.Error("Unexpected length byte", payload)
+	} else if msg_out, err := proto.Decode(payload, time.Now()); err != nil {
+		t.Error(err)
+	} else if Equals(msg_in, msg_out) == false {
+		t.Errorf("Messages don't match: %v and %v", msg_in, msg_out)
+	} else if msg_out_, ok := msg_out.(sensors.ProtoMessage); ok == false {
+		t.Error("Expected ProtoMessage", msg_out)
+	} else if msg_out_.Protocol() != "bme280" || msg_out_.Sensor() != 0xABCDEF {
+		t.Error("Unexpected message", msg_out)
+	} else if value, err := msg_out_.Records()[0].Float32Value(); err != nil || value != 21.5 {
+		t.Error("Unexpected temperature", msg_out_.Records()[0], err)
+	} else if value, err := msg_out_.Records()[3].IntValue(); err != nil || value != -42 {
+		t.Error("Unexpected frequency", msg_out_.Records()[3], err)
+	} else {
+		t.Logf("payload=%v", strings.ToUpper(hex.EncodeToString(payload)))
+		t.Log(msg_out)
+	}
+}
+
+func Test_Protobuf_003(t *testing.T) {
+	proto := Protobuf()
+	if proto == nil {
+		t.Fatal("Missing Protobuf module")
+	}
+	msg, err := proto.New("tsl2561", 0x03, 0x000001, 0x05)
+	if err != nil {
+		t.Fatal(err)
+	} else if record, err := proto.NewReading(sensors.OT_PARAM_LIGHT_LEVEL, float64(123.25)); err != nil {
+		t.Fatal(err)
+	} else {
+		msg.Append(record)
+	}
+
+	// Messages for other addresses are ignored
+	payload := proto.Encode(msg)
+	if _, err := proto.Decode(payload, time.Now()); err == nil {
+		t.Error("Expected error for message to another address")
+	}
+
+	// Receiving any address
+	if driver, err := gopi.Open(protobuf.Protobuf{}, Logger()); err != nil {
+		t.Fatal(err)
+	} else if _, err := driver.(sensors.Proto).Decode(payload, time.Now()); err != nil {
+		t.Error(err)
+	}
+
+	// Broadcast messages are received
+	if msg, err := proto.New("tsl2561", 0x03, 0x000001, protobuf.PROTOBUF_BROADCAST); err != nil {
+		t.Fatal(err)
+	} else if _, err := proto.Decode(proto.Encode(msg), time.Now()); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Protobuf_004(t *testing.T) {
+	proto := Protobuf()
+	if proto == nil {
+		t.Fatal("Missing Protobuf module")
+	}
+	msg, err := proto.New("bme280", 0x01, 0x000001, protobuf.PROTOBUF_ADDRESS)
+	if err != nil {
+		t.Fatal(err)
+	} else if record, err := proto.NewReading(sensors.OT_PARAM_TEMPERATURE, float32(19.0)); err != nil {
+		t.Fatal(err)
+	} else {
+		msg.Append(record)
+	}
+	payload := proto.Encode(msg)
+
+	// Corrupted data fails the CRC
+	corrupt := append([]byte{}, payload...)
+	corrupt[len(corrupt)/2] ^= 0xFF
+	if _, err := proto.Decode(corrupt, time.Now()); err != sensors.ErrMessageCRC {
+		t.Error("Expected CRC error, got", err)
+	}
+
+	// Truncated data fails the length check
+	if _, err := proto.Decode(payload[:len(payload)-1], time.Now()); err != sensors.ErrMessageCorruption {
+		t.Error("Expected corruption error, got", err)
+	}
+
+	// Messages which are too large cannot be encoded
+	for i := 0; i < protobuf.PROTOBUF_PAYLOAD_MAX/8; i++ {
+		if record, err := proto.NewReading(sensors.OT_PARAM_TEMPERATURE, float32(i)); err != nil {
+			t.Fatal(err)
+		} else {
+			msg.Append(record)
+		}
+	}
+	if payload := proto.Encode(msg); payload != nil {
+		t.Error("Expected empty payload for large message")
+	}
+}
+
+func Test_Protobuf_005(t *testing.T) {
+	proto := Protobuf()
+	if proto == nil {
+		t.Fatal("Missing Protobuf module")
+	}
+
+	// Message with an unknown field 15 (varint) and a reading with
+	// an unknown field 9 (fixed32), which should be skipped
+	pb := []byte{
+		0x0A, 0x02, 'n', 'x', // protocol = "nx"
+		0x10, 0x07, // product = 7
+		0x78, 0x96, 0x01, // field 15 = 150
+		0x22, 0x09, // readings
+		0x08, byte(sensors.OT_PARAM_TEMPERATURE), // parameter
+		0x4D, 0x01, 0x02, 0x03, 0x04, // field 9
+		0x20, 0x05, // uint_value = 5
+	}
+	payload := append([]byte{0x00, protobuf.PROTOBUF_ADDRESS}, pb...)
+	payload = append(payload, 0x00, 0x00)
+	payload[0] = uint8(len(payload) - 1)
+	crc := CRC(payload[1 : len(payload)-2])
+	payload[len(payload)-2], payload[len(payload)-1] = uint8(crc>>8), uint8(crc)
+
+	if msg, err := proto.Decode(payload, time.Now()); err != nil {
+		t.Error(err)
+	} else if msg_, ok := msg.(sensors.ProtoMessage); ok == false {
+		t.Error("Expected ProtoMessage", msg)
+	} else if msg_.Protocol() != "nx" || msg_.Product() != 7 || len(msg_.Records()) != 1 {
+		t.Error("Unexpected message", msg)
+	} else if value, err := msg_.Records()[0].UintValue(); err != nil || value != 5 {
+		t.Error("Unexpected reading", msg_.Records()[0], err)
+	}
+}
+
+func Test_Protobuf_006(t *testing.T) {
+	// The codec is written by hand, so check it against the schema
+	// in radio.proto: encoded fields have the field number and wire
+	// type of the schema field, and a message encoded with the schema
+	// field numbers is decoded
+	proto := Protobuf()
+	if proto == nil {
+		t.Fatal("Missing Protobuf module")
+	}
+	schema, err := ProtobufSchema("../rpc/protobuf/radio/radio.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Encode a message with every field set
+	msg, err := proto.New("bme280", 0x02, 0xABCDEF, protobuf.PROTOBUF_ADDRESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reading := range []struct {
+		name  sensors.OTParameter
+		value interface{}
+	}{
+		{sensors.OT_PARAM_TEMPERATURE, float32(21.5)},
+		{sensors.OT_PARAM_FREQUENCY, int(-42)},
+		{sensors.OT_PARAM_AIR_PRESSURE, uint(101325)},
+		{sensors.OT_PARAM_DEBUG_OUTPUT, "hello"},
+	} {
+		if record, err := proto.NewReading(reading.name, reading.value); err != nil {
+			t.Fatal(reading.name, err)
+		} else {
+			msg.Append(record)
+		}
+	}
+	payload := proto.Encode(msg)
+	if len(payload) < 4 {
+		t.Fatal("Unexpected payload", payload)
+	}
+
+	// Walk the encoded message and readings
+	fields := make(map[string]interface{})
+	if err := ProtobufWalk(schema, "Message", payload[2:len(payload)-2], fields); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]interface{}{
+		"Message.protocol":     "bme280",
+		"Message.product":      uint64(0x02),
+		"Message.sensor":       uint64(0xABCDEF),
+		"Reading.float_value":  float32(21.5),
+		"Reading.int_value":    int64(-42),
+		"Reading.uint_value":   uint64(101325),
+		"Reading.string_value": "hello",
+	} {
+		if fields[name] != value {
+			t.Errorf("Unexpected %v: %v", name, fields[name])
+		}
+	}
+	for name := range schema {
+		if _, exists := fields[name]; exists == false {
+			t.Error("Field not encoded:", name)
+		}
+	}
+
+	// Decode a message encoded with the schema field numbers
+	reading := pb.NewBuffer(nil)
+	reading.EncodeVarint(schema["Reading.parameter"].key)
+	reading.EncodeVarint(uint64(sensors.OT_PARAM_FREQUENCY))
+	reading.EncodeVarint(schema["Reading.int_value"].key)
+	value := int64(-7)
+	reading.EncodeZigzag64(uint64(value))
+	message := pb.NewBuffer(nil)
+	message.EncodeVarint(schema["Message.protocol"].key)
+	message.EncodeStringBytes("bme280")
+	message.EncodeVarint(schema["Message.sensor"].key)
+	message.EncodeVarint(0x000042)
+	message.EncodeVarint(schema["Message.readings"].key)
+	message.EncodeRawBytes(reading.Bytes())
+	payload = append([]byte{0x00, protobuf.PROTOBUF_ADDRESS}, message.Bytes()...)
+	crc := CRC(payload[1:])
+	payload = append(payload, uint8(crc>>8), uint8(crc))
+	payload[0] = uint8(len(payload) - 1)
+	if msg, err := proto.Decode(payload, time.Now()); err != nil {
+		t.Error(err)
+	} else if msg_ := msg.(sensors.ProtoMessage); msg_.Protocol() != "bme280" || msg_.Sensor() != 0x000042 || len(msg_.Records()) != 1 {
+		t.Error("Unexpected message", msg)
+	} else if value, err := msg_.Records()[0].IntValue(); err != nil || value != -7 {
+		t.Error("Unexpected reading", msg_.Records()[0], err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROTOBUF
+
+func Protobuf() sensors.ProtobufProto {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/protobuf")); err != nil {
+		return nil
+	} else if proto, ok := app.ModuleInstance("sensors/protocol/protobuf").(sensors.ProtobufProto); ok == false {
+		return nil
+	} else {
+		return proto
+	}
+}
+
+func CRC(buf []byte) uint16 {
+	rem := uint16(0)
+	for _, v := range buf {
+		rem = rem ^ (uint16(v) << 8)
+		for bit := 0; bit < 8; bit++ {
+			if rem&(1<<15) != 0 {
+				rem = ((rem << 1) ^ 0x1021)
+			} else {
+				rem = (rem << 1)
+			}
+		}
+	}
+	return rem
+}
+
+// ProtobufField is a field in the schema, with the key
+// (field number and wire type) used to encode it
+type ProtobufField struct {
+	kind string
+	key  uint64
+}
+
+// ProtobufSchema returns the fields of each message in a schema,
+// keyed by message and field name
+func ProtobufSchema(path string) (map[string]ProtobufField, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema := make(map[string]ProtobufField)
+	message := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.SplitN(line, "//", 2)[0])
+		if match := regexp.MustCompile(`^message\s+(\w+)`).FindStringSubmatch(line); match != nil {
+			message = match[1]
+		} else if match := regexp.MustCompile(`^(repeated\s+)?(\w+)\s+(\w+)\s*=\s*(\d+);`).FindStringSubmatch(line); match != nil && message != "" {
+			number, _ := strconv.ParseUint(match[4], 10, 32)
+			wire := uint64(2)
+			switch match[2] {
+			case "uint32", "uint64", "sint64", "bool":
+				wire = 0
+			case "double":
+				wire = 1
+			case "float":
+				wire = 5
+			}
+			schema[message+"."+match[3]] = ProtobufField{match[2], number<<3 | wire}
+		}
+	}
+	return schema, nil
+}
+
+// ProtobufWalk decodes each field of a message with the schema, and
+// sets the value keyed by message and field name
+func ProtobufWalk(schema map[string]ProtobufField, message string, data []byte, fields map[string]interface{}) error {
+	buf := pb.NewBuffer(data)
+	for {
+		key, err := buf.DecodeVarint()
+		if err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name, field := "", ProtobufField{}
+		for name_, field_ := range schema {
+			if strings.HasPrefix(name_, message+".") && field_.key == key {
+				name, field = name_, field_
+			}
+		}
+		switch field.kind {
+		case "":
+			return fmt.Errorf("%v: Field %v with wire type %v is not in the schema", message, key>>3, key&7)
+		case "string":
+			fields[name], err = buf.DecodeStringBytes()
+		case "uint32", "uint64":
+			fields[name], err = buf.DecodeVarint()
+		case "sint64":
+			var value uint64
+			value, err = buf.DecodeZigzag64()
+			fields[name] = int64(value)
+		case "float":
+			var value uint64
+			value, err = buf.DecodeFixed32()
+			fields[name] = math.Float32frombits(uint32(value))
+		default:
+			var value []byte
+			if value, err = buf.DecodeRawBytes(false); err == nil {
+				fields[name] = value
+				err = ProtobufWalk(schema, field.kind, value, fields)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -11,6 +11,8 @@ package sensors
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/djthorpe/gopi"
@@ -20,22 +22,79 @@ import (
 // RFM69 TYPES
 
 type (
-	RFMMode          uint8
-	RFMDataMode      uint8
-	RFMModulation    uint8
-	RFMPacketFormat  uint8
-	RFMPacketCoding  uint8
-	RFMPacketFilter  uint8
-	RFMPacketCRC     uint8
-	RFMAFCMode       uint8
-	RFMAFCRoutine    uint8
-	RFMTXStart       uint8
-	RFMLNAImpedance  uint8
-	RFMLNAGain       uint8
-	RFMRXBWFrequency uint8
-	RFMRXBWCutoff    uint8
+	RFMMode                  uint8
+	RFMDataMode              uint8
+	RFMModulation            uint8
+	RFMPacketFormat          uint8
+	RFMPacketCoding          uint8
+	RFMPacketFilter          uint8
+	RFMPacketCRC             uint8
+	RFMAFCMode               uint8
+	RFMAFCRoutine            uint8
+	RFMTXStart               uint8
+	RFMLNAImpedance          uint8
+	RFMLNAGain               uint8
+	RFMRXBWFrequency         uint8
+	RFMRXBWCutoff            uint8
+	RFMOOKThresholdType      uint8
+	RFMOOKThresholdStep      uint8
+	RFMOOKThresholdDecrement uint8
 )
 
+// RFMSignal is the signal metadata for a received packet
+type RFMSignal struct {
+	RSSI    float32    // Signal strength in dBm, sampled at sync address match
+	AFC     float64    // Frequency correction applied by AFC in Hz
+	FEI     float64    // Frequency error in Hz, or zero if not measured
+	LNAGain RFMLNAGain // LNA gain in use when the packet was received
+}
+
+// RFMScanResult is the RSSI measured at one frequency during a scan
+type RFMScanResult struct {
+	Frequency uint    // Carrier frequency in Hz
+	Max       float32 // Maximum RSSI in dBm
+	Average   float32 // Average RSSI in dBm
+	Samples   uint    // Number of RSSI samples
+}
+
+// RFMProfile is a set of register settings, which can be saved and
+// restored as JSON. Values are named as returned by the String method
+// of each type, and the sync word and AES key are hex-encoded. The
+// output power is not changed when a profile is applied and it is nil
+type RFMProfile struct {
+	Name                  string                   `json:"name,omitempty"`
+	Modulation            RFMModulation            `json:"modulation"`
+	DataMode              RFMDataMode              `json:"data_mode"`
+	Sequencer             bool                     `json:"sequencer"`
+	Bitrate               uint                     `json:"bitrate"`
+	FreqCarrier           uint                     `json:"freq_carrier"`
+	FreqDeviation         uint                     `json:"freq_deviation"`
+	OutputPower           *int8                    `json:"output_power,omitempty"`
+	AFCMode               RFMAFCMode               `json:"afc_mode"`
+	AFCRoutine            RFMAFCRoutine            `json:"afc_routine"`
+	LNAImpedance          RFMLNAImpedance          `json:"lna_impedance"`
+	LNAGain               RFMLNAGain               `json:"lna_gain"`
+	RXFilterFrequency     RFMRXBWFrequency         `json:"rxbw_frequency"`
+	RXFilterCutoff        RFMRXBWCutoff            `json:"rxbw_cutoff"`
+	PacketFormat          RFMPacketFormat          `json:"packet_format"`
+	PacketCoding          RFMPacketCoding          `json:"packet_coding"`
+	PacketFilter          RFMPacketFilter          `json:"packet_filter"`
+	PacketCRC             RFMPacketCRC             `json:"packet_crc"`
+	PreambleSize          uint16                   `json:"preamble_size"`
+	PayloadSize           uint8                    `json:"payload_size"`
+	NodeAddress           uint8                    `json:"node_address"`
+	BroadcastAddress      uint8                    `json:"broadcast_address"`
+	SyncWord              string                   `json:"sync_word"`
+	SyncTolerance         uint8                    `json:"sync_tolerance"`
+	AESKey                string                   `json:"aes_key"`
+	FIFOThreshold         uint8                    `json:"fifo_threshold"`
+	FIFOFillCondition     bool                     `json:"fifo_fill_condition"`
+	TXStart               RFMTXStart               `json:"tx_start"`
+	OOKThresholdType      RFMOOKThresholdType      `json:"ook_threshold_type"`
+	OOKThresholdStep      RFMOOKThresholdStep      `json:"ook_threshold_step"`
+	OOKThresholdDecrement RFMOOKThresholdDecrement `json:"ook_threshold_dec"`
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // RFM69 INTERFACE
 
@@ -125,8 +184,8 @@ type RFM69 interface {
 
 	// ReadPayload listens for a packet and returns it. If the data is
 	// read then it will also return true if the CRC value was
-	// correct, or false otherwise
-	ReadPayload(ctx context.Context) ([]byte, bool, error)
+	// correct, or false otherwise, and the signal metadata for the packet
+	ReadPayload(ctx context.Context) ([]byte, bool, RFMSignal, error)
 
 	// WritePayload writes a packet a number of times, with a delay between each
 	// when the repeat is greater than zero
@@ -135,23 +194,69 @@ type RFM69 interface {
 	// MeasureTemperature and return after calibration
 	MeasureTemperature(calibration float32) (float32, error)
 
-	/*
-		// OOK Parameters
-		SetOOK(ook_threshold_type RFMOOKThresholdType, ook_threshold_step RFMOOKThresholdStep, ook_threshold_dec RFMOOKThresholdDecrement) error
+	// MeasureRSSI triggers an RSSI measurement and returns it in dBm
+	MeasureRSSI() (float32, error)
+
+	// Output power in dBm, which is between -18 and +13 for the RFM69W
+	// and between -2 and +20 for the RFM69HW
+	OutputPower() int8
+	SetOutputPower(dbm int8) error
+
+	// OOK Parameters
+	OOKThresholdType() RFMOOKThresholdType
+	OOKThresholdStep() RFMOOKThresholdStep
+	OOKThresholdDecrement() RFMOOKThresholdDecrement
+	SetOOK(ook_threshold_type RFMOOKThresholdType, ook_threshold_step RFMOOKThresholdStep, ook_threshold_dec RFMOOKThresholdDecrement) error
+
+	// FIFO Fill Condition, true when the FIFO is filled regardless
+	// of the sync address
+	FIFOFillCondition() bool
+	SetFIFOFillCondition(fifo_fill_condition bool) error
+
+	// TX Start Condition
+	TXStart() RFMTXStart
+	SetTXStart(tx_start RFMTXStart) error
+
+	// ReadFEIHertz measures the frequency error in RX mode
+	ReadFEIHertz() (float64, error)
+
+	// ClearAFC clears the AFC value
+	ClearAFC() error
+
+	// CalibrateRCOsc calibrates the RC oscillator in standby mode
+	CalibrateRCOsc() error
+
+	// Profile returns the current register settings
+	Profile() RFMProfile
+
+	// ApplyProfile validates a profile and then writes the register
+	// settings, which should be done in standby mode
+	ApplyProfile(profile RFMProfile) error
+
+	// Scan steps the carrier frequency from start to stop in Hz,
+	// sampling RSSI in RX mode for the dwell time at each step
+	Scan(ctx context.Context, start, stop, step uint, dwell time.Duration) ([]RFMScanResult, error)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM69 TRANSPORT INTERFACE
 
-		// FIFO
-		FIFOFillCondition() bool
-		SetFIFOFillCondition(fifo_fill_condition bool) error
+// RFMTransport sends data between nodes in packet mode, with
+// acknowledgement, retransmission and duplicate suppression
+type RFMTransport interface {
+	gopi.Driver
 
-		// Other
-		SetTXStart(tx_start RFMTXStart) error
-		TXStart() RFMTXStart
+	// Address returns the node address
+	Address() uint8
 
-		// Methods
-		ReadFEIHertz() (float64, error)
-		ClearAFC() error
-		CalibrateRCOsc() error
-	*/
+	// Send data to a node and wait for acknowledgement, retransmitting
+	// until acknowledged, the retries are exhausted or the context is
+	// done. Data sent to the broadcast address is not acknowledged
+	Send(ctx context.Context, node uint8, data []byte) error
+
+	// Receive waits for data from any node and returns the address
+	// of the node and the data, or an error if the context is done
+	Receive(ctx context.Context) (uint8, []byte, error)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -238,6 +343,42 @@ const (
 	RFM_TXSTART_MAX          RFMTXStart = 0x01 // Mask
 )
 
+const (
+	// OOK Threshold Type
+	RFM_OOK_THRESHOLD_FIXED   RFMOOKThresholdType = 0x00 // Fixed threshold
+	RFM_OOK_THRESHOLD_PEAK    RFMOOKThresholdType = 0x01 // Peak threshold
+	RFM_OOK_THRESHOLD_AVERAGE RFMOOKThresholdType = 0x02 // Average threshold
+	RFM_OOK_THRESHOLD_MAX     RFMOOKThresholdType = 0x02
+)
+
+const (
+	// OOK Threshold Step, the size of each decrement of the
+	// threshold in peak mode
+	RFM_OOK_THRESHOLD_STEP_0P5 RFMOOKThresholdStep = 0x00 // 0.5dB
+	RFM_OOK_THRESHOLD_STEP_1P0 RFMOOKThresholdStep = 0x01 // 1.0dB
+	RFM_OOK_THRESHOLD_STEP_1P5 RFMOOKThresholdStep = 0x02 // 1.5dB
+	RFM_OOK_THRESHOLD_STEP_2P0 RFMOOKThresholdStep = 0x03 // 2.0dB
+	RFM_OOK_THRESHOLD_STEP_3P0 RFMOOKThresholdStep = 0x04 // 3.0dB
+	RFM_OOK_THRESHOLD_STEP_4P0 RFMOOKThresholdStep = 0x05 // 4.0dB
+	RFM_OOK_THRESHOLD_STEP_5P0 RFMOOKThresholdStep = 0x06 // 5.0dB
+	RFM_OOK_THRESHOLD_STEP_6P0 RFMOOKThresholdStep = 0x07 // 6.0dB
+	RFM_OOK_THRESHOLD_STEP_MAX RFMOOKThresholdStep = 0x07
+)
+
+const (
+	// OOK Threshold Decrement, the period of decrements of the
+	// threshold in peak mode
+	RFM_OOK_THRESHOLD_DEC_0P125 RFMOOKThresholdDecrement = 0x00 // Once per 8 chips
+	RFM_OOK_THRESHOLD_DEC_0P25  RFMOOKThresholdDecrement = 0x01 // Once per 4 chips
+	RFM_OOK_THRESHOLD_DEC_0P5   RFMOOKThresholdDecrement = 0x02 // Once per 2 chips
+	RFM_OOK_THRESHOLD_DEC_1     RFMOOKThresholdDecrement = 0x03 // Once per chip
+	RFM_OOK_THRESHOLD_DEC_2     RFMOOKThresholdDecrement = 0x04 // Twice per chip
+	RFM_OOK_THRESHOLD_DEC_4     RFMOOKThresholdDecrement = 0x05 // Four times per chip
+	RFM_OOK_THRESHOLD_DEC_8     RFMOOKThresholdDecrement = 0x06 // Eight times per chip
+	RFM_OOK_THRESHOLD_DEC_16    RFMOOKThresholdDecrement = 0x07 // Sixteen times per chip
+	RFM_OOK_THRESHOLD_DEC_MAX   RFMOOKThresholdDecrement = 0x07
+)
+
 const (
 	// Low Noise Amplifier Impedance
 	RFM_LNA_IMPEDANCE_50  RFMLNAImpedance = 0x00 // 50 Ohms
@@ -463,6 +604,65 @@ func (v RFMTXStart) String() string {
 	}
 }
 
+func (v RFMOOKThresholdType) String() string {
+	switch v {
+	case RFM_OOK_THRESHOLD_FIXED:
+		return "RFM_OOK_THRESHOLD_FIXED"
+	case RFM_OOK_THRESHOLD_PEAK:
+		return "RFM_OOK_THRESHOLD_PEAK"
+	case RFM_OOK_THRESHOLD_AVERAGE:
+		return "RFM_OOK_THRESHOLD_AVERAGE"
+	default:
+		return "[?? Invalid RFMOOKThresholdType value]"
+	}
+}
+
+func (v RFMOOKThresholdStep) String() string {
+	switch v {
+	case RFM_OOK_THRESHOLD_STEP_0P5:
+		return "RFM_OOK_THRESHOLD_STEP_0P5"
+	case RFM_OOK_THRESHOLD_STEP_1P0:
+		return "RFM_OOK_THRESHOLD_STEP_1P0"
+	case RFM_OOK_THRESHOLD_STEP_1P5:
+		return "RFM_OOK_THRESHOLD_STEP_1P5"
+	case RFM_OOK_THRESHOLD_STEP_2P0:
+		return "RFM_OOK_THRESHOLD_STEP_2P0"
+	case RFM_OOK_THRESHOLD_STEP_3P0:
+		return "RFM_OOK_THRESHOLD_STEP_3P0"
+	case RFM_OOK_THRESHOLD_STEP_4P0:
+		return "RFM_OOK_THRESHOLD_STEP_4P0"
+	case RFM_OOK_THRESHOLD_STEP_5P0:
+		return "RFM_OOK_THRESHOLD_STEP_5P0"
+	case RFM_OOK_THRESHOLD_STEP_6P0:
+		return "RFM_OOK_THRESHOLD_STEP_6P0"
+	default:
+		return "[?? Invalid RFMOOKThresholdStep value]"
+	}
+}
+
+func (v RFMOOKThresholdDecrement) String() string {
+	switch v {
+	case RFM_OOK_THRESHOLD_DEC_0P125:
+		return "RFM_OOK_THRESHOLD_DEC_0P125"
+	case RFM_OOK_THRESHOLD_DEC_0P25:
+		return "RFM_OOK_THRESHOLD_DEC_0P25"
+	case RFM_OOK_THRESHOLD_DEC_0P5:
+		return "RFM_OOK_THRESHOLD_DEC_0P5"
+	case RFM_OOK_THRESHOLD_DEC_1:
+		return "RFM_OOK_THRESHOLD_DEC_1"
+	case RFM_OOK_THRESHOLD_DEC_2:
+		return "RFM_OOK_THRESHOLD_DEC_2"
+	case RFM_OOK_THRESHOLD_DEC_4:
+		return "RFM_OOK_THRESHOLD_DEC_4"
+	case RFM_OOK_THRESHOLD_DEC_8:
+		return "RFM_OOK_THRESHOLD_DEC_8"
+	case RFM_OOK_THRESHOLD_DEC_16:
+		return "RFM_OOK_THRESHOLD_DEC_16"
+	default:
+		return "[?? Invalid RFMOOKThresholdDecrement value]"
+	}
+}
+
 func (v RFMLNAImpedance) String() string {
 	switch v {
 	case RFM_LNA_IMPEDANCE_50:
@@ -495,6 +695,14 @@ func (v RFMLNAGain) String() string {
 	}
 }
 
+func (s RFMSignal) String() string {
+	return fmt.Sprintf("<sensors.RFMSignal>{ rssi=%.1fdBm afc=%.0fHz fei=%.0fHz lna_gain=%v }", s.RSSI, s.AFC, s.FEI, s.LNAGain)
+}
+
+func (r RFMScanResult) String() string {
+	return fmt.Sprintf("<sensors.RFMScanResult>{ frequency=%vHz max=%.1fdBm average=%.1fdBm samples=%v }", r.Frequency, r.Max, r.Average, r.Samples)
+}
+
 func (v RFMRXBWCutoff) String() string {
 	switch v {
 	case RFM_RXBW_CUTOFF_16:
@@ -573,3 +781,236 @@ func (v RFMRXBWFrequency) String() string {
 
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// MARSHAL AND UNMARSHAL TEXT
+
+// marshalRFMText returns the name of a value, or an error if the
+// value is invalid
+func marshalRFMText(value fmt.Stringer) ([]byte, error) {
+	if name := value.String(); strings.HasPrefix(name, "[??") {
+		return nil, fmt.Errorf("Invalid value: %v", name)
+	} else {
+		return []byte(name), nil
+	}
+}
+
+// unmarshalRFMText returns the value with a name, or an error if no
+// value has the name
+func unmarshalRFMText(text []byte, value func(uint8) fmt.Stringer) (uint8, error) {
+	name := string(text)
+	for v := 0; v <= 0xFF; v++ {
+		if value(uint8(v)).String() == name {
+			return uint8(v), nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid value: %v", name)
+}
+
+func (m RFMModulation) MarshalText() ([]byte, error) {
+	return marshalRFMText(m)
+}
+
+func (m *RFMModulation) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMModulation(v) }); err != nil {
+		return err
+	} else {
+		*m = RFMModulation(value)
+		return nil
+	}
+}
+
+func (m RFMDataMode) MarshalText() ([]byte, error) {
+	return marshalRFMText(m)
+}
+
+func (m *RFMDataMode) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMDataMode(v) }); err != nil {
+		return err
+	} else {
+		*m = RFMDataMode(value)
+		return nil
+	}
+}
+
+func (f RFMPacketFormat) MarshalText() ([]byte, error) {
+	return marshalRFMText(f)
+}
+
+func (f *RFMPacketFormat) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMPacketFormat(v) }); err != nil {
+		return err
+	} else {
+		*f = RFMPacketFormat(value)
+		return nil
+	}
+}
+
+func (c RFMPacketCoding) MarshalText() ([]byte, error) {
+	return marshalRFMText(c)
+}
+
+func (c *RFMPacketCoding) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMPacketCoding(v) }); err != nil {
+		return err
+	} else {
+		*c = RFMPacketCoding(value)
+		return nil
+	}
+}
+
+func (f RFMPacketFilter) MarshalText() ([]byte, error) {
+	return marshalRFMText(f)
+}
+
+func (f *RFMPacketFilter) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMPacketFilter(v) }); err != nil {
+		return err
+	} else {
+		*f = RFMPacketFilter(value)
+		return nil
+	}
+}
+
+func (c RFMPacketCRC) MarshalText() ([]byte, error) {
+	return marshalRFMText(c)
+}
+
+func (c *RFMPacketCRC) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMPacketCRC(v) }); err != nil {
+		return err
+	} else {
+		*c = RFMPacketCRC(value)
+		return nil
+	}
+}
+
+func (m RFMAFCMode) MarshalText() ([]byte, error) {
+	return marshalRFMText(m)
+}
+
+func (m *RFMAFCMode) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMAFCMode(v) }); err != nil {
+		return err
+	} else {
+		*m = RFMAFCMode(value)
+		return nil
+	}
+}
+
+func (r RFMAFCRoutine) MarshalText() ([]byte, error) {
+	return marshalRFMText(r)
+}
+
+func (r *RFMAFCRoutine) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMAFCRoutine(v) }); err != nil {
+		return err
+	} else {
+		*r = RFMAFCRoutine(value)
+		return nil
+	}
+}
+
+func (v RFMTXStart) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMTXStart) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMTXStart(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMTXStart(value)
+		return nil
+	}
+}
+
+func (v RFMOOKThresholdType) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMOOKThresholdType) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMOOKThresholdType(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMOOKThresholdType(value)
+		return nil
+	}
+}
+
+func (v RFMOOKThresholdStep) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMOOKThresholdStep) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMOOKThresholdStep(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMOOKThresholdStep(value)
+		return nil
+	}
+}
+
+func (v RFMOOKThresholdDecrement) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMOOKThresholdDecrement) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMOOKThresholdDecrement(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMOOKThresholdDecrement(value)
+		return nil
+	}
+}
+
+func (v RFMLNAImpedance) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMLNAImpedance) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMLNAImpedance(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMLNAImpedance(value)
+		return nil
+	}
+}
+
+func (v RFMLNAGain) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMLNAGain) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMLNAGain(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMLNAGain(value)
+		return nil
+	}
+}
+
+func (v RFMRXBWCutoff) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMRXBWCutoff) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMRXBWCutoff(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMRXBWCutoff(value)
+		return nil
+	}
+}
+
+func (v RFMRXBWFrequency) MarshalText() ([]byte, error) {
+	return marshalRFMText(v)
+}
+
+func (v *RFMRXBWFrequency) UnmarshalText(text []byte) error {
+	if value, err := unmarshalRFMText(text, func(v uint8) fmt.Stringer { return RFMRXBWFrequency(v) }); err != nil {
+		return err
+	} else {
+		*v = RFMRXBWFrequency(value)
+		return nil
+	}
+}
@@ -99,6 +99,20 @@ func (this *Client) On(product sensors.MiHomeProduct, sensor uint32) error {
 		return nil
 	}
 }
+
+func (this *Client) OnConfirmed(product sensors.MiHomeProduct, sensor uint32) error {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	key := toProtoSensorKey(sensors.OT_MANUFACTURER_ENERGENIE, product, sensor)
+	key.Confirm = true
+	if _, err := this.MiHomeClient.On(this.NewContext(), key); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
 func (this *Client) Off(product sensors.MiHomeProduct, sensor uint32) error {
 	this.conn.Lock()
 	defer this.conn.Unlock()
@@ -110,6 +124,19 @@ func (this *Client) Off(product sensors.MiHomeProduct, sensor uint32) error {
 	}
 }
 
+func (this *Client) OffConfirmed(product sensors.MiHomeProduct, sensor uint32) error {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	key := toProtoSensorKey(sensors.OT_MANUFACTURER_ENERGENIE, product, sensor)
+	key.Confirm = true
+	if _, err := this.MiHomeClient.Off(this.NewContext(), key); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
 func (this *Client) SendJoin(product sensors.MiHomeProduct, sensor uint32) error {
 	this.conn.Lock()
 	defer this.conn.Unlock()
@@ -165,6 +192,17 @@ func (this *Client) RequestBatteryLevel(product sensors.MiHomeProduct, sensor ui
 	}
 }
 
+func (this *Client) RequestTimeSync(product sensors.MiHomeProduct, sensor uint32) error {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.MiHomeClient.RequestTimeSync(this.NewContext(), toProtoSensorRequest(true, sensors.OT_MANUFACTURER_ENERGENIE, product, sensor)); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
 func (this *Client) SendTargetTemperature(product sensors.MiHomeProduct, sensor uint32, temperature float64) error {
 	this.conn.Lock()
 	defer this.conn.Unlock()
@@ -209,6 +247,63 @@ func (this *Client) SendPowerMode(product sensors.MiHomeProduct, sensor uint32,
 	}
 }
 
+func (this *Client) ListSchedules() ([]*sensors.MiHomeSchedule, error) {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if reply, err := this.MiHomeClient.ListSchedules(this.NewContext(), &empty.Empty{}); err != nil {
+		return nil, err
+	} else {
+		return fromProtoScheduleList(reply), nil
+	}
+}
+
+func (this *Client) SetSchedule(schedule *sensors.MiHomeSchedule) error {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if schedule == nil {
+		return gopi.ErrBadParameter
+	} else if _, err := this.MiHomeClient.SetSchedule(this.NewContext(), toProtoSchedule(schedule)); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) DeleteSchedule(name string) error {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.MiHomeClient.DeleteSchedule(this.NewContext(), &pb.ScheduleName{Name: name}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) SetOverride(name string, override sensors.MiHomeOverridePeriod) error {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.MiHomeClient.SetOverride(this.NewContext(), &pb.ScheduleOverrideRequest{Name: name, Override: toProtoScheduleOverride(override)}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) ClearOverrides(name string) error {
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.MiHomeClient.ClearOverrides(this.NewContext(), &pb.ScheduleName{Name: name}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
 func (this *Client) StreamMessages(ctx context.Context) error {
 	this.conn.Lock()
 	defer this.conn.Unlock()
@@ -19,16 +19,22 @@ import (
 // INIT
 
 func init() {
-	// Register server
+	// Register server, which uses the radio
 	gopi.RegisterModule(gopi.Module{
 		Name:     "rpc/mihome:service",
 		Type:     gopi.MODULE_TYPE_SERVICE,
-		Requires: []string{"rpc/server", "sensors/mihome"},
+		Requires: []string{"rpc/server", "sensors/mihome", "sensors/schedule"},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
-			return gopi.Open(Service{
-				Server: app.ModuleInstance("rpc/server").(gopi.RPCServer),
-				MiHome: app.ModuleInstance("sensors/mihome").(sensors.MiHome),
-			}, app.Logger)
+			return newService(app, "sensors/mihome")
+		},
+	})
+	// Register server, which receives from a capture file
+	gopi.RegisterModule(gopi.Module{
+		Name:     "rpc/mihome:service/replay",
+		Type:     gopi.MODULE_TYPE_SERVICE,
+		Requires: []string{"rpc/server", "sensors/mihome/replay", "sensors/schedule"},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			return newService(app, "sensors/mihome/replay")
 		},
 	})
 	// Register client
@@ -47,3 +53,12 @@ func init() {
 	})
 
 }
+
+// newService opens the service with the MiHome driver from the module
+func newService(app *gopi.AppInstance, mihome string) (gopi.Driver, error) {
+	return gopi.Open(Service{
+		Server:   app.ModuleInstance("rpc/server").(gopi.RPCServer),
+		MiHome:   app.ModuleInstance(mihome).(sensors.MiHome),
+		Schedule: app.ModuleInstance("sensors/schedule").(sensors.MiHomeScheduler),
+	}, app.Logger)
+}
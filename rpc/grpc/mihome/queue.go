@@ -23,13 +23,18 @@ import (
 // TYPES
 
 type queue struct {
-	log    gopi.Logger
-	mihome sensors.MiHome
-	queue  []*message
+	log      gopi.Logger
+	mihome   sensors.MiHome
+	schedule sensors.MiHomeScheduler
+	queue    []*message
 
 	// Lock queue
 	sync.Mutex
 
+	// Target temperatures queued from the schedules
+	scheduled      map[uint32]float64
+	scheduled_lock sync.Mutex
+
 	// Receive messages in the background
 	event.Tasks
 }
@@ -44,6 +49,14 @@ type message struct {
 	low_power   bool
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Interval between checking the schedules for target temperatures
+	SCHEDULE_INTERVAL = time.Minute
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // OPEN AND CLOSE
 
@@ -51,16 +64,21 @@ type message struct {
 func (this *queue) Init(log gopi.Logger, config Service) error {
 	log.Debug("<grpc.service.mihome.Queue>Init{}")
 
-	if log == nil || config.MiHome == nil {
+	if log == nil || config.MiHome == nil || config.Schedule == nil {
 		return gopi.ErrBadParameter
 	}
 	this.log = log
 	this.mihome = config.MiHome
+	this.schedule = config.Schedule
 	this.queue = make([]*message, 0)
+	this.scheduled = make(map[uint32]float64)
 
 	// Start background task which reports on all events (for debugging, device collection)
 	this.Tasks.Start(this.EventTask)
 
+	// Start background task which queues target temperatures from the schedules
+	this.Tasks.Start(this.ScheduleTask)
+
 	// Success
 	return nil
 }
@@ -76,7 +94,9 @@ func (this *queue) Destroy() error {
 	// Release resources
 	this.log = nil
 	this.mihome = nil
+	this.schedule = nil
 	this.queue = nil
+	this.scheduled = nil
 
 	// Success
 	return nil
@@ -158,6 +178,23 @@ func (this *queue) QueueBatteryLevel(product sensors.MiHomeProduct, sensor uint3
 	return nil
 }
 
+// QueueTimeSync queues sending the time, which is read when the
+// device next reports and the request is passed to the radio, rather
+// than when the request is queued here
+func (this *queue) QueueTimeSync(product sensors.MiHomeProduct, sensor uint32) error {
+	this.log.Debug("<grpc.service.mihome.Queue>QueueTimeSync{ product=%v sensor=0x%08X }", product, sensor)
+
+	if this.Match(product, sensor, sensors.OT_PARAM_TIME_AND_DATE, false) != nil {
+		// Ignore if there is an existing message in the queue
+		return gopi.ErrNotModified
+	} else {
+		this.Append(&message{product, sensor, sensors.OT_PARAM_TIME_AND_DATE, 0, 0, 0, false})
+	}
+
+	// Return sucess
+	return nil
+}
+
 func (this *queue) QueueTargetTemperature(product sensors.MiHomeProduct, sensor uint32, temperature float64) error {
 	this.log.Debug("<grpc.service.mihome.Queue>QueueTargetTemperature{ product=%v sensor=0x%08X temperature=%v }", product, sensor, temperature)
 
@@ -239,9 +276,55 @@ func (this *queue) QueueLowPowerMode(product sensors.MiHomeProduct, sensor uint3
 	return nil
 }
 
+// QueueSchedule queues the target temperature for each eTRV in the schedules
+// at a time, when it differs from the target temperature previously queued
+// from the schedules, so that it's sent when the eTRV next reports
+func (this *queue) QueueSchedule(ts time.Time) {
+	this.scheduled_lock.Lock()
+	defer this.scheduled_lock.Unlock()
+
+	// Forget sensors which are no longer in a schedule
+	targets := this.schedule.Targets(ts)
+	for sensor := range this.scheduled {
+		if _, exists := targets[sensor]; exists == false {
+			delete(this.scheduled, sensor)
+		}
+	}
+
+	// Queue target temperatures which have changed
+	for sensor, temperature := range targets {
+		if previous, exists := this.scheduled[sensor]; exists && previous == temperature {
+			continue
+		} else if err := this.QueueTargetTemperature(sensors.MIHOME_PRODUCT_MIHO013, sensor, temperature); err != nil && err != gopi.ErrNotModified {
+			this.log.Error("QueueSchedule: 0x%06X: %v", sensor, err)
+		} else {
+			this.scheduled[sensor] = temperature
+		}
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // BACKGROUND TASKS
 
+func (this *queue) ScheduleTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+	ticker := time.NewTicker(SCHEDULE_INTERVAL)
+	this.QueueSchedule(time.Now())
+FOR_LOOP:
+	for {
+		select {
+		case ts := <-ticker.C:
+			this.QueueSchedule(ts)
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+	ticker.Stop()
+
+	// Success
+	return nil
+}
+
 func (this *queue) EventTask(start chan<- event.Signal, stop <-chan event.Signal) error {
 	start <- gopi.DONE
 	events := this.mihome.Subscribe()
@@ -249,7 +332,14 @@ FOR_LOOP:
 	for {
 		select {
 		case evt := <-events:
-			if evt_, ok := evt.(sensors.Message); ok == false {
+			if evt_, ok := evt.(sensors.MiHomeTXEvent); ok {
+				if err := evt_.Err(); err != nil {
+					this.log.Warn("Transmit: %v: %v", evt_.Message(), err)
+				}
+			} else if _, ok := evt.(sensors.MiHomeChangeEvent); ok {
+				// Changes are handled through the messages
+				continue
+			} else if evt_, ok := evt.(sensors.Message); ok == false {
 				this.log.Warn("Ignoring: %v", evt)
 			} else if err := this.HandleEvent(evt_); err != nil {
 				this.log.Error("%v", err)
@@ -327,6 +417,8 @@ func (this *queue) SendReply(message *message) error {
 		return this.mihome.RequestExercise(message.product, message.sensor)
 	case sensors.OT_PARAM_BATTERY_LEVEL:
 		return this.mihome.RequestBatteryLevel(message.product, message.sensor)
+	case sensors.OT_PARAM_TIME_AND_DATE:
+		return this.mihome.RequestTimeSync(message.product, message.sensor)
 	case sensors.OT_PARAM_TEMPERATURE:
 		return this.mihome.RequestTargetTemperature(message.product, message.sensor, message.temperature)
 	case sensors.OT_PARAM_REPORT_PERIOD:
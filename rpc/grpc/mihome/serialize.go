@@ -11,6 +11,7 @@ package mihome
 import (
 	"encoding/hex"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	pb "github.com/djthorpe/sensors/rpc/protobuf/mihome"
 	ptypes "github.com/golang/protobuf/ptypes"
 	duration "github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -96,16 +98,19 @@ func toProtoMessage(msg sensors.Message) *pb.Message {
 		return nil
 	} else if msg_, ok := msg.(sensors.OTMessage); ok {
 		return &pb.Message{
-			Sender: toProtoSensorKey(msg_.Manufacturer(), sensors.MiHomeProduct(msg_.Product()), msg_.Sensor()),
-			Ts:     ts,
-			Data:   msg_.Data(),
-			Params: toProtoParameterArray(msg_.Records()),
+			Sender:  toProtoSensorKey(msg_.Manufacturer(), sensors.MiHomeProduct(msg_.Product()), msg_.Sensor()),
+			Ts:      ts,
+			Data:    msg_.Data(),
+			Params:  toProtoParameterArray(msg_.Records()),
+			Signal:  toProtoSignal(msg_.Signal()),
+			Partial: msg_.IsPartial(),
 		}
 	} else if msg_, ok := msg.(sensors.OOKMessage); ok {
 		return &pb.Message{
 			Sender: toProtoSensorKeyOOK(msg_.Addr(), msg_.Socket()),
 			Ts:     ts,
 			Data:   msg_.Data(),
+			Signal: toProtoSignal(msg_.Signal()),
 		}
 	} else {
 		return nil
@@ -116,6 +121,35 @@ func fromProtoMessage(message *pb.Message, conn gopi.RPCClientConn) sensors.OTMe
 	return &pb_message{message, conn}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// SIGNAL
+
+func toProtoSignal(signal *sensors.RFMSignal) *pb.Signal {
+	if signal == nil {
+		return nil
+	} else {
+		return &pb.Signal{
+			Rssi:    signal.RSSI,
+			Afc:     signal.AFC,
+			Fei:     signal.FEI,
+			LnaGain: uint32(signal.LNAGain),
+		}
+	}
+}
+
+func fromProtoSignal(signal *pb.Signal) *sensors.RFMSignal {
+	if signal == nil {
+		return nil
+	} else {
+		return &sensors.RFMSignal{
+			RSSI:    signal.Rssi,
+			AFC:     signal.Afc,
+			FEI:     signal.Fei,
+			LNAGain: sensors.RFMLNAGain(signal.LnaGain),
+		}
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SENSOR KEY
 
@@ -186,6 +220,119 @@ func toProtoSensorRequestPowerMode(queue_request bool, manufacturer sensors.OTMa
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// SCHEDULES
+
+func toProtoScheduleList(schedules []*sensors.MiHomeSchedule) *pb.ScheduleList {
+	list := &pb.ScheduleList{
+		Schedules: make([]*pb.Schedule, len(schedules)),
+	}
+	for i, schedule := range schedules {
+		list.Schedules[i] = toProtoSchedule(schedule)
+	}
+	return list
+}
+
+func toProtoSchedule(schedule *sensors.MiHomeSchedule) *pb.Schedule {
+	if schedule == nil {
+		return nil
+	}
+	proto := &pb.Schedule{
+		Name:      schedule.Name,
+		Sensors:   schedule.Sensors,
+		Periods:   make([]*pb.SchedulePeriod, len(schedule.Periods)),
+		Overrides: make([]*pb.ScheduleOverride, len(schedule.Overrides)),
+	}
+	for i, period := range schedule.Periods {
+		proto.Periods[i] = &pb.SchedulePeriod{
+			Day:     uint32(period.Day),
+			Start:   ptypes.DurationProto(period.Start),
+			Celcius: period.Celcius,
+		}
+	}
+	for i, override := range schedule.Overrides {
+		proto.Overrides[i] = toProtoScheduleOverride(override)
+	}
+	return proto
+}
+
+func fromProtoScheduleList(proto *pb.ScheduleList) []*sensors.MiHomeSchedule {
+	if proto == nil {
+		return nil
+	}
+	schedules := make([]*sensors.MiHomeSchedule, 0, len(proto.Schedules))
+	for _, schedule := range proto.Schedules {
+		if schedule_ := fromProtoSchedule(schedule); schedule_ != nil {
+			schedules = append(schedules, schedule_)
+		}
+	}
+	return schedules
+}
+
+func fromProtoSchedule(proto *pb.Schedule) *sensors.MiHomeSchedule {
+	if proto == nil {
+		return nil
+	}
+	schedule := &sensors.MiHomeSchedule{
+		Name:      proto.Name,
+		Sensors:   proto.Sensors,
+		Periods:   make([]sensors.MiHomePeriod, 0, len(proto.Periods)),
+		Overrides: make([]sensors.MiHomeOverridePeriod, 0, len(proto.Overrides)),
+	}
+	for _, period := range proto.Periods {
+		if period != nil {
+			schedule.Periods = append(schedule.Periods, sensors.MiHomePeriod{
+				Day:     time.Weekday(period.Day),
+				Start:   fromProtoDuration(period.Start),
+				Celcius: period.Celcius,
+			})
+		}
+	}
+	for _, override := range proto.Overrides {
+		if override != nil {
+			schedule.Overrides = append(schedule.Overrides, fromProtoScheduleOverride(override))
+		}
+	}
+	return schedule
+}
+
+func toProtoScheduleOverride(override sensors.MiHomeOverridePeriod) *pb.ScheduleOverride {
+	return &pb.ScheduleOverride{
+		Type:    pb.ScheduleOverride_Type(override.Type),
+		Start:   toProtoTimestamp(override.Start),
+		End:     toProtoTimestamp(override.End),
+		Celcius: override.Celcius,
+	}
+}
+
+func fromProtoScheduleOverride(proto *pb.ScheduleOverride) sensors.MiHomeOverridePeriod {
+	if proto == nil {
+		return sensors.MiHomeOverridePeriod{}
+	}
+	return sensors.MiHomeOverridePeriod{
+		Type:    sensors.MiHomeOverride(proto.Type),
+		Start:   fromProtoTimestamp(proto.Start),
+		End:     fromProtoTimestamp(proto.End),
+		Celcius: proto.Celcius,
+	}
+}
+
+func toProtoTimestamp(ts time.Time) *timestamp.Timestamp {
+	if proto, err := ptypes.TimestampProto(ts); err != nil {
+		return nil
+	} else {
+		return proto
+	}
+}
+
+func fromProtoTimestamp(proto *timestamp.Timestamp) time.Time {
+	if ts, err := ptypes.Timestamp(proto); err != nil {
+		return time.Time{}
+	} else {
+		return ts
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PARAMETERS
 
@@ -210,6 +357,12 @@ func toProtoParameter(record sensors.OTRecord) *pb.Parameter {
 			Name:   pb.Parameter_Name(record.Name()),
 			Report: record.IsReport(),
 			Data:   data,
+			Opaque: record.IsOpaque(),
+		}
+
+		// Opaque records only carry the data
+		if param.Opaque {
+			return param
 		}
 
 		switch record.Type() {
@@ -245,7 +398,15 @@ func toProtoParameter(record sensors.OTRecord) *pb.Parameter {
 					IntValue: dec,
 				}
 			}
-		case sensors.OT_DATATYPE_DEC_8, sensors.OT_DATATYPE_DEC_16, sensors.OT_DATATYPE_DEC_24:
+		case sensors.OT_DATATYPE_ENUM:
+			if enum, err := record.EnumValue(); err != nil {
+				return nil
+			} else {
+				param.Value = &pb.Parameter_UintValue{
+					UintValue: enum,
+				}
+			}
+		case sensors.OT_DATATYPE_DEC_8, sensors.OT_DATATYPE_DEC_16, sensors.OT_DATATYPE_DEC_24, sensors.OT_DATATYPE_FLOAT:
 			if dec, err := record.FloatValue(); err != nil {
 				return nil
 			} else {
@@ -304,6 +465,14 @@ func (this *pb_message) Records() []sensors.OTRecord {
 	}
 }
 
+func (this *pb_message) IsPartial() bool {
+	if this.pb == nil {
+		return false
+	} else {
+		return this.pb.Partial
+	}
+}
+
 func (this *pb_message) Data() []byte {
 	if this.pb == nil {
 		return nil
@@ -322,6 +491,18 @@ func (this *pb_message) Timestamp() time.Time {
 	}
 }
 
+func (this *pb_message) Signal() *sensors.RFMSignal {
+	if this.pb == nil {
+		return nil
+	} else {
+		return fromProtoSignal(this.pb.Signal)
+	}
+}
+
+func (this *pb_message) SetSignal(*sensors.RFMSignal) {
+	// NOT IMPLEMENTED
+}
+
 func (this *pb_message) IsDuplicate(other sensors.Message) bool {
 	if this.pb == nil || other == nil {
 		return false
@@ -382,8 +563,24 @@ func (this *pb_record) Name() sensors.OTParameter {
 	}
 }
 
+// Type returns the data type from the encoded record, which is the
+// high nibble of the second byte
 func (this *pb_record) Type() sensors.OTDataType {
-	return 0
+	if this.pb == nil || len(this.pb.Data) < 2 {
+		return 0
+	} else if this.pb.Opaque && this.Name() == sensors.OT_PARAM_NONE {
+		return 0
+	} else {
+		return sensors.OTDataType(this.pb.Data[1]>>4) & 0x0F
+	}
+}
+
+func (this *pb_record) IsOpaque() bool {
+	if this.pb == nil {
+		return false
+	} else {
+		return this.pb.Opaque
+	}
 }
 
 func (this *pb_record) IsReport() bool {
@@ -422,6 +619,29 @@ func (this *pb_record) FloatValue() (float64, error) {
 	return 0, gopi.ErrAppError
 }
 
+func (this *pb_record) EnumValue() (uint64, error) {
+	return 0, gopi.ErrAppError
+}
+
+func (this *pb_record) Float32Value() (float32, error) {
+	return 0, gopi.ErrAppError
+}
+
+// TimeValue returns a UDEC_0 record as seconds since the epoch
+func (this *pb_record) TimeValue() (time.Time, error) {
+	if this.pb == nil {
+		return time.Time{}, gopi.ErrAppError
+	} else if this.Type() != sensors.OT_DATATYPE_UDEC_0 {
+		return time.Time{}, gopi.ErrNotImplemented
+	} else if value, ok := this.pb.Value.(*pb.Parameter_UintValue); ok == false || len(this.pb.Data) <= 2 {
+		return time.Time{}, gopi.ErrBadParameter
+	} else if value.UintValue > math.MaxUint32 {
+		return time.Time{}, gopi.ErrBadParameter
+	} else {
+		return time.Unix(int64(value.UintValue), 0).UTC(), nil
+	}
+}
+
 // Compares one record against another and returns true if identical
 func (this *pb_record) IsDuplicate(other sensors.OTRecord) bool {
 	if this.pb == nil || other == nil {
@@ -445,6 +665,8 @@ func (this *pb_record) IsDuplicate(other sensors.OTRecord) bool {
 func (this *pb_record) String() string {
 	if this.pb == nil {
 		return "<nil>"
+	} else if this.IsOpaque() {
+		return fmt.Sprintf("<%v=%v [opaque]>", this.Name(), strings.ToUpper(hex.EncodeToString(this.pb.Data)))
 	} else if this.IsReport() {
 		return fmt.Sprintf("<%v=%v [report]>", this.Name(), this.Value())
 	} else {
@@ -455,6 +677,8 @@ func (this *pb_record) String() string {
 func (this *pb_record) Value() interface{} {
 	if this.pb == nil {
 		return nil
+	} else if this.pb.Opaque && this.Name() == sensors.OT_PARAM_NONE {
+		return this.pb.Data
 	}
 	switch this.pb.Value.(type) {
 	case *pb.Parameter_StringValue:
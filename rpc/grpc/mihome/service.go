@@ -29,8 +29,9 @@ import (
 // TYPES
 
 type Service struct {
-	Server gopi.RPCServer
-	MiHome sensors.MiHome
+	Server   gopi.RPCServer
+	MiHome   sensors.MiHome
+	Schedule sensors.MiHomeScheduler
 }
 
 type service struct {
@@ -56,7 +57,7 @@ func (config Service) Open(log gopi.Logger) (gopi.Driver, error) {
 	log.Debug("<grpc.service.mihome.Open>{ server=%v mihome=%v }", config.Server, config.MiHome)
 
 	// Check for bad input parameters
-	if config.Server == nil || config.MiHome == nil {
+	if config.Server == nil || config.MiHome == nil || config.Schedule == nil {
 		return nil, gopi.ErrBadParameter
 	}
 
@@ -146,14 +147,11 @@ func (this *service) Reset(context.Context, *empty.Empty) (*empty.Empty, error)
 func (this *service) On(ctx context.Context, key *pb.SensorKey) (*empty.Empty, error) {
 	this.log.Debug("<grpc.service.mihome>On{ key=%v }", key)
 
-	this.Lock()
-	defer this.Unlock()
-
 	if manufacturer, product, sensor, err := fromProtobufSensorKey(key); err != nil {
 		return nil, err
 	} else if manufacturer != sensors.OT_MANUFACTURER_ENERGENIE {
 		return nil, gopi.ErrBadParameter
-	} else if err := this.mihome.RequestSwitchOn(product, sensor); err != nil {
+	} else if err := this.request_switch(ctx, product, sensor, true, key.Confirm); err != nil {
 		this.log.Error("On: %v", err)
 		return nil, err
 	} else {
@@ -165,14 +163,11 @@ func (this *service) On(ctx context.Context, key *pb.SensorKey) (*empty.Empty, e
 func (this *service) Off(ctx context.Context, key *pb.SensorKey) (*empty.Empty, error) {
 	this.log.Debug("<grpc.service.mihome>Off{ key=%v }", key)
 
-	this.Lock()
-	defer this.Unlock()
-
 	if manufacturer, product, sensor, err := fromProtobufSensorKey(key); err != nil {
 		return nil, err
 	} else if manufacturer != sensors.OT_MANUFACTURER_ENERGENIE {
 		return nil, gopi.ErrBadParameter
-	} else if err := this.mihome.RequestSwitchOff(product, sensor); err != nil {
+	} else if err := this.request_switch(ctx, product, sensor, false, key.Confirm); err != nil {
 		this.log.Error("Off: %v", err)
 		return nil, err
 	} else {
@@ -214,6 +209,12 @@ FOR_LOOP:
 		case evt := <-events:
 			if evt == nil {
 				break FOR_LOOP
+			} else if _, ok := evt.(sensors.MiHomeTXEvent); ok {
+				// Transmitted messages are not streamed
+				continue
+			} else if _, ok := evt.(sensors.MiHomeChangeEvent); ok {
+				// Changes are streamed as messages
+				continue
 			} else if evt_, ok := evt.(sensors.Message); ok {
 				if err := stream.Send(toProtoMessage(evt_)); err != nil {
 					this.log.Warn("StreamMessages: %v", err)
@@ -365,6 +366,32 @@ func (this *service) RequestBatteryLevel(ctx context.Context, req *pb.SensorRequ
 	return &empty.Empty{}, nil
 }
 
+func (this *service) RequestTimeSync(ctx context.Context, req *pb.SensorRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.mihome>RequestTimeSync{ req=%v }", req)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if manufacturer, product, sensor, err := fromProtobufSensorKey(req.Sensor); err != nil {
+		return nil, err
+	} else if manufacturer != sensors.OT_MANUFACTURER_ENERGENIE {
+		return nil, gopi.ErrBadParameter
+	} else if req.QueueRequest {
+		if err := this.queue.QueueTimeSync(product, sensor); err != nil {
+			this.log.Error("QueueTimeSync: %v", err)
+			return nil, err
+		}
+	} else {
+		if err := this.mihome.RequestTimeSync(product, sensor); err != nil {
+			this.log.Error("RequestTimeSync: %v", err)
+			return nil, err
+		}
+	}
+
+	// Success
+	return &empty.Empty{}, nil
+}
+
 func (this *service) SendTargetTemperature(ctx context.Context, req *pb.SensorRequestTemperature) (*empty.Empty, error) {
 	this.log.Debug("<grpc.service.mihome>SendTargetTemperature{ req=%v }", req)
 
@@ -470,3 +497,103 @@ func (this *service) SendValveState(ctx context.Context, req *pb.SensorRequestVa
 	// Success
 	return &empty.Empty{}, nil
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// SCHEDULES
+
+func (this *service) ListSchedules(ctx context.Context, _ *empty.Empty) (*pb.ScheduleList, error) {
+	this.log.Debug("<grpc.service.mihome>ListSchedules{}")
+
+	return toProtoScheduleList(this.queue.schedule.Schedules()), nil
+}
+
+func (this *service) SetSchedule(ctx context.Context, req *pb.Schedule) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.mihome>SetSchedule{ req=%v }", req)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.queue.schedule.SetSchedule(fromProtoSchedule(req)); err != nil {
+		this.log.Error("SetSchedule: %v", err)
+		return nil, err
+	} else {
+		this.queue.QueueSchedule(time.Now())
+	}
+
+	// Success
+	return &empty.Empty{}, nil
+}
+
+func (this *service) DeleteSchedule(ctx context.Context, req *pb.ScheduleName) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.mihome>DeleteSchedule{ req=%v }", req)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.queue.schedule.DeleteSchedule(req.Name); err != nil {
+		this.log.Error("DeleteSchedule: %v", err)
+		return nil, err
+	} else {
+		this.queue.QueueSchedule(time.Now())
+	}
+
+	// Success
+	return &empty.Empty{}, nil
+}
+
+func (this *service) SetOverride(ctx context.Context, req *pb.ScheduleOverrideRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.mihome>SetOverride{ req=%v }", req)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if req.Override == nil {
+		return nil, gopi.ErrBadParameter
+	} else if err := this.queue.schedule.SetOverride(req.Name, fromProtoScheduleOverride(req.Override)); err != nil {
+		this.log.Error("SetOverride: %v", err)
+		return nil, err
+	} else {
+		this.queue.QueueSchedule(time.Now())
+	}
+
+	// Success
+	return &empty.Empty{}, nil
+}
+
+func (this *service) ClearOverrides(ctx context.Context, req *pb.ScheduleName) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.mihome>ClearOverrides{ req=%v }", req)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.queue.schedule.ClearOverrides(req.Name); err != nil {
+		this.log.Error("ClearOverrides: %v", err)
+		return nil, err
+	} else {
+		this.queue.QueueSchedule(time.Now())
+	}
+
+	// Success
+	return &empty.Empty{}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// request_switch sends an On or Off signal. When confirm is set it waits
+// for the device to report the switch state, and the lock isn't held
+// while waiting
+func (this *service) request_switch(ctx context.Context, product sensors.MiHomeProduct, sensor uint32, state, confirm bool) error {
+	if confirm {
+		return this.mihome.RequestSwitchConfirmed(ctx, product, sensor, state)
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if state {
+		return this.mihome.RequestSwitchOn(product, sensor)
+	} else {
+		return this.mihome.RequestSwitchOff(product, sensor)
+	}
+}
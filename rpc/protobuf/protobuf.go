@@ -19,7 +19,9 @@ package protobuf
 	go generate -x github.com/djthorpe/sensors/protobuf
 
 	where you have installed the protoc compiler and the GRPC plugin for
-	golang. In order to do that on a Mac:
+	golang. The radio/radio.proto messages are sent by sensor nodes over
+	the radio and are encoded by protocol/protobuf without generated code,
+	which is tested against the schema. In order to do that on a Mac:
 
 	mac# brew install protobuf
 	mac# go get -u github.com/golang/protobuf/protoc-gen-go
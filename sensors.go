@@ -180,6 +180,7 @@ var (
 	ErrDeviceTimeout      = errors.New("Device timeout")
 	ErrMessageCorruption  = errors.New("Message Corrupt")
 	ErrMessageCRC         = errors.New("CRC Error")
+	ErrMessageSuperseded  = errors.New("Message superseded")
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,162 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package capture
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Direction is whether a payload was received or transmitted
+type Direction uint
+
+// Entry is a single payload in a capture file. Each entry is
+// written as a line with the following space-separated fields:
+//
+//	<timestamp> <direction> <mode> <payload>
+//
+// where timestamp is in RFC3339 format with nanoseconds, direction
+// is RX or TX, mode is MONITOR or CONTROL and payload is hex-encoded.
+// Empty lines and lines starting with '#' are ignored.
+type Entry struct {
+	Timestamp time.Time
+	Direction Direction
+	Mode      sensors.MiHomeMode
+	Payload   []byte
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	DIRECTION_NONE Direction = iota
+	DIRECTION_RX
+	DIRECTION_TX
+)
+
+const (
+	// Number of fields in a line
+	ENTRY_FIELDS = 4
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (d Direction) String() string {
+	switch d {
+	case DIRECTION_RX:
+		return "RX"
+	case DIRECTION_TX:
+		return "TX"
+	default:
+		return "[?? Invalid Direction value]"
+	}
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("%v %v %v %v",
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.Direction,
+		stringFromMiHomeMode(e.Mode),
+		strings.ToUpper(hex.EncodeToString(e.Payload)),
+	)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE ENTRIES
+
+// WriteEntry writes a single entry as a line
+func WriteEntry(w io.Writer, entry *Entry) error {
+	_, err := fmt.Fprintln(w, entry)
+	return err
+}
+
+// ReadEntries reads all entries until end of file, and returns an
+// error which includes the line number if a line cannot be parsed
+func ReadEntries(r io.Reader) ([]*Entry, error) {
+	entries := make([]*Entry, 0)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		} else if entry, err := parseEntry(text); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func parseEntry(text string) (*Entry, error) {
+	entry := new(Entry)
+	if fields := strings.Fields(text); len(fields) != ENTRY_FIELDS {
+		return nil, fmt.Errorf("Expected %v fields", ENTRY_FIELDS)
+	} else if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+		return nil, fmt.Errorf("Invalid timestamp: %v", fields[0])
+	} else if direction, err := directionFromString(fields[1]); err != nil {
+		return nil, err
+	} else if mode, err := miHomeModeFromString(fields[2]); err != nil {
+		return nil, err
+	} else if payload, err := hex.DecodeString(fields[3]); err != nil || len(payload) == 0 {
+		return nil, fmt.Errorf("Invalid payload: %v", fields[3])
+	} else {
+		entry.Timestamp = ts
+		entry.Direction = direction
+		entry.Mode = mode
+		entry.Payload = payload
+	}
+	return entry, nil
+}
+
+func directionFromString(value string) (Direction, error) {
+	switch strings.ToUpper(value) {
+	case "RX":
+		return DIRECTION_RX, nil
+	case "TX":
+		return DIRECTION_TX, nil
+	default:
+		return DIRECTION_NONE, fmt.Errorf("Invalid direction: %v", value)
+	}
+}
+
+// stringFromMiHomeMode returns an upper-case string from a MiHomeMode
+func stringFromMiHomeMode(mode sensors.MiHomeMode) string {
+	return strings.TrimPrefix(fmt.Sprint(mode), "MIHOME_MODE_")
+}
+
+// miHomeModeFromString returns a MiHomeMode other than NONE given
+// a string, or returns an error otherwise. Case-insensitive
+func miHomeModeFromString(value string) (sensors.MiHomeMode, error) {
+	value_upper := strings.ToUpper(value)
+	for mode := sensors.MIHOME_MODE_NONE + 1; mode <= sensors.MIHOME_MODE_MAX; mode++ {
+		if stringFromMiHomeMode(mode) == value_upper {
+			return mode, nil
+		}
+	}
+	return sensors.MIHOME_MODE_NONE, fmt.Errorf("Invalid mode: %v", value)
+}
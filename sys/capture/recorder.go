@@ -0,0 +1,184 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Recorder configuration. The writer is closed when the
+// recorder is closed if it implements io.Closer
+type Recorder struct {
+	Radio  sensors.ENER314RT // Radio to record payloads from
+	Writer io.Writer         // Capture file
+}
+
+// recorder driver, which implements sensors.ENER314RT
+type recorder struct {
+	log    gopi.Logger
+	radio  sensors.ENER314RT
+	writer io.Writer
+
+	// Locker for writing entries
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Recorder) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.capture.Recorder>Open{ radio=%v }", config.Radio)
+
+	if config.Radio == nil || config.Writer == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(recorder)
+	this.log = log
+	this.radio = config.Radio
+	this.writer = config.Writer
+
+	// Write header
+	if _, err := fmt.Fprintf(this.writer, "# Capture started %v\n", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *recorder) Close() error {
+	this.log.Debug("<sensors.capture.Recorder>Close{}")
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Close writer
+	if closer, ok := this.writer.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+
+	// Free resources
+	this.radio = nil
+	this.writer = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *recorder) String() string {
+	return fmt.Sprintf("<sensors.capture.Recorder>{ radio=%v }", this.radio)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Receive payloads from the radio, recording each one
+func (this *recorder) Receive(ctx context.Context, mode sensors.MiHomeMode, payload chan<- sensors.MiHomePayload) error {
+	this.log.Debug2("<sensors.capture.Recorder>Receive{ mode=%v }", mode)
+
+	if this.radio == nil {
+		return gopi.ErrOutOfOrder
+	} else if payload == nil {
+		return gopi.ErrBadParameter
+	}
+
+	// Record and forward payloads in the background
+	rx := make(chan sensors.MiHomePayload)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for data := range rx {
+			this.record(DIRECTION_RX, mode, data.Data)
+			select {
+			case payload <- data:
+				break
+			case <-ctx.Done():
+				break
+			}
+		}
+	}()
+
+	// Receive until context is done
+	err := this.radio.Receive(ctx, mode, rx)
+	close(rx)
+	<-done
+
+	return err
+}
+
+// Send a payload with the radio, recording it when successful
+func (this *recorder) Send(payload []byte, repeat uint, mode sensors.MiHomeMode) error {
+	if this.radio == nil {
+		return gopi.ErrOutOfOrder
+	} else if err := this.radio.Send(payload, repeat, mode); err != nil {
+		return err
+	} else {
+		this.record(DIRECTION_TX, mode, payload)
+		return nil
+	}
+}
+
+func (this *recorder) MeasureTemperature(offset float32) (float32, error) {
+	if this.radio == nil {
+		return 0, gopi.ErrOutOfOrder
+	} else {
+		return this.radio.MeasureTemperature(offset)
+	}
+}
+
+func (this *recorder) SetOutputPower(dbm int8) error {
+	if this.radio == nil {
+		return gopi.ErrOutOfOrder
+	} else {
+		return this.radio.SetOutputPower(dbm)
+	}
+}
+
+func (this *recorder) ResetRadio() error {
+	if this.radio == nil {
+		return gopi.ErrOutOfOrder
+	} else {
+		return this.radio.ResetRadio()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *recorder) record(direction Direction, mode sensors.MiHomeMode, payload []byte) {
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	if this.writer == nil {
+		return
+	}
+	entry := &Entry{time.Now(), direction, mode, payload}
+	if err := WriteEntry(this.writer, entry); err != nil {
+		this.log.Warn("<sensors.capture.Recorder>Record: %v", err)
+	}
+}
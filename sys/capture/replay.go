@@ -0,0 +1,195 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package capture
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Replay configuration. Received payloads in the capture are
+// delivered with the same intervals divided by Speed, so a speed
+// of 1 replays in real time and a speed of zero replays without
+// any delay
+type Replay struct {
+	Reader io.Reader // Capture file
+	Speed  float64   // Replay speed
+}
+
+// replay driver, which implements sensors.ENER314RT
+type replay struct {
+	log     gopi.Logger
+	speed   float64
+	entries []*Entry
+	next    int
+	start   time.Time
+
+	// Locker
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Replay) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.capture.Replay>Open{ speed=%v }", config.Speed)
+
+	if config.Reader == nil || config.Speed < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(replay)
+	this.log = log
+	this.speed = config.Speed
+
+	// Read all entries from the capture and close it
+	if entries, err := ReadEntries(config.Reader); err != nil {
+		return nil, err
+	} else {
+		this.entries = entries
+	}
+	if closer, ok := config.Reader.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *replay) Close() error {
+	this.log.Debug("<sensors.capture.Replay>Close{}")
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Free resources
+	this.entries = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *replay) String() string {
+	return fmt.Sprintf("<sensors.capture.Replay>{ speed=%v entries=%v next=%v }", this.speed, len(this.entries), this.next)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Receive payloads from the capture which were received in the same mode.
+// When the end of the capture is reached, blocks until the context is done
+func (this *replay) Receive(ctx context.Context, mode sensors.MiHomeMode, payload chan<- sensors.MiHomePayload) error {
+	this.log.Debug2("<sensors.capture.Replay>Receive{ mode=%v }", mode)
+
+	// Check incoming parameters
+	if ctx == nil || payload == nil || mode == sensors.MIHOME_MODE_NONE {
+		return gopi.ErrBadParameter
+	}
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Set the start time on first receive
+	if this.entries == nil {
+		return gopi.ErrOutOfOrder
+	} else if this.start.IsZero() {
+		this.start = time.Now()
+	}
+
+	// Deliver received payloads in order. Payloads received in another
+	// mode are skipped once they are due, so that when receiving
+	// alternates between modes they are delivered in the right mode
+	for this.next < len(this.entries) {
+		entry := this.entries[this.next]
+		if entry.Direction != DIRECTION_RX {
+			this.next++
+			continue
+		}
+		if delay := time.Until(this.deliverAt(entry)); delay > 0 {
+			select {
+			case <-time.After(delay):
+				break
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if entry.Mode != mode {
+			this.next++
+			continue
+		}
+		select {
+		case payload <- sensors.MiHomePayload{Data: entry.Payload}:
+			this.next++
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// End of capture
+	this.log.Debug("<sensors.capture.Replay>Receive: End of capture")
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Send does not transmit the payload
+func (this *replay) Send(payload []byte, repeat uint, mode sensors.MiHomeMode) error {
+	this.log.Debug2("<sensors.capture.Replay>Send{ mode=%v payload=%v repeat=%v }", mode, strings.ToUpper(hex.EncodeToString(payload)), repeat)
+
+	// Check parameters
+	if len(payload) == 0 || repeat == 0 || mode == sensors.MIHOME_MODE_NONE {
+		return gopi.ErrBadParameter
+	}
+
+	// Return success
+	return nil
+}
+
+func (this *replay) MeasureTemperature(offset float32) (float32, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *replay) SetOutputPower(dbm int8) error {
+	return nil
+}
+
+func (this *replay) ResetRadio() error {
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// deliverAt returns the time an entry should be delivered, relative
+// to the first entry in the capture
+func (this *replay) deliverAt(entry *Entry) time.Time {
+	if this.speed == 0 || len(this.entries) == 0 {
+		return this.start
+	}
+	offset := entry.Timestamp.Sub(this.entries[0].Timestamp)
+	return this.start.Add(time.Duration(float64(offset) / this.speed))
+}
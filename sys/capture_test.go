@@ -0,0 +1,141 @@
+package sys_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/capture"
+	"github.com/djthorpe/sensors/sys/ether"
+	"github.com/djthorpe/sensors/sys/mihome"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+func Test_Capture_000_entries(t *testing.T) {
+	text := `# Capture
+2019-01-05T10:00:00.5Z RX MONITOR 0D04AABB
+2019-01-05T10:00:01Z tx control 80000000888888
+`
+	if entries, err := capture.ReadEntries(strings.NewReader(text)); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 2 {
+		t.Error("Expected two entries, got", len(entries))
+	} else if entries[0].Direction != capture.DIRECTION_RX || entries[0].Mode != sensors.MIHOME_MODE_MONITOR {
+		t.Error("Unexpected entry", entries[0])
+	} else if entries[1].Direction != capture.DIRECTION_TX || entries[1].Mode != sensors.MIHOME_MODE_CONTROL {
+		t.Error("Unexpected entry", entries[1])
+	} else if entries[0].String() != "2019-01-05T10:00:00.5Z RX MONITOR 0D04AABB" {
+		t.Error("Unexpected entry", entries[0])
+	}
+
+	// Invalid lines return an error with the line number
+	for _, text := range []string{"2019-01-05T10:00:00Z RX MONITOR", "2019-01-05 RX MONITOR 00", "2019-01-05T10:00:00Z XX MONITOR 00", "2019-01-05T10:00:00Z RX NONE 00", "2019-01-05T10:00:00Z RX MONITOR 0"} {
+		if _, err := capture.ReadEntries(strings.NewReader("\n" + text)); err == nil {
+			t.Error("Expected error for", text)
+		} else if strings.HasPrefix(err.Error(), "Line 2:") == false {
+			t.Error("Unexpected error", err)
+		}
+	}
+}
+
+func Test_Capture_001_record(t *testing.T) {
+	_, radios := Ether(t, ether.Ether{}, 2)
+	log := Logger(t)
+	buf := new(bytes.Buffer)
+
+	// Record radio 0
+	recorder, err := gopi.Open(capture.Recorder{Radio: radios[0], Writer: buf}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+	receive := Receive(t, recorder.(sensors.ENER314RT), sensors.MIHOME_MODE_MONITOR)
+	time.Sleep(50 * time.Millisecond)
+
+	// Radio 1 sends one payload, received twice
+	if err := radios[1].Send([]byte{0x01, 0x02}, 1, sensors.MIHOME_MODE_MONITOR); err != nil {
+		t.Fatal(err)
+	} else if received := <-receive; len(received) != 2 {
+		t.Fatal("Expected two payloads, got", len(received))
+	} else if err := recorder.(sensors.ENER314RT).Send([]byte{0x03}, 1, sensors.MIHOME_MODE_CONTROL); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, err := capture.ReadEntries(buf); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 3 {
+		t.Error("Expected three entries, got", len(entries))
+	} else if entries[0].Direction != capture.DIRECTION_RX || Equals(entries[0].Payload, []byte{0x01, 0x02}) == false {
+		t.Error("Unexpected entry", entries[0])
+	} else if entries[2].Direction != capture.DIRECTION_TX || entries[2].Mode != sensors.MIHOME_MODE_CONTROL {
+		t.Error("Unexpected entry", entries[2])
+	}
+}
+
+func Test_Capture_002_replay(t *testing.T) {
+	text := `2019-01-05T10:00:00Z RX MONITOR 01
+2019-01-05T10:00:00.1Z TX MONITOR 02
+2019-01-05T10:00:00.1Z RX CONTROL 03
+2019-01-05T10:00:10Z RX MONITOR 04
+`
+	// Replay at 100x speed, so the second payload is received after 100ms
+	radio, err := gopi.Open(capture.Replay{Reader: strings.NewReader(text), Speed: 100}, Logger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radio.Close()
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	payloads := make(chan sensors.MiHomePayload)
+	errors := make(chan error)
+	go func() {
+		errors <- radio.(sensors.ENER314RT).Receive(ctx, sensors.MIHOME_MODE_MONITOR, payloads)
+	}()
+	for _, expected := range [][]byte{[]byte{0x01}, []byte{0x04}} {
+		select {
+		case payload := <-payloads:
+			if Equals(payload.Data, expected) == false {
+				t.Errorf("Expected %v, got %v", expected, payload.Data)
+			} else if payload.Signal != nil {
+				t.Error("Unexpected signal", payload.Signal)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timeout waiting for payload")
+		}
+	}
+	if since := time.Since(start); since < 100*time.Millisecond {
+		t.Error("Replay too fast", since)
+	}
+	cancel()
+	if err := <-errors; err != context.Canceled {
+		t.Error("Expected context.Canceled, got", err)
+	}
+}
+
+func Test_Capture_003_is_replay(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{}, false},
+		{[]string{"-mihome.replay", "capture.txt"}, true},
+		{[]string{"--mihome.replay=capture.txt", "-debug"}, true},
+		{[]string{"-mihome.record", "capture.txt"}, false},
+		{[]string{"-mihome.replayed"}, false},
+		{[]string{"--", "-mihome.replay", "capture.txt"}, false},
+	}
+	for _, test := range tests {
+		if replay := mihome.IsReplay(test.args); replay != test.expected {
+			t.Errorf("IsReplay(%v): expected %v, got %v", test.args, test.expected, replay)
+		}
+	}
+}
@@ -32,6 +32,7 @@ type ENER314RT struct {
 	PinReset gopi.GPIOPin  // Reset pin
 	PinLED1  gopi.GPIOPin  // LED1 (Green, Rx) pin
 	PinLED2  gopi.GPIOPin  // LED2 (Red, Tx) pin
+	AESKey   []byte        // Optional 16 byte AES key in monitor mode
 }
 
 // ener314rt driver
@@ -46,6 +47,13 @@ type ener314rt struct {
 	ledtx gopi.GPIOPin
 	mode  sensors.MiHomeMode
 
+	// Monitor profile, with the AES key
+	monitor sensors.RFMProfile
+
+	// Output power in dBm, set when power_set is true
+	power     int8
+	power_set bool
+
 	// Locker
 	sync.Mutex
 }
@@ -63,6 +71,16 @@ const (
 	LED_TX
 )
 
+const (
+	// The ENER314-RT header connects RESET, the LEDs and SPI to the radio
+	// but not DIO0 (see Figure 2 of doc/ENER314-RT.pdf), so the default
+	// DIO0 pin is none and payloads are received by polling
+	PIN_DIO0 = gopi.GPIO_PIN_NONE
+
+	// Size of an AES key
+	AESKEY_BYTES = 16
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // OPEN AND CLOSE
 
@@ -72,6 +90,9 @@ func (config ENER314RT) Open(log gopi.Logger) (gopi.Driver, error) {
 	if config.GPIO == nil || config.Radio == nil {
 		// Fail when either GPIO or Radio is nil
 		return nil, gopi.ErrBadParameter
+	} else if len(config.AESKey) != 0 && len(config.AESKey) != AESKEY_BYTES {
+		// Fail when the AES key is the wrong size
+		return nil, gopi.ErrBadParameter
 	}
 
 	this := new(ener314rt)
@@ -96,6 +117,11 @@ func (config ENER314RT) Open(log gopi.Logger) (gopi.Driver, error) {
 	// Set mode to undefined
 	this.mode = sensors.MIHOME_MODE_NONE
 
+	// Monitor mode uses the AES key when set, which needs to be the same
+	// key as the sensor nodes use
+	this.monitor = PROFILE_MONITOR
+	this.monitor.AESKey = hex.EncodeToString(config.AESKey)
+
 	// Return success
 	return this, nil
 }
@@ -150,6 +176,13 @@ func (this *ener314rt) ResetRadio() error {
 		return err
 	}
 
+	// Restore output power
+	if this.power_set {
+		if err := this.radio.SetOutputPower(this.power); err != nil {
+			return err
+		}
+	}
+
 	// Set undefined mode
 	this.mode = sensors.MIHOME_MODE_NONE
 
@@ -207,7 +240,7 @@ func (this *ener314rt) SetLED(led LED, state gopi.GPIOState) error {
 }
 
 // Receive payloads until context is cancelled or timeout
-func (this *ener314rt) Receive(ctx context.Context, mode sensors.MiHomeMode, payload chan<- []byte) error {
+func (this *ener314rt) Receive(ctx context.Context, mode sensors.MiHomeMode, payload chan<- sensors.MiHomePayload) error {
 	this.log.Debug2("<sensors.ener314rt>Receive{ mode=%v }", mode)
 
 	// Check incoming parameters
@@ -248,15 +281,15 @@ FOR_LOOP:
 			break FOR_LOOP
 		default:
 			this.log.Debug("<sensors.ener314rt>Receive: ReadPayload")
-			if data, _, err := this.radio.ReadPayload(ctx); err != nil {
+			if data, _, signal, err := this.radio.ReadPayload(ctx); err != nil {
 				return err
 			} else if data != nil {
 				// RX light on
 				this.SetLED(LED_RX, gopi.GPIO_HIGH)
 				defer this.SetLED(LED_RX, gopi.GPIO_LOW)
 
-				// Emit payload
-				payload <- data
+				// Emit payload with signal metadata
+				payload <- sensors.MiHomePayload{Data: data, Signal: &signal}
 
 				// Clear FIFO
 				if err := this.radio.ClearFIFO(); err != nil {
@@ -312,6 +345,25 @@ func (this *ener314rt) Send(payload []byte, repeat uint, mode sensors.MiHomeMode
 	return nil
 }
 
+// SetOutputPower sets the transmit power in dBm
+func (this *ener314rt) SetOutputPower(dbm int8) error {
+	this.log.Debug2("<sensors.ener314rt>SetOutputPower{ dbm=%v }", dbm)
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.radio.SetOutputPower(dbm); err != nil {
+		return err
+	} else {
+		this.power = dbm
+		this.power_set = true
+	}
+
+	// Return success
+	return nil
+}
+
 func (this *ener314rt) MeasureTemperature(tempoffset float32) (float32, error) {
 	this.log.Debug2("<sensors.ener314rt>MeasureTemperature{}")
 
@@ -369,52 +421,9 @@ func (this *ener314rt) SetMode(mode sensors.MiHomeMode) error {
 // PRIVATE METHODS
 
 func (this *ener314rt) setFSKMode() error {
-
 	if err := this.radio.SetMode(sensors.RFM_MODE_STDBY); err != nil {
 		return err
-	} else if err := this.radio.SetModulation(sensors.RFM_MODULATION_FSK); err != nil {
-		return err
-	} else if err := this.radio.SetSequencer(true); err != nil {
-		return err
-	} else if err := this.radio.SetBitrate(4800); err != nil {
-		return err
-	} else if err := this.radio.SetFreqCarrier(434300000); err != nil {
-		return err
-	} else if err := this.radio.SetFreqDeviation(30000); err != nil {
-		return err
-	} else if err := this.radio.SetAFCMode(sensors.RFM_AFCMODE_OFF); err != nil {
-		return err
-	} else if err := this.radio.SetAFCRoutine(sensors.RFM_AFCROUTINE_STANDARD); err != nil {
-		return err
-	} else if err := this.radio.SetLNA(sensors.RFM_LNA_IMPEDANCE_50, sensors.RFM_LNA_GAIN_AUTO); err != nil {
-		return err
-	} else if err := this.radio.SetRXFilter(sensors.RFM_RXBW_FREQUENCY_FSK_62P5, sensors.RFM_RXBW_CUTOFF_4); err != nil {
-		return err
-	} else if err := this.radio.SetDataMode(sensors.RFM_DATAMODE_PACKET); err != nil {
-		return err
-	} else if err := this.radio.SetPacketFormat(sensors.RFM_PACKET_FORMAT_VARIABLE); err != nil {
-		return err
-	} else if err := this.radio.SetPacketCoding(sensors.RFM_PACKET_CODING_MANCHESTER); err != nil {
-		return err
-	} else if err := this.radio.SetPacketFilter(sensors.RFM_PACKET_FILTER_NONE); err != nil {
-		return err
-	} else if err := this.radio.SetPacketCRC(sensors.RFM_PACKET_CRC_OFF); err != nil {
-		return err
-	} else if err := this.radio.SetPreambleSize(3); err != nil {
-		return err
-	} else if err := this.radio.SetPayloadSize(0x40); err != nil {
-		return err
-	} else if err := this.radio.SetSyncWord([]byte{0x2D, 0xD4}); err != nil {
-		return err
-	} else if err := this.radio.SetSyncTolerance(0); err != nil {
-		return err
-	} else if err := this.radio.SetNodeAddress(0x04); err != nil {
-		return err
-	} else if err := this.radio.SetBroadcastAddress(0x00); err != nil {
-		return err
-	} else if err := this.radio.SetAESKey(nil); err != nil {
-		return err
-	} else if err := this.radio.SetFIFOThreshold(1); err != nil {
+	} else if err := this.radio.ApplyProfile(this.monitor); err != nil {
 		return err
 	}
 
@@ -425,45 +434,7 @@ func (this *ener314rt) setFSKMode() error {
 func (this *ener314rt) setOOKMode() error {
 	if err := this.radio.SetMode(sensors.RFM_MODE_STDBY); err != nil {
 		return err
-	} else if err := this.radio.SetModulation(sensors.RFM_MODULATION_OOK); err != nil {
-		return err
-	} else if err := this.radio.SetSequencer(true); err != nil {
-		return err
-	} else if err := this.radio.SetBitrate(4800); err != nil {
-		return err
-	} else if err := this.radio.SetFreqCarrier(433920000); err != nil {
-		return err
-	} else if err := this.radio.SetFreqDeviation(0); err != nil {
-		return err
-	} else if err := this.radio.SetAFCMode(sensors.RFM_AFCMODE_OFF); err != nil {
-		return err
-	} else if err := this.radio.SetDataMode(sensors.RFM_DATAMODE_PACKET); err != nil {
-		return err
-	} else if err := this.radio.SetPacketFormat(sensors.RFM_PACKET_FORMAT_VARIABLE); err != nil {
-		return err
-	} else if err := this.radio.SetPacketCoding(sensors.RFM_PACKET_CODING_NONE); err != nil {
-		return err
-	} else if err := this.radio.SetPacketFilter(sensors.RFM_PACKET_FILTER_NONE); err != nil {
-		return err
-	} else if err := this.radio.SetPacketCRC(sensors.RFM_PACKET_CRC_OFF); err != nil {
-		return err
-	} else if err := this.radio.SetPreambleSize(0); err != nil {
-		return err
-	} else if err := this.radio.SetPayloadSize(0); err != nil {
-		return err
-	} else if err := this.radio.SetSyncWord(nil); err != nil {
-		return err
-	} else if err := this.radio.SetSyncTolerance(0); err != nil {
-		return err
-	} else if err := this.radio.SetNodeAddress(0x00); err != nil {
-		return err
-	} else if err := this.radio.SetBroadcastAddress(0x00); err != nil {
-		return err
-	} else if err := this.radio.SetAESKey(nil); err != nil {
-		return err
-	} else if err := this.radio.SetFIFOThreshold(1); err != nil {
-		return err
-	} else if err := this.radio.SetRXFilter(sensors.RFM_RXBW_FREQUENCY_OOK_125P0, sensors.RFM_RXBW_CUTOFF_4); err != nil {
+	} else if err := this.radio.ApplyProfile(PROFILE_CONTROL); err != nil {
 		return err
 	}
 
@@ -10,6 +10,7 @@
 package ener314rt
 
 import (
+	"encoding/hex"
 	"fmt"
 	"os"
 
@@ -32,10 +33,20 @@ func init() {
 			config.AppFlags.FlagUint("gpio.reset", 25, "Reset Pin (Logical)")
 			config.AppFlags.FlagUint("gpio.led1", 27, "Green LED Pin (Logical)")
 			config.AppFlags.FlagUint("gpio.led2", 22, "Red LED Pin (Logical)")
+			// AES key in monitor mode, for receiving from sensor nodes
+			config.AppFlags.FlagString("ener314rt.aes_key", "", "AES key in monitor mode (32 hex digits)")
 			// Default spi.slave to 1
 			if err := config.AppFlags.SetUint("spi.slave", 1); err != nil {
 				fmt.Fprintln(os.Stderr, err)
 			}
+			// Default rfm69.dio0 to the board DIO0 pin, or zero to poll
+			dio0 := uint(0)
+			if PIN_DIO0 != gopi.GPIO_PIN_NONE {
+				dio0 = uint(PIN_DIO0)
+			}
+			if err := config.AppFlags.SetUint("rfm69.dio0", dio0); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
 		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
 			if gpio, ok := app.ModuleInstance("gpio").(gopi.GPIO); !ok {
@@ -59,6 +70,13 @@ func init() {
 				if led2, _ := app.AppFlags.GetUint("gpio.led2"); led2 > 0 && led2 <= 0xFF {
 					config.PinLED2 = gopi.GPIOPin(led2)
 				}
+				if aes_key, _ := app.AppFlags.GetString("ener314rt.aes_key"); aes_key != "" {
+					if key, err := hex.DecodeString(aes_key); err != nil || len(key) != AESKEY_BYTES {
+						return nil, fmt.Errorf("Invalid -ener314rt.aes_key flag")
+					} else {
+						config.AESKey = key
+					}
+				}
 				return gopi.Open(config, app.Logger)
 			}
 		},
@@ -0,0 +1,90 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ener314rt
+
+import (
+	// Frameworks
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PROFILES
+
+var (
+	// PROFILE_MONITOR is the radio profile used to receive from and
+	// send to monitor devices with FSK
+	PROFILE_MONITOR = sensors.RFMProfile{
+		Name:                  "ener314rt-monitor",
+		Modulation:            sensors.RFM_MODULATION_FSK,
+		DataMode:              sensors.RFM_DATAMODE_PACKET,
+		Sequencer:             true,
+		Bitrate:               4800,
+		FreqCarrier:           434300000,
+		FreqDeviation:         30000,
+		AFCMode:               sensors.RFM_AFCMODE_OFF,
+		AFCRoutine:            sensors.RFM_AFCROUTINE_STANDARD,
+		LNAImpedance:          sensors.RFM_LNA_IMPEDANCE_50,
+		LNAGain:               sensors.RFM_LNA_GAIN_AUTO,
+		RXFilterFrequency:     sensors.RFM_RXBW_FREQUENCY_FSK_62P5,
+		RXFilterCutoff:        sensors.RFM_RXBW_CUTOFF_4,
+		PacketFormat:          sensors.RFM_PACKET_FORMAT_VARIABLE,
+		PacketCoding:          sensors.RFM_PACKET_CODING_MANCHESTER,
+		PacketFilter:          sensors.RFM_PACKET_FILTER_NONE,
+		PacketCRC:             sensors.RFM_PACKET_CRC_OFF,
+		PreambleSize:          3,
+		PayloadSize:           0x40,
+		NodeAddress:           0x04,
+		BroadcastAddress:      0x00,
+		SyncWord:              "2DD4",
+		SyncTolerance:         0,
+		AESKey:                "",
+		FIFOThreshold:         1,
+		FIFOFillCondition:     false,
+		TXStart:               sensors.RFM_TXSTART_FIFONOTEMPTY,
+		OOKThresholdType:      sensors.RFM_OOK_THRESHOLD_PEAK,
+		OOKThresholdStep:      sensors.RFM_OOK_THRESHOLD_STEP_0P5,
+		OOKThresholdDecrement: sensors.RFM_OOK_THRESHOLD_DEC_0P125,
+	}
+
+	// PROFILE_CONTROL is the radio profile used to send to
+	// control devices with OOK
+	PROFILE_CONTROL = sensors.RFMProfile{
+		Name:                  "ener314rt-control",
+		Modulation:            sensors.RFM_MODULATION_OOK,
+		DataMode:              sensors.RFM_DATAMODE_PACKET,
+		Sequencer:             true,
+		Bitrate:               4800,
+		FreqCarrier:           433920000,
+		FreqDeviation:         0,
+		AFCMode:               sensors.RFM_AFCMODE_OFF,
+		AFCRoutine:            sensors.RFM_AFCROUTINE_STANDARD,
+		LNAImpedance:          sensors.RFM_LNA_IMPEDANCE_50,
+		LNAGain:               sensors.RFM_LNA_GAIN_AUTO,
+		RXFilterFrequency:     sensors.RFM_RXBW_FREQUENCY_OOK_125P0,
+		RXFilterCutoff:        sensors.RFM_RXBW_CUTOFF_4,
+		PacketFormat:          sensors.RFM_PACKET_FORMAT_VARIABLE,
+		PacketCoding:          sensors.RFM_PACKET_CODING_NONE,
+		PacketFilter:          sensors.RFM_PACKET_FILTER_NONE,
+		PacketCRC:             sensors.RFM_PACKET_CRC_OFF,
+		PreambleSize:          0,
+		PayloadSize:           0,
+		NodeAddress:           0x00,
+		BroadcastAddress:      0x00,
+		SyncWord:              "",
+		SyncTolerance:         0,
+		AESKey:                "",
+		FIFOThreshold:         1,
+		FIFOFillCondition:     false,
+		TXStart:               sensors.RFM_TXSTART_FIFONOTEMPTY,
+		OOKThresholdType:      sensors.RFM_OOK_THRESHOLD_PEAK,
+		OOKThresholdStep:      sensors.RFM_OOK_THRESHOLD_STEP_0P5,
+		OOKThresholdDecrement: sensors.RFM_OOK_THRESHOLD_DEC_0P125,
+	}
+)
@@ -0,0 +1,203 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ether
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Configuration
+type Ether struct {
+	Loss     float64       // Probability a payload is not delivered to a radio (0 to 1)
+	BitError float64       // Probability each bit is flipped during delivery (0 to 1)
+	Delay    time.Duration // Time between sending and delivery
+	Seed     int64         // Random number seed, or zero to seed from the time
+}
+
+// Medium is a simulated shared radio channel. Payloads sent
+// in one MiHomeMode are delivered to all other radios which are
+// receiving in the same mode
+type Medium interface {
+	gopi.Driver
+
+	// Transmit a payload from a radio
+	Transmit(sender sensors.ENER314RT, mode sensors.MiHomeMode, payload []byte)
+}
+
+// ether driver
+type ether struct {
+	log       gopi.Logger
+	loss      float64
+	bit_error float64
+	delay     time.Duration
+	rand      *rand.Rand
+	listeners map[*listener]bool
+
+	sync.Mutex
+}
+
+// listener is a radio which is receiving in a mode
+type listener struct {
+	radio sensors.ENER314RT
+	mode  sensors.MiHomeMode
+	queue chan *delivery
+}
+
+// delivery is a payload which is received at a time
+type delivery struct {
+	ts   time.Time
+	data []byte
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Number of payloads which can be queued for a receiving radio
+	// before further payloads are lost
+	QUEUE_SIZE = 100
+
+	// Range of transmit power in dBm accepted by a radio, which is
+	// the range of the RFM69W and RFM69HW
+	POWER_MIN = -18
+	POWER_MAX = 20
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Ether) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.ether>Open{ loss=%v bit_error=%v delay=%v }", config.Loss, config.BitError, config.Delay)
+
+	// Check parameters
+	if config.Loss < 0 || config.Loss > 1 {
+		return nil, gopi.ErrBadParameter
+	}
+	if config.BitError < 0 || config.BitError > 1 {
+		return nil, gopi.ErrBadParameter
+	}
+	if config.Delay < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	this := new(ether)
+	this.log = log
+	this.loss = config.Loss
+	this.bit_error = config.BitError
+	this.delay = config.Delay
+	this.rand = rand.New(rand.NewSource(config.Seed))
+	this.listeners = make(map[*listener]bool)
+
+	// Return success
+	return this, nil
+}
+
+func (this *ether) Close() error {
+	this.log.Debug("<sensors.ether>Close{}")
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Free resources
+	this.listeners = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *ether) String() string {
+	return fmt.Sprintf("<sensors.ether>{ loss=%v bit_error=%v delay=%v }", this.loss, this.bit_error, this.delay)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TRANSMIT
+
+func (this *ether) Transmit(sender sensors.ENER314RT, mode sensors.MiHomeMode, payload []byte) {
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	ts := time.Now().Add(this.delay)
+	for l := range this.listeners {
+		if l.radio == sender || l.mode != mode {
+			continue
+		} else if this.loss > 0 && this.rand.Float64() < this.loss {
+			this.log.Debug2("<sensors.ether>Transmit: Lost payload for %v", l.radio)
+			continue
+		}
+		select {
+		case l.queue <- &delivery{ts, this.corrupt(payload)}:
+			break
+		default:
+			this.log.Warn("<sensors.ether>Transmit: Queue full for %v", l.radio)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Add a listener for a radio in a mode
+func (this *ether) listen(radio sensors.ENER314RT, mode sensors.MiHomeMode) *listener {
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	if this.listeners == nil {
+		return nil
+	}
+
+	l := &listener{radio, mode, make(chan *delivery, QUEUE_SIZE)}
+	this.listeners[l] = true
+	return l
+}
+
+// Remove a listener
+func (this *ether) unlisten(l *listener) {
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	if this.listeners != nil {
+		delete(this.listeners, l)
+	}
+}
+
+// Return a copy of a payload with bits flipped at random
+func (this *ether) corrupt(payload []byte) []byte {
+	data := make([]byte, len(payload))
+	copy(data, payload)
+	if this.bit_error > 0 {
+		for i := range data {
+			for bit := uint(0); bit < 8; bit++ {
+				if this.rand.Float64() < this.bit_error {
+					data[i] ^= 1 << bit
+				}
+			}
+		}
+	}
+	return data
+}
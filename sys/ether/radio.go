@@ -0,0 +1,201 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ether
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Configuration
+type Radio struct {
+	Ether       Medium  // Shared medium
+	Temperature float32 // Device temperature in celcius
+}
+
+// radio driver, which implements sensors.ENER314RT
+type radio struct {
+	log         gopi.Logger
+	ether       *ether
+	temperature float32
+	power       int8
+	mode        sensors.MiHomeMode
+
+	// Locker
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Radio) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.ether.Radio>Open{ ether=%v temperature=%v }", config.Ether, config.Temperature)
+
+	this := new(radio)
+	this.log = log
+	this.temperature = config.Temperature
+	this.mode = sensors.MIHOME_MODE_NONE
+
+	if ether, ok := config.Ether.(*ether); ok == false || ether == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.ether = ether
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *radio) Close() error {
+	this.log.Debug("<sensors.ether.Radio>Close{}")
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Free resources
+	this.ether = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *radio) String() string {
+	return fmt.Sprintf("<sensors.ether.Radio>{ mode=%v temperature=%v }", this.mode, this.temperature)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Receive payloads until context is cancelled or timeout
+func (this *radio) Receive(ctx context.Context, mode sensors.MiHomeMode, payload chan<- sensors.MiHomePayload) error {
+	this.log.Debug2("<sensors.ether.Radio>Receive{ mode=%v }", mode)
+
+	// Check incoming parameters
+	if ctx == nil || payload == nil || mode == sensors.MIHOME_MODE_NONE {
+		return gopi.ErrBadParameter
+	}
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Listen on the medium
+	if this.ether == nil {
+		return gopi.ErrOutOfOrder
+	} else {
+		this.mode = mode
+	}
+	listener := this.ether.listen(this, mode)
+	if listener == nil {
+		return gopi.ErrOutOfOrder
+	}
+	defer this.ether.unlisten(listener)
+
+	// Deliver payloads until context is done
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case delivery := <-listener.queue:
+			if delay := time.Until(delivery.ts); delay > 0 {
+				select {
+				case <-time.After(delay):
+					break
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			select {
+			case payload <- sensors.MiHomePayload{Data: delivery.data}:
+				break
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Send a raw payload, which is delivered repeat+1 times
+func (this *radio) Send(payload []byte, repeat uint, mode sensors.MiHomeMode) error {
+	this.log.Debug2("<sensors.ether.Radio>Send{ mode=%v payload=%v repeat=%v }", mode, strings.ToUpper(hex.EncodeToString(payload)), repeat)
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Check parameters
+	if len(payload) == 0 || repeat == 0 || mode == sensors.MIHOME_MODE_NONE {
+		return gopi.ErrBadParameter
+	} else if this.ether == nil {
+		return gopi.ErrOutOfOrder
+	} else {
+		this.mode = mode
+	}
+
+	// Transmit payload
+	for i := uint(0); i <= repeat; i++ {
+		this.ether.Transmit(this, mode, payload)
+	}
+
+	// Return success
+	return nil
+}
+
+func (this *radio) MeasureTemperature(offset float32) (float32, error) {
+	this.log.Debug2("<sensors.ether.Radio>MeasureTemperature{ offset=%v }", offset)
+	return this.temperature + offset, nil
+}
+
+// SetOutputPower records the transmit power, which does not
+// affect the medium
+func (this *radio) SetOutputPower(dbm int8) error {
+	this.log.Debug2("<sensors.ether.Radio>SetOutputPower{ dbm=%v }", dbm)
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Check parameters
+	if dbm < POWER_MIN || dbm > POWER_MAX {
+		return gopi.ErrBadParameter
+	} else {
+		this.power = dbm
+	}
+
+	// Return success
+	return nil
+}
+
+func (this *radio) ResetRadio() error {
+	this.log.Debug2("<sensors.ether.Radio>ResetRadio{}")
+
+	// Lock until finished
+	this.Lock()
+	defer this.Unlock()
+
+	// Set undefined mode
+	this.mode = sensors.MIHOME_MODE_NONE
+
+	return nil
+}
@@ -0,0 +1,807 @@
+package sys_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/ether"
+	"github.com/djthorpe/sensors/sys/mihome"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+	_ "github.com/djthorpe/sensors/protocol/ook"
+	_ "github.com/djthorpe/sensors/protocol/openthings"
+)
+
+func Test_Ether_000_open(t *testing.T) {
+	log := Logger(t)
+	if _, err := gopi.Open(ether.Ether{Loss: 2}, log); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := gopi.Open(ether.Radio{}, log); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if medium, radios := Ether(t, ether.Ether{}, 2); medium == nil || len(radios) != 2 {
+		t.Error("Missing ether")
+	}
+}
+
+func Test_Ether_001_send(t *testing.T) {
+	_, radios := Ether(t, ether.Ether{Seed: 1}, 3)
+	payload := []byte{0x01, 0x02, 0x03}
+
+	// Radio 1 receives in monitor mode, radio 2 in control mode
+	monitor := Receive(t, radios[1], sensors.MIHOME_MODE_MONITOR)
+	control := Receive(t, radios[2], sensors.MIHOME_MODE_CONTROL)
+	time.Sleep(50 * time.Millisecond)
+
+	// Send with two repeats, so three copies should be received
+	if err := radios[0].Send(payload, 2, sensors.MIHOME_MODE_MONITOR); err != nil {
+		t.Fatal(err)
+	}
+	if received := <-monitor; len(received) != 3 {
+		t.Error("Expected three payloads, got", len(received))
+	} else {
+		for _, data := range received {
+			if Equals(data, payload) == false {
+				t.Errorf("Expected %v, got %v", payload, data)
+			}
+		}
+	}
+	if received := <-control; len(received) != 0 {
+		t.Error("Expected no payloads in control mode, got", len(received))
+	}
+}
+
+func Test_Ether_002_loss(t *testing.T) {
+	_, radios := Ether(t, ether.Ether{Loss: 1}, 2)
+	receive := Receive(t, radios[1], sensors.MIHOME_MODE_MONITOR)
+	time.Sleep(50 * time.Millisecond)
+	if err := radios[0].Send([]byte{0x01, 0x02}, 5, sensors.MIHOME_MODE_MONITOR); err != nil {
+		t.Fatal(err)
+	} else if received := <-receive; len(received) != 0 {
+		t.Error("Expected no payloads, got", len(received))
+	}
+}
+
+func Test_Ether_003_corruption(t *testing.T) {
+	_, radios := Ether(t, ether.Ether{BitError: 1, Delay: 50 * time.Millisecond}, 2)
+	receive := Receive(t, radios[1], sensors.MIHOME_MODE_CONTROL)
+	time.Sleep(50 * time.Millisecond)
+	if err := radios[0].Send([]byte{0x0F, 0xF0}, 1, sensors.MIHOME_MODE_CONTROL); err != nil {
+		t.Fatal(err)
+	} else if received := <-receive; len(received) != 2 {
+		t.Error("Expected two payloads, got", len(received))
+	} else if Equals(received[0], []byte{0xF0, 0x0F}) == false {
+		t.Error("Expected all bits flipped, got", received[0])
+	}
+}
+
+func Test_Ether_004_mihome(t *testing.T) {
+	_, radios := Ether(t, ether.Ether{}, 2)
+	log := Logger(t)
+
+	// Create two MiHome instances which share the ether
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/openthings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto := app.ModuleInstance("sensors/protocol/openthings").(sensors.Proto)
+	instances := make([]sensors.MiHome, len(radios))
+	for i, radio := range radios {
+		if driver, err := gopi.Open(mihome.MiHome{Radio: radio, Mode: sensors.MIHOME_MODE_MONITOR, Repeat: 1}, log); err != nil {
+			t.Fatal(err)
+		} else if err := driver.(sensors.MiHome).AddProto(proto); err != nil {
+			t.Fatal(err)
+		} else {
+			instances[i] = driver.(sensors.MiHome)
+			defer driver.Close()
+		}
+	}
+
+	// Send an identify request from one to the other, draining
+	// events so that repeated payloads do not block the emitter
+	events := make(chan gopi.Event, 10)
+	go func(source <-chan gopi.Event) {
+		for evt := range source {
+			select {
+			case events <- evt:
+				break
+			default:
+				break
+			}
+		}
+	}(instances[1].Subscribe())
+	time.Sleep(50 * time.Millisecond)
+	if err := instances[0].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO013, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case evt := <-events:
+		if message, ok := evt.(sensors.OTMessage); ok == false {
+			t.Error("Expected OTMessage, got", evt)
+		} else if message.Sensor() != 0x1234 {
+			t.Error("Unexpected sensor", message.Sensor())
+		} else if records := message.Records(); len(records) != 1 || records[0].Name() != sensors.OT_PARAM_IDENTIFY {
+			t.Error("Unexpected records", records)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timeout waiting for message")
+	}
+}
+
+func Test_Ether_005_time_sync(t *testing.T) {
+	instances, events := MiHomeEther(t, mihome.MiHome{Mode: sensors.MIHOME_MODE_MONITOR})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// Send the time from one to the other
+	now := time.Now()
+	if err := instances[0].RequestTimeSync(sensors.MIHOME_PRODUCT_MIHO013, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case evt := <-events:
+		if message, ok := evt.(sensors.OTMessage); ok == false {
+			t.Error("Expected OTMessage, got", evt)
+		} else if records := message.Records(); len(records) != 1 || records[0].Name() != sensors.OT_PARAM_TIME_AND_DATE {
+			t.Error("Unexpected records", records)
+		} else if ts, err := records[0].TimeValue(); err != nil {
+			t.Error(err)
+		} else if ts.Sub(now.Truncate(time.Second)) < 0 || ts.Sub(now) > time.Second {
+			t.Error("Unexpected time", ts)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timeout waiting for message")
+	}
+}
+
+func Test_Ether_006_both(t *testing.T) {
+	instances, events := MiHomeEther(t, mihome.MiHome{
+		Mode:         sensors.MIHOME_MODE_BOTH,
+		MonitorDwell: 100 * time.Millisecond,
+		ControlDwell: 100 * time.Millisecond,
+	})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// Send in each mode until the message is received, since it's lost
+	// when the receiver is in the other mode
+	send := map[string]func() error{
+		"openthings": func() error {
+			return instances[0].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO013, 0x1234)
+		},
+		"ook": func() error {
+			return instances[0].RequestSwitchOn(sensors.MIHOME_PRODUCT_CONTROL_ONE, 0x1234)
+		},
+	}
+	for name, fn := range send {
+		timeout := time.After(2 * time.Second)
+	FOR_LOOP:
+		for {
+			if err := fn(); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case evt := <-events:
+				if evt.Name() == name {
+					break FOR_LOOP
+				}
+			case <-timeout:
+				t.Fatal("Timeout waiting for", name)
+			case <-time.After(20 * time.Millisecond):
+				break
+			}
+		}
+	}
+
+	// Time is spent receiving in both modes
+	stats := instances[1].Statistics()
+	for _, mode := range []sensors.MiHomeMode{sensors.MIHOME_MODE_MONITOR, sensors.MIHOME_MODE_CONTROL} {
+		if stats[mode].Duration == 0 || stats[mode].Decoded == 0 {
+			t.Error("Unexpected statistics for", mode, stats[mode])
+		} else if stats[mode].Payloads < stats[mode].Decoded {
+			t.Error("Unexpected statistics for", mode, stats[mode])
+		}
+	}
+	if _, exists := stats[sensors.MIHOME_MODE_BOTH]; exists {
+		t.Error("Unexpected statistics for", sensors.MIHOME_MODE_BOTH)
+	}
+}
+
+func Test_Ether_007_queue(t *testing.T) {
+	instances, _ := MiHomeEther(t, mihome.MiHome{Mode: sensors.MIHOME_MODE_MONITOR})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// Collect transmit events from the sender
+	events := make(chan sensors.MiHomeTXEvent, 10)
+	go func(source <-chan gopi.Event) {
+		for evt := range source {
+			if evt_, ok := evt.(sensors.MiHomeTXEvent); ok {
+				events <- evt_
+			}
+		}
+	}(instances[0].Subscribe())
+
+	// The first message may be transmitted straight away, but the others
+	// are queued and transmitted by priority, with the duplicate request
+	// coalesced with the queued one
+	if err := instances[0].RequestTimeSync(sensors.MIHOME_PRODUCT_MIHO013, 0x01); err != nil {
+		t.Fatal(err)
+	} else if err := instances[0].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO013, 0x02); err != nil {
+		t.Fatal(err)
+	} else if err := instances[0].RequestSwitchOn(sensors.MIHOME_PRODUCT_MIHO005, 0x03); err != nil {
+		t.Fatal(err)
+	} else if err := instances[0].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO013, 0x02); err != nil {
+		t.Fatal(err)
+	}
+	sensors_ := make([]uint32, 0)
+	for len(sensors_) < 3 {
+		select {
+		case evt := <-events:
+			if evt.Err() != nil {
+				t.Error(evt.Err())
+			} else if message, ok := evt.Message().(sensors.OTMessage); ok == false {
+				t.Error("Expected OTMessage, got", evt.Message())
+			} else {
+				sensors_ = append(sensors_, message.Sensor())
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for transmit events")
+		}
+	}
+	if sensors_[0] == 0x01 && (sensors_[1] != 0x03 || sensors_[2] != 0x02) {
+		t.Error("Unexpected transmit order", sensors_)
+	} else if sensors_[0] != 0x01 && (sensors_[0] != 0x03 || sensors_[1] != 0x02 || sensors_[2] != 0x01) {
+		t.Error("Unexpected transmit order", sensors_)
+	}
+
+	// A message past its deadline is discarded
+	var proto sensors.OTProto
+	for _, proto_ := range instances[0].Protos() {
+		if proto_, ok := proto_.(sensors.OTProto); ok {
+			proto = proto_
+		}
+	}
+	if proto == nil {
+		t.Fatal("Missing OTProto")
+	} else if message, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, uint8(sensors.MIHOME_PRODUCT_MIHO013), 0x04); err != nil {
+		t.Fatal(err)
+	} else if err := instances[0].SendMessage(message, sensors.MiHomeTXOptions{Deadline: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case evt := <-events:
+		if evt.Err() != gopi.ErrDeadlineExceeded {
+			t.Error("Expected ErrDeadlineExceeded, got", evt.Err())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for transmit event")
+	}
+	if err := instances[0].SendMessage(nil, sensors.MiHomeTXOptions{}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func Test_Ether_008_devices(t *testing.T) {
+	instances, events := MiHomeEther(t, mihome.MiHome{Mode: sensors.MIHOME_MODE_MONITOR})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// Return the next change event, or nil if there is none
+	change := func() sensors.MiHomeChangeEvent {
+		timeout := time.After(500 * time.Millisecond)
+		for {
+			select {
+			case evt := <-events:
+				if evt_, ok := evt.(sensors.MiHomeChangeEvent); ok {
+					return evt_
+				}
+			case <-timeout:
+				return nil
+			}
+		}
+	}
+
+	// The first value is a change, the same value is not and a
+	// different value is
+	if instances[1].Device(sensors.MIHOME_PRODUCT_MIHO005, 0x10) != nil {
+		t.Error("Unexpected device")
+	}
+	if err := instances[0].RequestSwitchOn(sensors.MIHOME_PRODUCT_MIHO005, 0x10); err != nil {
+		t.Fatal(err)
+	} else if evt := change(); evt == nil {
+		t.Fatal("Expected change event")
+	} else if evt.Product() != sensors.MIHOME_PRODUCT_MIHO005 || evt.Sensor() != 0x10 || evt.Parameter() != sensors.OT_PARAM_SWITCH_STATE {
+		t.Error("Unexpected change event", evt)
+	} else if value, err := evt.Value().BoolValue(); err != nil || value != true || evt.Previous() != nil {
+		t.Error("Unexpected change event", evt)
+	}
+	if err := instances[0].RequestSwitchOn(sensors.MIHOME_PRODUCT_MIHO005, 0x10); err != nil {
+		t.Fatal(err)
+	} else if evt := change(); evt != nil {
+		t.Error("Unexpected change event", evt)
+	}
+	if err := instances[0].RequestSwitchOff(sensors.MIHOME_PRODUCT_MIHO005, 0x10); err != nil {
+		t.Fatal(err)
+	} else if evt := change(); evt == nil {
+		t.Fatal("Expected change event")
+	} else if value, err := evt.Value().BoolValue(); err != nil || value != false || evt.Previous() == nil {
+		t.Error("Unexpected change event", evt)
+	}
+
+	// Requests without values are not recorded
+	if err := instances[0].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO005, 0x10); err != nil {
+		t.Fatal(err)
+	} else if evt := change(); evt != nil {
+		t.Error("Unexpected change event", evt)
+	}
+
+	// The registry has the last value
+	if devices := instances[1].Devices(); len(devices) != 1 {
+		t.Error("Expected one device, got", devices)
+	} else if device := instances[1].Device(sensors.MIHOME_PRODUCT_MIHO005, 0x10); device == nil {
+		t.Error("Missing device")
+	} else if device.LastSeen.IsZero() || len(device.Values) != 1 {
+		t.Error("Unexpected device", device)
+	} else if value, exists := device.Values[sensors.OT_PARAM_SWITCH_STATE]; exists == false {
+		t.Error("Missing value", device)
+	} else if state, err := value.Record.BoolValue(); err != nil || state != false {
+		t.Error("Unexpected value", value)
+	} else if value.Changed.After(value.Updated) {
+		t.Error("Unexpected timestamps", value)
+	}
+}
+
+func Test_Ether_009_confirm(t *testing.T) {
+	instances, _ := MiHomeEther(t, mihome.MiHome{
+		Mode:           sensors.MIHOME_MODE_MONITOR,
+		ConfirmRetries: 2,
+		ConfirmTimeout: 300 * time.Millisecond,
+	})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// The first instance is a device which reports the switch state
+	// returned by the report function, or doesn't report when it
+	// returns false. Each case uses a different sensor, so that late
+	// reports from one case are not received by the next
+	var lock sync.Mutex
+	var report func(bool) (bool, bool)
+	go func(source <-chan gopi.Event) {
+		for evt := range source {
+			if message, ok := evt.(sensors.OTMessage); ok == false || message.Product() != uint8(sensors.MIHOME_PRODUCT_MIHO005) {
+				continue
+			} else if records := message.Records(); len(records) != 1 || records[0].Name() != sensors.OT_PARAM_SWITCH_STATE {
+				continue
+			} else if state, err := records[0].BoolValue(); err != nil {
+				t.Error(err)
+			} else {
+				lock.Lock()
+				state, ok := report(state)
+				lock.Unlock()
+				if ok == false {
+					continue
+				}
+				proto := message.Source().(sensors.OTProto)
+				if reply, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, uint8(sensors.MIHOME_PRODUCT_MIHO005), message.Sensor()); err != nil {
+					t.Error(err)
+				} else if record, err := proto.NewBool(sensors.OT_PARAM_SWITCH_STATE, state, true); err != nil {
+					t.Error(err)
+				} else if err := instances[0].SendMessage(reply.Append(record), sensors.MiHomeTXOptions{}); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}(instances[0].Subscribe())
+	set_report := func(fn func(bool) (bool, bool)) {
+		lock.Lock()
+		defer lock.Unlock()
+		report = fn
+	}
+
+	// The first request is lost, so the state is confirmed on retry
+	requests := 0
+	set_report(func(state bool) (bool, bool) {
+		requests++
+		return state, requests > 1
+	})
+	if err := instances[1].RequestSwitchConfirmed(context.Background(), sensors.MIHOME_PRODUCT_MIHO005, 0x20, true); err != nil {
+		t.Error(err)
+	}
+	lock.Lock()
+	if requests < 2 {
+		t.Error("Expected retry, got", requests, "requests")
+	}
+	lock.Unlock()
+
+	// The device reports a different state
+	set_report(func(state bool) (bool, bool) {
+		return !state, true
+	})
+	if err := instances[1].RequestSwitchConfirmed(context.Background(), sensors.MIHOME_PRODUCT_MIHO005, 0x21, false); err != sensors.ErrUnexpectedResponse {
+		t.Error("Expected ErrUnexpectedResponse, got", err)
+	}
+
+	// The device doesn't report, so the retries are exhausted or the
+	// context ends first
+	set_report(func(state bool) (bool, bool) {
+		return state, false
+	})
+	if err := instances[1].RequestSwitchConfirmed(context.Background(), sensors.MIHOME_PRODUCT_MIHO005, 0x22, false); err != sensors.ErrDeviceTimeout {
+		t.Error("Expected ErrDeviceTimeout, got", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := instances[1].RequestSwitchConfirmed(ctx, sensors.MIHOME_PRODUCT_MIHO005, 0x23, false); err != context.DeadlineExceeded {
+		t.Error("Expected DeadlineExceeded, got", err)
+	}
+
+	// Only products which report switch state can be confirmed
+	if err := instances[1].RequestSwitchConfirmed(context.Background(), sensors.MIHOME_PRODUCT_CONTROL_ONE, 0x20, true); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func Test_Ether_010_supersede(t *testing.T) {
+	instances, _ := MiHomeEther(t, mihome.MiHome{Mode: sensors.MIHOME_MODE_MONITOR})
+	defer instances[1].Close()
+
+	// Collect transmit events from the sender until it is closed
+	events := make(chan sensors.MiHomeTXEvent, 20)
+	go func(source <-chan gopi.Event) {
+		defer close(events)
+		for evt := range source {
+			if evt_, ok := evt.(sensors.MiHomeTXEvent); ok {
+				events <- evt_
+			}
+		}
+	}(instances[0].Subscribe())
+
+	// Switching on, off and on again leaves the switch on, as a queued
+	// request is superseded by a later one for the same device
+	for _, state := range []bool{true, false, true} {
+		if state {
+			if err := instances[0].RequestSwitchOn(sensors.MIHOME_PRODUCT_MIHO005, 0x30); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := instances[0].RequestSwitchOff(sensors.MIHOME_PRODUCT_MIHO005, 0x30); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var last *bool
+	for i := 0; i < 3; i++ {
+		select {
+		case evt := <-events:
+			if evt.Err() == sensors.ErrMessageSuperseded {
+				continue
+			} else if evt.Err() != nil {
+				t.Error(evt.Err())
+			} else if state, exists := SwitchState(evt.Message()); exists == false {
+				t.Error("Expected switch state, got", evt.Message())
+			} else {
+				last = &state
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for transmit events")
+		}
+	}
+	if last == nil || *last != true {
+		t.Error("Expected switch on to be transmitted last")
+	}
+
+	// Queued messages are discarded when closed
+	for sensor := uint32(0x31); sensor <= 0x33; sensor++ {
+		if err := instances[0].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO013, sensor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := instances[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for evt := range events {
+		if evt.Err() != nil && evt.Err() != gopi.ErrOutOfOrder {
+			t.Error("Expected ErrOutOfOrder, got", evt.Err())
+		}
+		count++
+	}
+	if count != 3 {
+		t.Error("Expected 3 transmit events, got", count)
+	}
+}
+
+func Test_Ether_011_confirm_once(t *testing.T) {
+	instances, _ := MiHomeEther(t, mihome.MiHome{
+		Mode:           sensors.MIHOME_MODE_MONITOR,
+		ConfirmRetries: mihome.CONFIRM_RETRIES_NONE,
+		ConfirmTimeout: 100 * time.Millisecond,
+	})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// Without retries, the request is sent once and times out within
+	// the first timeout and its jitter, before a retry would end
+	start := time.Now()
+	if err := instances[1].RequestSwitchConfirmed(context.Background(), sensors.MIHOME_PRODUCT_MIHO005, 0x40, true); err != sensors.ErrDeviceTimeout {
+		t.Error("Expected ErrDeviceTimeout, got", err)
+	} else if elapsed := time.Since(start); elapsed >= 300*time.Millisecond {
+		t.Error("Expected one attempt, took", elapsed)
+	}
+}
+
+func Test_Ether_012_add_proto(t *testing.T) {
+	_, radios := Ether(t, ether.Ether{}, 2)
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/openthings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto := app.ModuleInstance("sensors/protocol/openthings").(sensors.OTProto)
+	driver, err := gopi.Open(mihome.MiHome{Radio: radios[1], Mode: sensors.MIHOME_MODE_MONITOR}, Logger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	// Events are drained so that repeated payloads do not block the emitter
+	events := make(chan gopi.Event, 10)
+	go func(source <-chan gopi.Event) {
+		for evt := range source {
+			select {
+			case events <- evt:
+			default:
+			}
+		}
+	}(driver.(sensors.MiHome).Subscribe())
+	time.Sleep(50 * time.Millisecond)
+
+	// A payload received before the protocol is added isn't decoded,
+	// but one received afterwards is
+	var payload []byte
+	if message, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, uint8(sensors.MIHOME_PRODUCT_MIHO013), 0x1234); err != nil {
+		t.Fatal(err)
+	} else if record, err := proto.NewNull(sensors.OT_PARAM_IDENTIFY, false); err != nil {
+		t.Fatal(err)
+	} else {
+		payload = proto.Encode(message.Append(record))
+	}
+	if err := radios[0].Send(payload, 1, sensors.MIHOME_MODE_MONITOR); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := driver.(sensors.MiHome).AddProto(proto); err != nil {
+		t.Fatal(err)
+	} else if err := radios[0].Send(payload, 1, sensors.MIHOME_MODE_MONITOR); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case evt := <-events:
+			if message, ok := evt.(sensors.OTMessage); ok && message.Sensor() == 0x1234 {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for message")
+		}
+	}
+}
+
+func Test_Ether_013_subscriber(t *testing.T) {
+	instances, _ := MiHomeEther(t, mihome.MiHome{Mode: sensors.MIHOME_MODE_MONITOR})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// Requests are made by a subscriber when the first message is
+	// transmitted, where one supersedes the other
+	events := make(chan sensors.MiHomeTXEvent, 10)
+	go func(source <-chan gopi.Event) {
+		for evt := range source {
+			if evt_, ok := evt.(sensors.MiHomeTXEvent); ok == false {
+				continue
+			} else if message, ok := evt_.Message().(sensors.OTMessage); ok == false {
+				continue
+			} else if message.Sensor() == 0x60 {
+				if err := instances[0].RequestSwitchOn(sensors.MIHOME_PRODUCT_MIHO005, 0x61); err != nil {
+					t.Error(err)
+				} else if err := instances[0].RequestSwitchOff(sensors.MIHOME_PRODUCT_MIHO005, 0x61); err != nil {
+					t.Error(err)
+				}
+			} else {
+				events <- evt_
+			}
+		}
+	}(instances[0].Subscribe())
+	if err := instances[0].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO013, 0x60); err != nil {
+		t.Fatal(err)
+	}
+
+	// The superseded request and the transmitted request have events
+	errs := make([]error, 0, 2)
+	for len(errs) < 2 {
+		select {
+		case evt := <-events:
+			errs = append(errs, evt.Err())
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for transmit events")
+		}
+	}
+	if errs[0] != sensors.ErrMessageSuperseded || errs[1] != nil {
+		t.Error("Unexpected transmit events", errs)
+	}
+}
+
+func Test_Ether_014_confirm_window(t *testing.T) {
+	instances, _ := MiHomeEther(t, mihome.MiHome{
+		Mode:           sensors.MIHOME_MODE_MONITOR,
+		RXWindow:       300 * time.Millisecond,
+		ConfirmRetries: mihome.CONFIRM_RETRIES_NONE,
+		ConfirmTimeout: time.Second,
+	})
+	defer instances[0].Close()
+	defer instances[1].Close()
+
+	// The first instance is a device which reports the requested state
+	proto := OTProto(t, instances[0])
+	report := func(state bool) {
+		if message, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, uint8(sensors.MIHOME_PRODUCT_MIHO005), 0x71); err != nil {
+			t.Error(err)
+		} else if record, err := proto.NewBool(sensors.OT_PARAM_SWITCH_STATE, state, true); err != nil {
+			t.Error(err)
+		} else if err := instances[0].SendMessage(message.Append(record), sensors.MiHomeTXOptions{}); err != nil {
+			t.Error(err)
+		}
+	}
+	go func(source <-chan gopi.Event) {
+		for evt := range source {
+			if message, ok := evt.(sensors.OTMessage); ok && message.Sensor() == 0x71 {
+				if state, exists := SwitchState(message); exists {
+					report(state)
+				}
+			}
+		}
+	}(instances[0].Subscribe())
+
+	// The switch request waits for the receive window after the first
+	// transmission, during which the device reports a different state,
+	// which is ignored
+	if err := instances[1].RequestIdentify(sensors.MIHOME_PRODUCT_MIHO013, 0x70); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	errs := make(chan error)
+	go func() {
+		errs <- instances[1].RequestSwitchConfirmed(context.Background(), sensors.MIHOME_PRODUCT_MIHO005, 0x71, true)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	report(false)
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+}
+
+// OTProto returns the OpenThings protocol for an instance
+func OTProto(t *testing.T, instance sensors.MiHome) sensors.OTProto {
+	for _, proto := range instance.Protos() {
+		if proto_, ok := proto.(sensors.OTProto); ok {
+			return proto_
+		}
+	}
+	t.Fatal("Missing OTProto")
+	return nil
+}
+
+// SwitchState returns the switch state in a message
+func SwitchState(message sensors.Message) (bool, bool) {
+	if message, ok := message.(sensors.OTMessage); ok {
+		for _, record := range message.Records() {
+			if record.Name() != sensors.OT_PARAM_SWITCH_STATE {
+				continue
+			} else if state, err := record.BoolValue(); err == nil {
+				return state, true
+			}
+		}
+	}
+	return false, false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ETHER
+
+func Ether(t *testing.T, config ether.Ether, count int) (ether.Medium, []sensors.ENER314RT) {
+	log := Logger(t)
+	medium, err := gopi.Open(config, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	radios := make([]sensors.ENER314RT, count)
+	for i := range radios {
+		if radio, err := gopi.Open(ether.Radio{Ether: medium.(ether.Medium)}, log); err != nil {
+			t.Fatal(err)
+		} else {
+			radios[i] = radio.(sensors.ENER314RT)
+		}
+	}
+	return medium.(ether.Medium), radios
+}
+
+// Receive payloads in the background for 250ms and return them
+func Receive(t *testing.T, radio sensors.ENER314RT, mode sensors.MiHomeMode) <-chan [][]byte {
+	result := make(chan [][]byte)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+		defer cancel()
+		payloads := make(chan sensors.MiHomePayload)
+		received := make([][]byte, 0)
+		errors := make(chan error)
+		go func() {
+			errors <- radio.Receive(ctx, mode, payloads)
+		}()
+		for {
+			select {
+			case payload := <-payloads:
+				received = append(received, payload.Data)
+			case err := <-errors:
+				if err != context.DeadlineExceeded {
+					t.Error(err)
+				}
+				result <- received
+				return
+			}
+		}
+	}()
+	return result
+}
+
+// MiHomeEther returns two MiHome instances which share the ether, where
+// the second instance is opened with the configuration, and the events
+// emitted by the second instance. Events are drained so that repeated
+// payloads do not block the emitter
+func MiHomeEther(t *testing.T, config mihome.MiHome) ([]sensors.MiHome, <-chan gopi.Event) {
+	_, radios := Ether(t, ether.Ether{}, 2)
+	log := Logger(t)
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig("sensors/protocol/openthings", "sensors/protocol/ook"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	protos := []sensors.Proto{
+		app.ModuleInstance("sensors/protocol/openthings").(sensors.Proto),
+		app.ModuleInstance("sensors/protocol/ook").(sensors.Proto),
+	}
+	configs := []mihome.MiHome{
+		{Mode: sensors.MIHOME_MODE_MONITOR}, config,
+	}
+	instances := make([]sensors.MiHome, len(radios))
+	for i, radio := range radios {
+		configs[i].Radio = radio
+		configs[i].Repeat = 1
+		if driver, err := gopi.Open(configs[i], log); err != nil {
+			t.Fatal(err)
+		} else {
+			for _, proto := range protos {
+				if err := driver.(sensors.MiHome).AddProto(proto); err != nil {
+					t.Fatal(err)
+				}
+			}
+			instances[i] = driver.(sensors.MiHome)
+		}
+	}
+	events := make(chan gopi.Event, 10)
+	go func(source <-chan gopi.Event) {
+		for evt := range source {
+			select {
+			case events <- evt:
+				break
+			default:
+				break
+			}
+		}
+	}(instances[1].Subscribe())
+	time.Sleep(50 * time.Millisecond)
+	return instances, events
+}
@@ -0,0 +1,152 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mihome
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	sensors "github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - CONFIRMED SWITCH
+
+// RequestSwitchConfirmed sends the switch state to a device which reports
+// its switch state, and waits for a report with the state. The request is
+// sent again with backoff when there is no report or the report has a
+// different state, until the retries are exhausted when
+// ErrUnexpectedResponse is returned if a report had a different state, or
+// ErrDeviceTimeout if there was no report. Only reports received after
+// each request is transmitted are considered, and the time waiting for a
+// report starts once the request is transmitted. A request which isn't
+// transmitted before its deadline is sent again, and the error is returned
+// if a request is discarded for another reason
+func (this *mihome) RequestSwitchConfirmed(ctx context.Context, product sensors.MiHomeProduct, sensor uint32, state bool) error {
+	this.log.Debug2("<sensors.mihome>RequestSwitchConfirmed{ product=%v sensor=0x%05X state=%v }", product, sensor, state)
+
+	// Only the MIHO005 reports switch state
+	if product != sensors.MIHOME_PRODUCT_MIHO005 {
+		return gopi.ErrBadParameter
+	}
+
+	// Watch for reports before sending the first request
+	reports := this.watch(product, sensor)
+	defer this.unwatch(product, sensor, reports)
+
+	// Send with backoff until a report with the state is received, where
+	// mismatch is set once a report with a different state is received
+	mismatch := false
+	timeout := this.confirm_timeout
+	for attempt := uint(0); attempt <= this.confirm_retries; attempt++ {
+		wait := timeout + time.Duration(rand.Int63n(int64(timeout/2)+1))
+		done := make(chan tx_result, 1)
+		if err := this.tx_switch(product, sensor, state, time.Now().Add(wait), done); err != nil {
+			return err
+		}
+
+		// Wait for the request to be transmitted, ignoring reports
+		var sent time.Time
+	TX_LOOP:
+		for {
+			select {
+			case result := <-done:
+				if result.err == gopi.ErrDeadlineExceeded {
+					this.log.Debug("<sensors.mihome>RequestSwitchConfirmed: Not transmitted to 0x%05X (attempt %v)", sensor, attempt+1)
+				} else if result.err != nil {
+					return result.err
+				}
+				sent = result.ts
+				break TX_LOOP
+			case <-reports:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if sent.IsZero() {
+			timeout *= 2
+			continue
+		}
+
+		// Wait for a report after transmission
+		timer := time.NewTimer(time.Until(sent.Add(wait)))
+	WAIT_LOOP:
+		for {
+			select {
+			case message := <-reports:
+				if message.Timestamp().Before(sent) {
+					continue
+				} else if value, exists := switch_state(message); exists == false {
+					continue
+				} else if value == state {
+					timer.Stop()
+					return nil
+				} else {
+					this.log.Debug("<sensors.mihome>RequestSwitchConfirmed: Unexpected state from 0x%05X (attempt %v)", sensor, attempt+1)
+					timer.Stop()
+					mismatch = true
+					timeout *= 2
+					break WAIT_LOOP
+				}
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+				this.log.Debug("<sensors.mihome>RequestSwitchConfirmed: No report from 0x%05X (attempt %v)", sensor, attempt+1)
+				timeout *= 2
+				break WAIT_LOOP
+			}
+		}
+	}
+
+	// Retries exhausted
+	if mismatch {
+		return sensors.ErrUnexpectedResponse
+	} else {
+		return sensors.ErrDeviceTimeout
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// tx_switch queues a switch state request, which is discarded if not
+// transmitted before the deadline
+func (this *mihome) tx_switch(product sensors.MiHomeProduct, sensor uint32, state bool, deadline time.Time, done chan<- tx_result) error {
+	if proto := this.otProto(this.ProtosByMode(product.Mode())); proto == nil {
+		return gopi.ErrBadParameter
+	} else if message, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, uint8(product), sensor); err != nil {
+		return err
+	} else if record, err := proto.NewBool(sensors.OT_PARAM_SWITCH_STATE, state, true); err != nil {
+		return err
+	} else {
+		return this.tx_queue_wait(proto, message.Append(record), sensors.MiHomeTXOptions{
+			Priority: sensors.MIHOME_PRIORITY_HIGH,
+			Deadline: deadline,
+		}, done)
+	}
+}
+
+// switch_state returns the switch state reported in a message, or
+// false if the message has no switch state
+func switch_state(message sensors.OTMessage) (bool, bool) {
+	for _, record := range message.Records() {
+		if record.Name() != sensors.OT_PARAM_SWITCH_STATE {
+			continue
+		} else if value, err := record.BoolValue(); err == nil {
+			return value, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,218 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mihome
+
+import (
+	"fmt"
+	"sort"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	sensors "github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// change_event is emitted when a value received from a device changes
+type change_event struct {
+	source   gopi.Driver
+	product  sensors.MiHomeProduct
+	sensor   uint32
+	value    sensors.OTRecord
+	previous sensors.OTRecord
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - DEVICES
+
+func (this *mihome) Devices() []*sensors.MiHomeDevice {
+	this.devices_lock.Lock()
+	defer this.devices_lock.Unlock()
+
+	// Return copies of the devices, ordered by product and sensor
+	keys := make([]uint64, 0, len(this.devices))
+	for key := range this.devices {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	devices := make([]*sensors.MiHomeDevice, 0, len(keys))
+	for _, key := range keys {
+		devices = append(devices, device_copy(this.devices[key]))
+	}
+	return devices
+}
+
+func (this *mihome) Device(product sensors.MiHomeProduct, sensor uint32) *sensors.MiHomeDevice {
+	this.devices_lock.Lock()
+	defer this.devices_lock.Unlock()
+
+	if device, exists := this.devices[device_key(product, sensor)]; exists {
+		return device_copy(device)
+	} else {
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - UPDATE DEVICES
+
+// update_device records the values in a message received from a device
+// and returns events for the values which changed
+func (this *mihome) update_device(message sensors.OTMessage) []gopi.Event {
+	this.devices_lock.Lock()
+	defer this.devices_lock.Unlock()
+
+	// Only Energenie devices are recorded
+	if message.Manufacturer() != sensors.OT_MANUFACTURER_ENERGENIE {
+		return nil
+	}
+
+	product, sensor := sensors.MiHomeProduct(message.Product()), message.Sensor()
+	key := device_key(product, sensor)
+	device, exists := this.devices[key]
+	if exists == false {
+		device = &sensors.MiHomeDevice{
+			Product: product,
+			Sensor:  sensor,
+			Values:  make(map[sensors.OTParameter]sensors.MiHomeValue),
+		}
+		this.devices[key] = device
+	}
+	ts := message.Timestamp()
+	device.LastSeen = ts
+	device.Signal = message.Signal()
+
+	// Records without a value, such as requests and corrupted
+	// records, are not recorded
+	events := make([]gopi.Event, 0)
+	for _, record := range message.Records() {
+		if record.Name() == sensors.OT_PARAM_NONE || record_has_value(record) == false {
+			continue
+		}
+		value, exists := device.Values[record.Name()]
+		if exists == false || value.Record.IsDuplicate(record) == false {
+			events = append(events, &change_event{this, product, sensor, record, value.Record})
+			value.Changed = ts
+		}
+		value.Record = record
+		value.Updated = ts
+		device.Values[record.Name()] = value
+	}
+
+	// Pass the message to watchers
+	for _, watcher := range this.watchers[key] {
+		select {
+		case watcher <- message:
+		default:
+			this.log.Warn("<sensors.mihome>UpdateDevice: Watcher is full, dropping message from 0x%05X", sensor)
+		}
+	}
+
+	// Return change events
+	return events
+}
+
+// watch returns a channel on which messages received from a device
+// are passed
+func (this *mihome) watch(product sensors.MiHomeProduct, sensor uint32) chan sensors.OTMessage {
+	this.devices_lock.Lock()
+	defer this.devices_lock.Unlock()
+	key := device_key(product, sensor)
+	watcher := make(chan sensors.OTMessage, WATCHER_QUEUE_SIZE)
+	this.watchers[key] = append(this.watchers[key], watcher)
+	return watcher
+}
+
+func (this *mihome) unwatch(product sensors.MiHomeProduct, sensor uint32, watcher chan sensors.OTMessage) {
+	this.devices_lock.Lock()
+	defer this.devices_lock.Unlock()
+	key := device_key(product, sensor)
+	watchers := make([]chan sensors.OTMessage, 0, len(this.watchers[key]))
+	for _, other := range this.watchers[key] {
+		if other != watcher {
+			watchers = append(watchers, other)
+		}
+	}
+	if len(watchers) == 0 {
+		delete(this.watchers, key)
+	} else {
+		this.watchers[key] = watchers
+	}
+}
+
+// record_has_value returns true if a record has a value, where the
+// encoded record is the parameter and type bytes followed by the value
+func record_has_value(record sensors.OTRecord) bool {
+	if data, err := record.Data(); err != nil || len(data) <= 2 {
+		return false
+	} else {
+		return record.Value() != nil
+	}
+}
+
+// device_key returns the registry key for a device
+func device_key(product sensors.MiHomeProduct, sensor uint32) uint64 {
+	return uint64(product)<<32 | uint64(sensor)
+}
+
+// device_copy returns a copy of a device, so that it can be used without
+// the lock held
+func device_copy(device *sensors.MiHomeDevice) *sensors.MiHomeDevice {
+	other := *device
+	if device.Signal != nil {
+		signal := *device.Signal
+		other.Signal = &signal
+	}
+	other.Values = make(map[sensors.OTParameter]sensors.MiHomeValue, len(device.Values))
+	for name, value := range device.Values {
+		other.Values[name] = value
+	}
+	return &other
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CHANGE EVENT
+
+func (this *change_event) Name() string {
+	return "change"
+}
+
+func (this *change_event) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *change_event) Product() sensors.MiHomeProduct {
+	return this.product
+}
+
+func (this *change_event) Sensor() uint32 {
+	return this.sensor
+}
+
+func (this *change_event) Parameter() sensors.OTParameter {
+	return this.value.Name()
+}
+
+func (this *change_event) Value() sensors.OTRecord {
+	return this.value
+}
+
+func (this *change_event) Previous() sensors.OTRecord {
+	return this.previous
+}
+
+func (this *change_event) String() string {
+	if this.previous == nil {
+		return fmt.Sprintf("<sensors.mihome.ChangeEvent>{ product=%v sensor=0x%05X value=%v }", this.product, this.sensor, this.value)
+	} else {
+		return fmt.Sprintf("<sensors.mihome.ChangeEvent>{ product=%v sensor=0x%05X value=%v previous=%v }", this.product, this.sensor, this.value, this.previous)
+	}
+}
@@ -11,64 +11,175 @@ package mihome
 
 import (
 	"fmt"
+	"math"
+	"os"
 	"strings"
 
 	// Frameworks
 	gopi "github.com/djthorpe/gopi"
 	sensors "github.com/djthorpe/sensors"
+	capture "github.com/djthorpe/sensors/sys/capture"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 // INIT
 
 func init() {
-	// Register mihome module
+	// Register mihome module, which receives from the radio
 	gopi.RegisterModule(gopi.Module{
 		Name:     "sensors/mihome",
 		Type:     gopi.MODULE_TYPE_OTHER,
 		Requires: []string{"sensors/ener314rt"},
 		Config: func(config *gopi.AppConfig) {
-			// MiHome flags
-			config.AppFlags.FlagString("mihome.mode", "monitor", "RX mode")
-			config.AppFlags.FlagUint("mihome.repeat", 0, "Default TX Repeat")
-			config.AppFlags.FlagFloat64("mihome.tempoffset", 0, "Temperature Calibration Value")
+			miHomeConfig(config)
+			config.AppFlags.FlagString("mihome.record", "", "Append received and transmitted payloads to capture file")
 		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
-			// Convert mode to a MiHomeMode value
-			mode_, _ := app.AppFlags.GetString("mihome.mode")
-			repeat, _ := app.AppFlags.GetUint("mihome.repeat")
-			tempoffset, _ := app.AppFlags.GetFloat64("mihome.tempoffset")
-			if mode, err := miHomeModeFromString(mode_); err != nil {
+			if radio, ok := app.ModuleInstance("sensors/ener314rt").(sensors.ENER314RT); ok == false {
+				return nil, fmt.Errorf("Missing or invalid Radio module")
+			} else if path, _ := app.AppFlags.GetString("mihome.record"); path == "" {
+				return miHomeNew(app, radio, false)
+			} else if fh, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+				return nil, err
+			} else if recorder, err := gopi.Open(capture.Recorder{Radio: radio, Writer: fh}, app.Logger); err != nil {
+				fh.Close()
 				return nil, err
 			} else {
-				return gopi.Open(MiHome{
-					Radio:      app.ModuleInstance("sensors/ener314rt").(sensors.ENER314RT),
-					Mode:       mode,
-					Repeat:     repeat,
-					TempOffset: float32(tempoffset),
-				}, app.Logger)
+				return miHomeNew(app, recorder.(sensors.ENER314RT), true)
 			}
 		},
-		Run: func(app *gopi.AppInstance, driver gopi.Driver) error {
-			// Register protocols with driver. Codecs have OTHER as module type
-			// and name starting with "sensors/protocol"
-			for _, module := range gopi.ModulesByType(gopi.MODULE_TYPE_OTHER) {
-				if strings.HasPrefix(module.Name, "sensors/protocol/") == false {
-					continue
-				}
-				// Get protocol instance and register it
-				if proto, ok := app.ModuleInstance(module.Name).(sensors.Proto); ok == false {
-					return fmt.Errorf("Invalid protocol: %v: %v", module.Name, proto)
-				} else if err := driver.(sensors.MiHome).AddProto(proto); err != nil {
-					return err
-				}
+		Run: miHomeRun,
+	})
+
+	// Register mihome module, which receives from a capture file
+	// and doesn't require the radio
+	gopi.RegisterModule(gopi.Module{
+		Name: "sensors/mihome/replay",
+		Type: gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			miHomeConfig(config)
+			config.AppFlags.FlagString("mihome.replay", "", "Receive payloads from capture file instead of radio")
+			config.AppFlags.FlagFloat64("mihome.speed", 1, "Capture file replay speed, or zero for no delay")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			path, _ := app.AppFlags.GetString("mihome.replay")
+			speed, _ := app.AppFlags.GetFloat64("mihome.speed")
+			if path == "" {
+				return nil, fmt.Errorf("Missing -mihome.replay value")
+			} else if fh, err := os.Open(path); err != nil {
+				return nil, err
+			} else if replay, err := gopi.Open(capture.Replay{Reader: fh, Speed: speed}, app.Logger); err != nil {
+				fh.Close()
+				return nil, err
+			} else {
+				return miHomeNew(app, replay.(sensors.ENER314RT), true)
 			}
-			// Return success
-			return nil
 		},
+		Run: miHomeRun,
 	})
 }
 
+// IsReplay returns true if the -mihome.replay flag is set in the command
+// line arguments. Modules are resolved before the flags are parsed, so
+// commands use this to choose the "sensors/mihome/replay" module, which
+// doesn't require the radio, instead of the "sensors/mihome" module
+func IsReplay(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		} else if name := strings.TrimLeft(arg, "-"); name == arg {
+			continue
+		} else if name == "mihome.replay" || strings.HasPrefix(name, "mihome.replay=") {
+			return true
+		}
+	}
+	return false
+}
+
+// miHomeConfig sets the flags for the mihome modules
+func miHomeConfig(config *gopi.AppConfig) {
+	config.AppFlags.FlagString("mihome.mode", "monitor", "RX mode (monitor, control or both)")
+	config.AppFlags.FlagDuration("mihome.dwell.monitor", DWELL_MONITOR_DEFAULT, "Time receiving in monitor mode when RX mode is both")
+	config.AppFlags.FlagDuration("mihome.dwell.control", DWELL_CONTROL_DEFAULT, "Time receiving in control mode when RX mode is both")
+	config.AppFlags.FlagUint("mihome.repeat", 0, "Default TX Repeat")
+	config.AppFlags.FlagDuration("mihome.rx.window", RX_WINDOW_DEFAULT, "Time receiving between queued transmissions")
+	config.AppFlags.FlagDuration("mihome.tx.deadline", TX_DEADLINE_DEFAULT, "Time after which queued transmissions are discarded")
+	config.AppFlags.FlagUint("mihome.confirm.retries", CONFIRM_RETRIES_DEFAULT, "Number of retries for confirmed switching")
+	config.AppFlags.FlagDuration("mihome.confirm.timeout", CONFIRM_TIMEOUT_DEFAULT, "Confirmed switching timeout, which doubles on each retry")
+	config.AppFlags.FlagFloat64("mihome.tempoffset", 0, "Temperature Calibration Value")
+	config.AppFlags.FlagInt("mihome.power", 0, "TX Power (dBm), or zero for radio default")
+}
+
+// miHomeNew opens the driver with the radio, which is closed when the
+// driver is closed, or on error, when close_radio is true
+func miHomeNew(app *gopi.AppInstance, radio sensors.ENER314RT, close_radio bool) (gopi.Driver, error) {
+	// Convert mode to a MiHomeMode value
+	mode_, _ := app.AppFlags.GetString("mihome.mode")
+	repeat, _ := app.AppFlags.GetUint("mihome.repeat")
+	tempoffset, _ := app.AppFlags.GetFloat64("mihome.tempoffset")
+	power, _ := app.AppFlags.GetInt("mihome.power")
+	monitor_dwell, _ := app.AppFlags.GetDuration("mihome.dwell.monitor")
+	control_dwell, _ := app.AppFlags.GetDuration("mihome.dwell.control")
+	rx_window, _ := app.AppFlags.GetDuration("mihome.rx.window")
+	tx_deadline, _ := app.AppFlags.GetDuration("mihome.tx.deadline")
+	confirm_retries, _ := app.AppFlags.GetUint("mihome.confirm.retries")
+	confirm_timeout, _ := app.AppFlags.GetDuration("mihome.confirm.timeout")
+	if confirm_retries == 0 {
+		confirm_retries = CONFIRM_RETRIES_NONE
+	}
+
+	var driver gopi.Driver
+	var err error
+	if mode, err_ := miHomeModeFromString(mode_); err_ != nil {
+		err = err_
+	} else if power < math.MinInt8 || power > math.MaxInt8 {
+		err = fmt.Errorf("Invalid -mihome.power value: %v", power)
+	} else if monitor_dwell <= 0 || control_dwell <= 0 {
+		err = fmt.Errorf("Invalid -mihome.dwell.monitor or -mihome.dwell.control value")
+	} else if rx_window <= 0 || tx_deadline <= 0 {
+		err = fmt.Errorf("Invalid -mihome.rx.window or -mihome.tx.deadline value")
+	} else if confirm_timeout <= 0 {
+		err = fmt.Errorf("Invalid -mihome.confirm.timeout value")
+	} else {
+		driver, err = gopi.Open(MiHome{
+			Radio:          radio,
+			CloseRadio:     close_radio,
+			Mode:           mode,
+			Repeat:         repeat,
+			TempOffset:     float32(tempoffset),
+			OutputPower:    int8(power),
+			MonitorDwell:   monitor_dwell,
+			ControlDwell:   control_dwell,
+			RXWindow:       rx_window,
+			TXDeadline:     tx_deadline,
+			ConfirmRetries: confirm_retries,
+			ConfirmTimeout: confirm_timeout,
+		}, app.Logger)
+	}
+	if err != nil && close_radio {
+		radio.Close()
+	}
+	return driver, err
+}
+
+// miHomeRun registers protocols with the driver. Codecs have OTHER as
+// module type and name starting with "sensors/protocol"
+func miHomeRun(app *gopi.AppInstance, driver gopi.Driver) error {
+	for _, module := range gopi.ModulesByType(gopi.MODULE_TYPE_OTHER) {
+		if strings.HasPrefix(module.Name, "sensors/protocol/") == false {
+			continue
+		}
+		// Get protocol instance and register it
+		if proto, ok := app.ModuleInstance(module.Name).(sensors.Proto); ok == false {
+			return fmt.Errorf("Invalid protocol: %v: %v", module.Name, proto)
+		} else if err := driver.(sensors.MiHome).AddProto(proto); err != nil {
+			return err
+		}
+	}
+	// Return success
+	return nil
+}
+
 // stringFromMode returns an upper-case string from a MiHomeMode
 // or returns an empty string otherwise
 func stringFromMiHomeMode(mode sensors.MiHomeMode) string {
@@ -80,7 +191,7 @@ func stringFromMiHomeMode(mode sensors.MiHomeMode) string {
 func miHomeModeFromString(value string) (sensors.MiHomeMode, error) {
 	value_upper := strings.TrimSpace(strings.ToUpper(value))
 	all_modes := make([]string, 0)
-	for mode := sensors.MIHOME_MODE_NONE; mode <= sensors.MIHOME_MODE_MAX; mode++ {
+	for mode := sensors.MIHOME_MODE_NONE; mode <= sensors.MIHOME_MODE_BOTH; mode++ {
 		if mode_string := stringFromMiHomeMode(mode); mode_string == value_upper {
 			// Return mode
 			return mode, nil
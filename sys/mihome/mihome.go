@@ -27,21 +27,64 @@ import (
 // TYPES
 
 type MiHome struct {
-	Radio      sensors.ENER314RT
-	Mode       sensors.MiHomeMode
-	Repeat     uint    // Number of times to repeat messages by default
-	TempOffset float32 // Temperature Offset
+	Radio        sensors.ENER314RT
+	CloseRadio   bool // Close the radio when the driver is closed
+	Mode         sensors.MiHomeMode
+	Repeat       uint          // Number of times to repeat messages by default
+	TempOffset   float32       // Temperature Offset
+	OutputPower  int8          // Transmit power in dBm, or zero for the radio default
+	MonitorDwell time.Duration // Time receiving in monitor mode when mode is both, or zero for the default
+	ControlDwell time.Duration // Time receiving in control mode when mode is both, or zero for the default
+	RXWindow     time.Duration // Time receiving between queued transmissions, or zero for the default
+	TXDeadline   time.Duration // Time after which queued messages are discarded by default, or zero for the default
+
+	// Number of times a confirmed request is sent again, and the time to wait
+	// for the first confirmation which doubles each time, or zero for the defaults.
+	// Use CONFIRM_RETRIES_NONE to send a confirmed request only once
+	ConfirmRetries uint
+	ConfirmTimeout time.Duration
 }
 
 type mihome struct {
-	log        gopi.Logger
-	radio      sensors.ENER314RT
-	repeat     uint
-	tempoffset float32
-	mode       sensors.MiHomeMode
-	cancel     context.CancelFunc
-	err        chan error
-	payload    chan []byte
+	log         gopi.Logger
+	radio       sensors.ENER314RT
+	close_radio bool
+	repeat      uint
+	tempoffset  float32
+	mode        sensors.MiHomeMode
+	dwell       map[sensors.MiHomeMode]time.Duration
+	cancel      context.CancelFunc
+	err         chan error
+	payload     chan payload
+
+	// Statistics for each mode, and the mode being received
+	stats      map[sensors.MiHomeMode]*sensors.MiHomeStatistics
+	rx_active  sensors.MiHomeMode
+	rx_since   time.Time
+	stats_lock sync.Mutex
+
+	// Messages queued for transmission, superseded messages waiting for
+	// an event to be emitted, the time receiving between transmissions
+	// and the transmit task
+	tx_requests   []*tx_request
+	tx_superseded []*tx_request
+	tx_seq        uint64
+	tx_deadline   time.Duration
+	rx_window     time.Duration
+	tx_signal     chan struct{}
+	tx_stop       chan struct{}
+	tx_done       chan struct{}
+	tx_lock       sync.Mutex
+
+	// State of devices messages have been received from, and channels
+	// waiting for messages from devices
+	devices      map[uint64]*sensors.MiHomeDevice
+	watchers     map[uint64][]chan sensors.OTMessage
+	devices_lock sync.Mutex
+
+	// Number of retries and the first timeout for confirmed requests
+	confirm_retries uint
+	confirm_timeout time.Duration
 
 	Protocols
 	event.Publisher
@@ -49,12 +92,42 @@ type mihome struct {
 	sync.Mutex
 }
 
+// payload is a received payload and the mode it was received in
+type payload struct {
+	sensors.MiHomePayload
+	mode sensors.MiHomeMode
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CONSTANTS
 
 const (
 	// Default number of times to repeat command
 	REPEAT_DEFAULT = 3
+
+	// Default time receiving in each mode when the mode is both. OOK
+	// remotes repeat their code, so need less time than FSK devices
+	DWELL_MONITOR_DEFAULT = 3 * time.Second
+	DWELL_CONTROL_DEFAULT = 1 * time.Second
+
+	// Default time receiving between queued transmissions, and the
+	// default time after which queued messages are discarded
+	RX_WINDOW_DEFAULT   = 100 * time.Millisecond
+	TX_DEADLINE_DEFAULT = 30 * time.Second
+
+	// Maximum time after which a queued time sync is discarded, since the
+	// time is read when it's queued rather than when it's transmitted
+	TIME_SYNC_DEADLINE = 2 * time.Second
+
+	// Default number of retries and first timeout for confirmed requests,
+	// and the number of messages which can wait for a confirmed request
+	CONFIRM_RETRIES_DEFAULT = 3
+	CONFIRM_TIMEOUT_DEFAULT = time.Second
+	WATCHER_QUEUE_SIZE      = 4
+
+	// Value for ConfirmRetries which sends confirmed requests once, since
+	// zero is the default number of retries
+	CONFIRM_RETRIES_NONE = ^uint(0)
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -62,24 +135,72 @@ const (
 
 // Open the server
 func (config MiHome) Open(log gopi.Logger) (gopi.Driver, error) {
-	log.Debug("<sensors.mihome>Open{ mode=%v radio=%v repeat=%v tempoffset=%v }", config.Mode, config.Radio, config.Repeat, config.TempOffset)
+	log.Debug("<sensors.mihome>Open{ mode=%v radio=%v repeat=%v tempoffset=%v output_power=%v }", config.Mode, config.Radio, config.Repeat, config.TempOffset, config.OutputPower)
 
 	// Check for bad input parameters
 	if config.Repeat == 0 {
 		config.Repeat = REPEAT_DEFAULT
 	}
-	if config.Radio == nil {
+	if config.MonitorDwell == 0 {
+		config.MonitorDwell = DWELL_MONITOR_DEFAULT
+	}
+	if config.ControlDwell == 0 {
+		config.ControlDwell = DWELL_CONTROL_DEFAULT
+	}
+	if config.RXWindow == 0 {
+		config.RXWindow = RX_WINDOW_DEFAULT
+	}
+	if config.TXDeadline == 0 {
+		config.TXDeadline = TX_DEADLINE_DEFAULT
+	}
+	if config.Radio == nil || config.Mode > sensors.MIHOME_MODE_BOTH {
+		return nil, gopi.ErrBadParameter
+	}
+	if config.MonitorDwell < 0 || config.ControlDwell < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	if config.ConfirmRetries == 0 {
+		config.ConfirmRetries = CONFIRM_RETRIES_DEFAULT
+	} else if config.ConfirmRetries == CONFIRM_RETRIES_NONE {
+		config.ConfirmRetries = 0
+	}
+	if config.ConfirmTimeout == 0 {
+		config.ConfirmTimeout = CONFIRM_TIMEOUT_DEFAULT
+	}
+	if config.RXWindow < 0 || config.TXDeadline < 0 || config.ConfirmTimeout < 0 {
 		return nil, gopi.ErrBadParameter
 	}
 
 	this := new(mihome)
 	this.log = log
 	this.radio = config.Radio
+	this.close_radio = config.CloseRadio
 	this.mode = config.Mode
 	this.err = make(chan error)
-	this.payload = make(chan []byte)
+	this.payload = make(chan payload)
 	this.repeat = config.Repeat
 	this.tempoffset = config.TempOffset
+	this.dwell = map[sensors.MiHomeMode]time.Duration{
+		sensors.MIHOME_MODE_MONITOR: config.MonitorDwell,
+		sensors.MIHOME_MODE_CONTROL: config.ControlDwell,
+	}
+	this.stats = make(map[sensors.MiHomeMode]*sensors.MiHomeStatistics)
+	this.devices = make(map[uint64]*sensors.MiHomeDevice)
+	this.watchers = make(map[uint64][]chan sensors.OTMessage)
+	this.confirm_retries = config.ConfirmRetries
+	this.confirm_timeout = config.ConfirmTimeout
+	this.rx_window = config.RXWindow
+	this.tx_deadline = config.TXDeadline
+	this.tx_signal = make(chan struct{}, 1)
+	this.tx_stop = make(chan struct{})
+	this.tx_done = make(chan struct{})
+
+	// Set transmit power
+	if config.OutputPower != 0 {
+		if err := this.radio.SetOutputPower(config.OutputPower); err != nil {
+			return nil, err
+		}
+	}
 
 	// Start receiving and recording device temperature
 	this.Tasks.Start(this.receive)
@@ -89,6 +210,9 @@ func (config MiHome) Open(log gopi.Logger) (gopi.Driver, error) {
 		return nil, err
 	}
 
+	// Start transmitting queued messages
+	go this.transmit()
+
 	// Success
 	return this, nil
 }
@@ -96,6 +220,10 @@ func (config MiHome) Open(log gopi.Logger) (gopi.Driver, error) {
 func (this *mihome) Close() error {
 	this.log.Debug("<sensors.mihome>Close{ mode=%v }", this.mode)
 
+	// Stop transmitting, which completes any transmission in progress
+	close(this.tx_stop)
+	<-this.tx_done
+
 	// Cancel receive mode in foreground
 	if err := this.rx_mode(false); err != nil {
 		return err
@@ -112,6 +240,13 @@ func (this *mihome) Close() error {
 	// Close protocol map and publisher
 	this.Protocols.Close()
 
+	// Close the radio, which flushes any capture file
+	if this.close_radio {
+		if err := this.radio.Close(); err != nil {
+			return err
+		}
+	}
+
 	// Release resources
 	this.radio = nil
 
@@ -123,7 +258,31 @@ func (this *mihome) Close() error {
 // STRINGIFY
 
 func (this *mihome) String() string {
-	return fmt.Sprintf("<sensors.mihome>{ mode=%v }", this.mode)
+	if this.mode == sensors.MIHOME_MODE_BOTH {
+		return fmt.Sprintf("<sensors.mihome>{ mode=%v monitor_dwell=%v control_dwell=%v }", this.mode, this.dwell[sensors.MIHOME_MODE_MONITOR], this.dwell[sensors.MIHOME_MODE_CONTROL])
+	} else {
+		return fmt.Sprintf("<sensors.mihome>{ mode=%v }", this.mode)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - STATISTICS
+
+func (this *mihome) Statistics() map[sensors.MiHomeMode]sensors.MiHomeStatistics {
+	this.stats_lock.Lock()
+	defer this.stats_lock.Unlock()
+
+	// Include the time in the mode currently being received
+	stats := make(map[sensors.MiHomeMode]sensors.MiHomeStatistics, len(this.stats))
+	for mode, value := range this.stats {
+		stats[mode] = *value
+	}
+	if this.rx_active != sensors.MIHOME_MODE_NONE {
+		value := stats[this.rx_active]
+		value.Duration += time.Since(this.rx_since)
+		stats[this.rx_active] = value
+	}
+	return stats
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -181,22 +340,22 @@ func (this *mihome) RequestSwitchState(product sensors.MiHomeProduct, sensor uin
 	} else if protos := this.ProtosByMode(mode); len(protos) == 0 {
 		// Invalid mode for product
 		return gopi.ErrBadParameter
-	} else if proto, ok := protos[0].(sensors.OOKProto); ok && proto != nil {
+	} else if proto := this.ookProto(protos); proto != nil {
 		// OOK Protocol
 		if message, err := proto.New(sensor, product.Socket(), state, nil); err != nil {
 			return err
-		} else if err := this.tx_mode(proto, message); err != nil {
+		} else if err := this.tx_queue(proto, message, sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_HIGH}); err != nil {
 			return err
 		} else {
 			return nil
 		}
-	} else if proto, ok := protos[0].(sensors.OTProto); ok && proto != nil {
+	} else if proto := this.otProto(protos); proto != nil {
 		// FSK (OpenThings) Protocol
 		if message, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, uint8(product), sensor); err != nil {
 			return err
 		} else if state, err := proto.NewBool(sensors.OT_PARAM_SWITCH_STATE, state, true); err != nil {
 			return err
-		} else if err := this.tx_mode(proto, message.Append(state)); err != nil {
+		} else if err := this.tx_queue(proto, message.Append(state), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_HIGH}); err != nil {
 			return err
 		} else {
 			return nil
@@ -222,7 +381,7 @@ func (this *mihome) RequestIdentify(product sensors.MiHomeProduct, sensor uint32
 		return err
 	} else if record, err := proto.NewNull(sensors.OT_PARAM_IDENTIFY, true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -242,7 +401,7 @@ func (this *mihome) RequestDiagnostics(product sensors.MiHomeProduct, sensor uin
 		return err
 	} else if record, err := proto.NewNull(sensors.OT_PARAM_DIAGNOSTICS, true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -261,7 +420,7 @@ func (this *mihome) RequestExercise(product sensors.MiHomeProduct, sensor uint32
 		return err
 	} else if record, err := proto.NewNull(sensors.OT_PARAM_EXERCISE, true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -281,7 +440,7 @@ func (this *mihome) RequestBatteryLevel(product sensors.MiHomeProduct, sensor ui
 		return err
 	} else if record, err := proto.NewNull(sensors.OT_PARAM_BATTERY_LEVEL, true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -301,7 +460,7 @@ func (this *mihome) SendJoin(product sensors.MiHomeProduct, sensor uint32) error
 		return err
 	} else if record, err := proto.NewNull(sensors.OT_PARAM_JOIN, false); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -326,7 +485,7 @@ func (this *mihome) RequestTargetTemperature(product sensors.MiHomeProduct, sens
 		return err
 	} else if record, err := proto.NewFloat(sensors.OT_PARAM_TEMPERATURE, sensors.OT_DATATYPE_DEC_8, celcius, true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -352,7 +511,7 @@ func (this *mihome) RequestReportInterval(product sensors.MiHomeProduct, sensor
 		return err
 	} else if record, err := proto.NewUint16(sensors.OT_PARAM_REPORT_PERIOD, uint16(seconds), true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -372,7 +531,7 @@ func (this *mihome) RequestValveState(product sensors.MiHomeProduct, sensor uint
 		return err
 	} else if record, err := proto.NewUint8(sensors.OT_PARAM_VALVE_STATE, uint8(state), true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
 		return err
 	}
 
@@ -392,7 +551,35 @@ func (this *mihome) RequestLowPowerMode(product sensors.MiHomeProduct, sensor ui
 		return err
 	} else if record, err := proto.NewBool(sensors.OT_PARAM_LOW_POWER, mode, true); err != nil {
 		return err
-	} else if err := this.tx_mode(proto, msg.Append(record)); err != nil {
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_NORMAL}); err != nil {
+		return err
+	}
+
+	// Success
+	return nil
+}
+
+func (this *mihome) RequestTimeSync(product sensors.MiHomeProduct, sensor uint32) error {
+	this.log.Debug2("<sensors.mihome>RequestTimeSync{ product=%v sensor=0x%08X }", product, sensor)
+
+	// The time is read now, so the request is discarded if it's not
+	// transmitted soon after
+	now := time.Now()
+	deadline := TIME_SYNC_DEADLINE
+	if this.tx_deadline < deadline {
+		deadline = this.tx_deadline
+	}
+
+	// We only support this with the openthings protocol in monitor mode
+	if proto_ := this.ProtoByName("openthings"); proto_ == nil {
+		return gopi.ErrBadParameter
+	} else if proto, ok := proto_.(sensors.OTProto); ok == false || proto == nil {
+		return gopi.ErrBadParameter
+	} else if msg, err := proto.New(sensors.OT_MANUFACTURER_ENERGENIE, uint8(product), sensor); err != nil {
+		return err
+	} else if record, err := proto.NewTime(sensors.OT_PARAM_TIME_AND_DATE, now, true); err != nil {
+		return err
+	} else if err := this.tx_queue(proto, msg.Append(record), sensors.MiHomeTXOptions{Priority: sensors.MIHOME_PRIORITY_LOW, Deadline: now.Add(deadline)}); err != nil {
 		return err
 	}
 
@@ -403,15 +590,17 @@ func (this *mihome) RequestLowPowerMode(product sensors.MiHomeProduct, sensor ui
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS - TX DATA
 
-func (this *mihome) tx_mode(proto sensors.Proto, message sensors.Message) error {
-	this.log.Debug("<sensors.mihome>TXMode{ proto=%v messgage=%v }", proto, message)
+func (this *mihome) tx_mode(req *tx_request) error {
+	this.log.Debug("<sensors.mihome>TXMode{ proto=%v message=%v repeat=%v }", req.proto, req.message, req.repeat)
 
-	// Encode the message, switch off RX mode, send then return to RX mode
-	if encoded := proto.Encode(message); len(encoded) == 0 {
-		return sensors.ErrMessageCorruption
-	} else if err := this.rx_mode(false); err != nil {
+	// Switch off RX mode, send then return to RX mode even when
+	// the send fails, returning the send error first
+	if err := this.rx_mode(false); err != nil {
 		return err
-	} else if err := this.radio.Send(encoded, this.repeat, proto.Mode()); err != nil {
+	} else if err := this.radio.Send(req.encoded, req.repeat, req.proto.Mode()); err != nil {
+		if err_ := this.rx_mode(true); err_ != nil {
+			this.log.Warn("<sensors.mihome>TXMode: %v", err_)
+		}
 		return err
 	} else if err := this.rx_mode(true); err != nil {
 		return err
@@ -420,6 +609,27 @@ func (this *mihome) tx_mode(proto sensors.Proto, message sensors.Message) error
 	}
 }
 
+// ookProto returns the first OOK protocol, since other protocols
+// can be registered in the same mode
+func (this *mihome) ookProto(protos []sensors.Proto) sensors.OOKProto {
+	for _, proto := range protos {
+		if proto_, ok := proto.(sensors.OOKProto); ok && proto_ != nil {
+			return proto_
+		}
+	}
+	return nil
+}
+
+// otProto returns the first OpenThings protocol
+func (this *mihome) otProto(protos []sensors.Proto) sensors.OTProto {
+	for _, proto := range protos {
+		if proto_, ok := proto.(sensors.OTProto); ok && proto_ != nil {
+			return proto_
+		}
+	}
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS - START AND STOP RX MODE
 
@@ -447,12 +657,14 @@ func (this *mihome) rx_mode(state bool) error {
 		// Do nothing with RX mode here
 	} else if state && this.cancel == nil {
 		// If state is ON, then run it in the background until we receive an error or nil
+		// The radio is passed to the background task, since it is
+		// released when the driver is closed
 		ctx, cancel := context.WithCancel(context.Background())
 		this.cancel = cancel
-		go func(ctx context.Context) {
-			err := this.radio.Receive(ctx, this.mode, this.payload)
+		go func(ctx context.Context, radio sensors.ENER314RT) {
+			err := this.rx(ctx, radio)
 			this.err <- err
-		}(ctx)
+		}(ctx, this.radio)
 	} else {
 		// Assume RX is already running
 		//this.log.Warn("<sensors.mihome>RXMode: Invalid state, state=%v cancel=%v", state, this.cancel)
@@ -462,6 +674,87 @@ func (this *mihome) rx_mode(state bool) error {
 	return nil
 }
 
+// rx receives payloads until the context is done, alternating between
+// monitor and control modes when the mode is both
+func (this *mihome) rx(ctx context.Context, radio sensors.ENER314RT) error {
+	if this.mode != sensors.MIHOME_MODE_BOTH {
+		return this.rx_dwell(ctx, radio, this.mode, 0)
+	}
+	for {
+		for _, mode := range []sensors.MiHomeMode{sensors.MIHOME_MODE_MONITOR, sensors.MIHOME_MODE_CONTROL} {
+			if err := this.rx_dwell(ctx, radio, mode, this.dwell[mode]); err != context.DeadlineExceeded {
+				return err
+			}
+		}
+	}
+}
+
+// rx_dwell receives payloads in a mode for the dwell time, or until the
+// context is done if the dwell time is zero, and passes them to the
+// receive task with the mode
+func (this *mihome) rx_dwell(ctx context.Context, radio sensors.ENER314RT, mode sensors.MiHomeMode, dwell time.Duration) error {
+	if dwell > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dwell)
+		defer cancel()
+	}
+
+	this.rx_start(mode)
+	defer this.rx_end(mode)
+
+	payloads := make(chan sensors.MiHomePayload)
+	done := make(chan error)
+	go func() {
+		done <- radio.Receive(ctx, mode, payloads)
+	}()
+	for {
+		select {
+		case value := <-payloads:
+			this.payload <- payload{value, mode}
+		case err := <-done:
+			return err
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - STATISTICS
+
+func (this *mihome) rx_start(mode sensors.MiHomeMode) {
+	this.stats_lock.Lock()
+	defer this.stats_lock.Unlock()
+	this.rx_active = mode
+	this.rx_since = time.Now()
+}
+
+func (this *mihome) rx_end(mode sensors.MiHomeMode) {
+	this.stats_lock.Lock()
+	defer this.stats_lock.Unlock()
+	this.stats_for(mode).Duration += time.Since(this.rx_since)
+	this.rx_active = sensors.MIHOME_MODE_NONE
+}
+
+func (this *mihome) rx_decoded(mode sensors.MiHomeMode, decoded bool) {
+	this.stats_lock.Lock()
+	defer this.stats_lock.Unlock()
+	stats := this.stats_for(mode)
+	stats.Payloads++
+	if decoded {
+		stats.Decoded++
+	}
+}
+
+// stats_for returns the statistics for a mode, which is called with
+// the lock held
+func (this *mihome) stats_for(mode sensors.MiHomeMode) *sensors.MiHomeStatistics {
+	if stats, exists := this.stats[mode]; exists {
+		return stats
+	}
+	stats := new(sensors.MiHomeStatistics)
+	this.stats[mode] = stats
+	return stats
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // RECEIVE AND DECODE DATA
 
@@ -469,22 +762,24 @@ func (this *mihome) receive(start chan<- struct{}, stop <-chan struct{}) error {
 	this.log.Debug("<sensors.mihome>receive: Started")
 	start <- gopi.DONE
 
-	// Obtain the protocols we will use to decode the message
-	var protocols []sensors.Proto
-
 FOR_LOOP:
 	for {
 		select {
-		case data := <-this.payload:
-			if protocols == nil {
-				protocols = this.ProtosByMode(this.mode)
-				if this.mode != sensors.MIHOME_MODE_NONE {
-					protocols = append(protocols, this.ProtosByMode(sensors.MIHOME_MODE_NONE)...)
-				}
-			} else if len(protocols) == 0 {
-				this.log.Warn("<sensors.mihome>Receive: No protocols found for mode %v", this.mode)
-			} else if err := this.decode(data, protocols); err != nil {
+		case payload := <-this.payload:
+			// Obtain the protocols for the mode the payload was received in
+			// for each payload, since protocols can be added at any time
+			protos := this.ProtosByMode(payload.mode)
+			if payload.mode != sensors.MIHOME_MODE_NONE {
+				protos = append(protos, this.ProtosByMode(sensors.MIHOME_MODE_NONE)...)
+			}
+			if len(protos) == 0 {
+				this.log.Warn("<sensors.mihome>Receive: No protocols found for mode %v", payload.mode)
+				this.rx_decoded(payload.mode, false)
+			} else if err := this.decode(payload.MiHomePayload, protos); err != nil {
 				this.log.Warn("<sensors.mihome>Receive: %v", err)
+				this.rx_decoded(payload.mode, false)
+			} else {
+				this.rx_decoded(payload.mode, true)
 			}
 		case err := <-this.err:
 			if err != context.Canceled && err != sensors.ErrDeviceTimeout {
@@ -506,17 +801,25 @@ FOR_LOOP:
 	return nil
 }
 
-func (this *mihome) decode(payload []byte, protos []sensors.Proto) error {
+func (this *mihome) decode(payload sensors.MiHomePayload, protos []sensors.Proto) error {
 	// Check arguments
-	if len(payload) == 0 || len(protos) == 0 {
+	if len(payload.Data) == 0 || len(protos) == 0 {
 		return gopi.ErrBadParameter
 	}
 
-	// Decode through protocols until we find one which decodes the payload
+	// Decode through protocols until we find one which decodes the payload,
+	// and attach the signal metadata to the message. Values which change
+	// are emitted after the message
 	var last_err error
 	for _, proto := range protos {
-		if msg, err := proto.Decode(payload, time.Now()); err == nil {
+		if msg, err := proto.Decode(payload.Data, time.Now()); err == nil {
+			msg.SetSignal(payload.Signal)
 			this.Emit(msg)
+			if msg_, ok := msg.(sensors.OTMessage); ok {
+				for _, evt := range this.update_device(msg_) {
+					this.Emit(evt)
+				}
+			}
 			return nil
 		} else {
 			// Record the error returned
@@ -10,6 +10,8 @@
 package mihome
 
 import (
+	"sync"
+
 	// Frameworks
 	gopi "github.com/djthorpe/gopi"
 	sensors "github.com/djthorpe/sensors"
@@ -21,12 +23,15 @@ import (
 type Protocols struct {
 	proto_map  map[string]sensors.Proto
 	proto_mode map[sensors.MiHomeMode][]sensors.Proto
+	proto_lock sync.RWMutex
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // RELEASE RESOURCES
 
 func (this *Protocols) Close() {
+	this.proto_lock.Lock()
+	defer this.proto_lock.Unlock()
 	this.proto_map = nil
 	this.proto_mode = nil
 }
@@ -35,6 +40,9 @@ func (this *Protocols) Close() {
 // PUBLIC METHODS
 
 func (this *Protocols) AddProto(proto sensors.Proto) error {
+	this.proto_lock.Lock()
+	defer this.proto_lock.Unlock()
+
 	// Create data structures as necessary
 	if this.proto_map == nil {
 		this.proto_map = make(map[string]sensors.Proto, 1)
@@ -65,6 +73,8 @@ func (this *Protocols) AddProto(proto sensors.Proto) error {
 
 // Protos returns registered protocols
 func (this *Protocols) Protos() []sensors.Proto {
+	this.proto_lock.RLock()
+	defer this.proto_lock.RUnlock()
 	protos := make([]sensors.Proto, 0, len(this.proto_map))
 	for _, proto := range this.proto_map {
 		protos = append(protos, proto)
@@ -74,6 +84,8 @@ func (this *Protocols) Protos() []sensors.Proto {
 
 // ProtoByName returns a single protocol
 func (this *Protocols) ProtoByName(name string) sensors.Proto {
+	this.proto_lock.RLock()
+	defer this.proto_lock.RUnlock()
 	if proto, exists := this.proto_map[name]; exists == false {
 		return nil
 	} else {
@@ -81,11 +93,14 @@ func (this *Protocols) ProtoByName(name string) sensors.Proto {
 	}
 }
 
-// ProtosByMode returns zero or more protocols by mode
+// ProtosByMode returns zero or more protocols by mode, which is a
+// copy so that it can be appended to
 func (this *Protocols) ProtosByMode(mode sensors.MiHomeMode) []sensors.Proto {
+	this.proto_lock.RLock()
+	defer this.proto_lock.RUnlock()
 	if protos, exists := this.proto_mode[mode]; exists == false {
 		return nil
 	} else {
-		return protos
+		return append([]sensors.Proto(nil), protos...)
 	}
 }
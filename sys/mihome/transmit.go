@@ -0,0 +1,382 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mihome
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	sensors "github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// tx_request is a message waiting to be transmitted, where seq is the
+// order in which it was queued
+type tx_request struct {
+	proto    sensors.Proto
+	message  sensors.Message
+	encoded  []byte
+	priority sensors.MiHomePriority
+	repeat   uint
+	deadline time.Time
+	seq      uint64
+	done     []chan<- tx_result
+}
+
+// tx_result is sent to the channels waiting for a request once it has
+// been transmitted, with the time transmission started, or discarded
+type tx_result struct {
+	ts  time.Time
+	err error
+}
+
+// tx_event is emitted when a request has been transmitted or discarded
+type tx_event struct {
+	source  gopi.Driver
+	message sensors.Message
+	err     error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - SEND MESSAGE
+
+func (this *mihome) SendMessage(message sensors.Message, options sensors.MiHomeTXOptions) error {
+	this.log.Debug2("<sensors.mihome>SendMessage{ message=%v priority=%v repeat=%v deadline=%v }", message, options.Priority, options.Repeat, options.Deadline)
+
+	if message == nil {
+		return gopi.ErrBadParameter
+	} else if proto := this.ProtoByName(message.Name()); proto == nil {
+		return gopi.ErrBadParameter
+	} else {
+		return this.tx_queue(proto, message, options)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - QUEUE
+
+// tx_queue encodes a message and queues it for transmission. When an
+// identical message is already queued, the queued message takes the
+// higher priority and repeat count and the later deadline instead. When
+// a different message for the same device and parameters is queued, it
+// is superseded and the message is queued after any others. Events are
+// only emitted by the transmit task, so that this can be called by a
+// subscriber
+func (this *mihome) tx_queue(proto sensors.Proto, message sensors.Message, options sensors.MiHomeTXOptions) error {
+	return this.tx_queue_wait(proto, message, options, nil)
+}
+
+// tx_queue_wait queues a message for transmission, where the result is
+// sent to the done channel, which can be nil
+func (this *mihome) tx_queue_wait(proto sensors.Proto, message sensors.Message, options sensors.MiHomeTXOptions, done chan<- tx_result) error {
+	this.log.Debug("<sensors.mihome>TXQueue{ proto=%v message=%v priority=%v }", proto, message, options.Priority)
+
+	// Check parameters and set defaults
+	if options.Priority > sensors.MIHOME_PRIORITY_HIGH {
+		return gopi.ErrBadParameter
+	}
+	if options.Repeat == 0 {
+		options.Repeat = this.repeat
+	}
+	if options.Deadline.IsZero() {
+		options.Deadline = time.Now().Add(this.tx_deadline)
+	}
+
+	// Encode the message
+	encoded := proto.Encode(message)
+	if len(encoded) == 0 {
+		return sensors.ErrMessageCorruption
+	}
+
+	// Coalesce with an identical queued message, or else append
+	// after removing any superseded message
+	this.tx_append(proto, message, encoded, options, done)
+
+	// Signal the transmit task
+	select {
+	case this.tx_signal <- struct{}{}:
+	default:
+	}
+
+	// Success
+	return nil
+}
+
+// tx_append coalesces a message with an identical queued request or
+// else appends it to the queue, moving the requests it supersedes to
+// the superseded requests
+func (this *mihome) tx_append(proto sensors.Proto, message sensors.Message, encoded []byte, options sensors.MiHomeTXOptions, done chan<- tx_result) {
+	this.tx_lock.Lock()
+	defer this.tx_lock.Unlock()
+
+	// Coalesce with an identical queued message
+	if req := this.tx_pending(message); req != nil {
+		this.log.Debug("<sensors.mihome>TXQueue: Coalesced with queued message")
+		if options.Priority > req.priority {
+			req.priority = options.Priority
+		}
+		if options.Repeat > req.repeat {
+			req.repeat = options.Repeat
+		}
+		if options.Deadline.After(req.deadline) {
+			req.deadline = options.Deadline
+		}
+		if done != nil {
+			req.done = append(req.done, done)
+		}
+		return
+	}
+
+	// Remove superseded requests and append
+	requests := make([]*tx_request, 0, len(this.tx_requests)+1)
+	for _, req := range this.tx_requests {
+		if is_same_target(req.message, message) {
+			this.log.Debug("<sensors.mihome>TXQueue: Superseded: %v", req.message)
+			this.tx_superseded = append(this.tx_superseded, req)
+		} else {
+			requests = append(requests, req)
+		}
+	}
+	this.tx_seq++
+	req := &tx_request{
+		proto:    proto,
+		message:  message,
+		encoded:  encoded,
+		priority: options.Priority,
+		repeat:   options.Repeat,
+		deadline: options.Deadline,
+		seq:      this.tx_seq,
+	}
+	if done != nil {
+		req.done = []chan<- tx_result{done}
+	}
+	this.tx_requests = append(requests, req)
+}
+
+// tx_pending returns a queued request for an identical message, which
+// is called with the lock held
+func (this *mihome) tx_pending(message sensors.Message) *tx_request {
+	for _, req := range this.tx_requests {
+		if req.message.Name() == message.Name() && req.message.IsDuplicate(message) {
+			return req
+		}
+	}
+	return nil
+}
+
+// tx_next removes and returns the queued request with the highest
+// priority, which was queued first for requests with equal priority,
+// or nil if there are none. Requests past their deadline and superseded
+// requests are also removed and returned
+func (this *mihome) tx_next() (*tx_request, []*tx_request, []*tx_request) {
+	this.tx_lock.Lock()
+	defer this.tx_lock.Unlock()
+
+	now := time.Now()
+	expired := make([]*tx_request, 0)
+	requests := make([]*tx_request, 0, len(this.tx_requests))
+	for _, req := range this.tx_requests {
+		if now.After(req.deadline) {
+			expired = append(expired, req)
+		} else {
+			requests = append(requests, req)
+		}
+	}
+
+	next := -1
+	for i, req := range requests {
+		if next < 0 || req.priority > requests[next].priority {
+			next = i
+		} else if req.priority == requests[next].priority && req.seq < requests[next].seq {
+			next = i
+		}
+	}
+	superseded := this.tx_superseded
+	this.tx_superseded = nil
+	if next < 0 {
+		this.tx_requests = requests
+		return nil, expired, superseded
+	} else {
+		req := requests[next]
+		this.tx_requests = append(requests[:next], requests[next+1:]...)
+		return req, expired, superseded
+	}
+}
+
+// tx_discard removes all queued and superseded requests and returns them
+func (this *mihome) tx_discard() ([]*tx_request, []*tx_request) {
+	this.tx_lock.Lock()
+	defer this.tx_lock.Unlock()
+	requests, superseded := this.tx_requests, this.tx_superseded
+	this.tx_requests, this.tx_superseded = nil, nil
+	return requests, superseded
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+// transmit sends queued requests until stopped, receiving for the
+// receive window between each transmission so that queued requests
+// do not prevent messages from being received
+func (this *mihome) transmit() {
+	this.log.Debug("<sensors.mihome>transmit: Started")
+	defer close(this.tx_done)
+
+FOR_LOOP:
+	for {
+		select {
+		case <-this.tx_stop:
+			break FOR_LOOP
+		case <-this.tx_signal:
+		}
+		for {
+			req, expired, superseded := this.tx_next()
+			for _, req := range superseded {
+				this.tx_finished(req, time.Time{}, sensors.ErrMessageSuperseded)
+			}
+			for _, req := range expired {
+				this.log.Warn("<sensors.mihome>transmit: Deadline exceeded: %v", req.message)
+				this.tx_finished(req, time.Time{}, gopi.ErrDeadlineExceeded)
+			}
+			if req == nil {
+				break
+			}
+			ts := time.Now()
+			err := this.tx_mode(req)
+			if err != nil {
+				this.log.Warn("<sensors.mihome>transmit: %v", err)
+			}
+			this.tx_finished(req, ts, err)
+
+			// Receive before the next transmission
+			select {
+			case <-this.tx_stop:
+				break FOR_LOOP
+			case <-time.After(this.rx_window):
+			}
+		}
+	}
+
+	// Discard requests which have not been transmitted
+	requests, superseded := this.tx_discard()
+	for _, req := range superseded {
+		this.tx_finished(req, time.Time{}, sensors.ErrMessageSuperseded)
+	}
+	if len(requests) > 0 {
+		this.log.Warn("<sensors.mihome>transmit: Discarding %v queued messages", len(requests))
+		for _, req := range requests {
+			this.tx_finished(req, time.Time{}, gopi.ErrOutOfOrder)
+		}
+	}
+	this.log.Debug("<sensors.mihome>transmit: Ended")
+}
+
+// tx_finished sends the result of a request to the channels waiting for
+// it, then emits an event
+func (this *mihome) tx_finished(req *tx_request, ts time.Time, err error) {
+	for _, done := range req.done {
+		select {
+		case done <- tx_result{ts, err}:
+		default:
+		}
+	}
+	this.Emit(&tx_event{this, req.message, err})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - TARGET
+
+// is_same_target returns true if two messages are for the same device
+// and set the same parameters, so that the later supersedes the earlier
+func is_same_target(a, b sensors.Message) bool {
+	if a.Name() != b.Name() {
+		return false
+	}
+	switch a_ := a.(type) {
+	case sensors.OTMessage:
+		if b_, ok := b.(sensors.OTMessage); ok == false {
+			return false
+		} else if a_.Manufacturer() != b_.Manufacturer() || a_.Product() != b_.Product() || a_.Sensor() != b_.Sensor() {
+			return false
+		} else {
+			return is_same_params(a_.Records(), b_.Records())
+		}
+	case sensors.OOKMessage:
+		if b_, ok := b.(sensors.OOKMessage); ok == false {
+			return false
+		} else {
+			return a_.Addr() == b_.Addr() && a_.Socket() == b_.Socket()
+		}
+	case sensors.LightwaveRFMessage:
+		if b_, ok := b.(sensors.LightwaveRFMessage); ok == false {
+			return false
+		} else {
+			return a_.ID() == b_.ID() && a_.Room() == b_.Room() && a_.Device() == b_.Device()
+		}
+	case sensors.FixedCodeMessage:
+		if b_, ok := b.(sensors.FixedCodeMessage); ok == false {
+			return false
+		} else {
+			return a_.Code() == b_.Code() && a_.Bits() == b_.Bits() && a_.Button() == b_.Button()
+		}
+	default:
+		return a.IsDuplicate(b)
+	}
+}
+
+// is_same_params returns true if two sets of records have the same
+// parameter names
+func is_same_params(a, b []sensors.OTRecord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	params := make(map[sensors.OTParameter]int, len(a))
+	for _, record := range a {
+		params[record.Name()]++
+	}
+	for _, record := range b {
+		if params[record.Name()] == 0 {
+			return false
+		}
+		params[record.Name()]--
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TX EVENT
+
+func (this *tx_event) Name() string {
+	return "tx"
+}
+
+func (this *tx_event) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *tx_event) Message() sensors.Message {
+	return this.message
+}
+
+func (this *tx_event) Err() error {
+	return this.err
+}
+
+func (this *tx_event) String() string {
+	if this.err != nil {
+		return fmt.Sprintf("<sensors.mihome.TXEvent>{ message=%v err=%v }", this.message, this.err)
+	} else {
+		return fmt.Sprintf("<sensors.mihome.TXEvent>{ message=%v }", this.message)
+	}
+}
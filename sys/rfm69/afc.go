@@ -12,6 +12,7 @@ package rfm69
 import (
 	"time"
 
+	"github.com/djthorpe/gopi"
 	"github.com/djthorpe/sensors"
 )
 
@@ -124,3 +125,54 @@ func (this *rfm69) TriggerAFC() error {
 
 	return nil
 }
+
+// Clear AFC and read the value back
+func (this *rfm69) ClearAFC() error {
+	this.log.Debug("<sensors.RFM69.ClearAFC>{}")
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if err := this.setAFCControl(this.afc_mode, false, true, false); err != nil {
+		return err
+	} else if afc, err := this.getAFC(); err != nil {
+		return err
+	} else {
+		this.afc = afc
+	}
+
+	return nil
+}
+
+// Measure the frequency error in Hertz, which needs to be in RX mode
+func (this *rfm69) ReadFEIHertz() (float64, error) {
+	this.log.Debug("<sensors.RFM69.ReadFEIHertz>{}")
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Ensure we're in RX mode or else return "OutOfOrder" message
+	if this.mode != sensors.RFM_MODE_RX {
+		this.log.Debug("Expected mode=%v, got %v", sensors.RFM_MODE_RX, this.mode)
+		return 0, gopi.ErrOutOfOrder
+	}
+
+	// Start FEI measurement
+	if err := this.setAFCControl(this.afc_mode, true, false, false); err != nil {
+		return 0, err
+	}
+
+	// Wait for fei_done bit, and convert the value to hertz
+	if err := wait_for_condition(func() (bool, error) {
+		_, _, fei_done, err := this.getAFCControl()
+		return fei_done, err
+	}, true, time.Millisecond*1000); err != nil {
+		return 0, err
+	} else if fei, err := this.getFEI(); err != nil {
+		return 0, err
+	} else {
+		return to_fstep_hertz(fei), nil
+	}
+}
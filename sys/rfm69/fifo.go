@@ -20,6 +20,19 @@ import (
 	"github.com/djthorpe/sensors"
 )
 
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// FIFO threshold used when writing a payload larger than the FIFO,
+	// which is topped up when the level drops to the threshold
+	RFM_FIFO_THRESHOLD_STREAM = 16
+
+	// Maximum time to wait for the FIFO level to change while a payload
+	// larger than the FIFO is being sent or received
+	RFM_STREAM_TIMEOUT = time.Second
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // READ & CLEAR FIFO
 
@@ -45,67 +58,57 @@ func (this *rfm69) ClearFIFO() error {
 func (this *rfm69) ReadFIFO(ctx context.Context) ([]byte, error) {
 	this.log.Debug("<sensors.RFM69.ReadFIFO>{ }")
 
-	// Mutex lock
-	this.lock.Lock()
-	defer this.lock.Unlock()
-
-	// Check FIFO every 100 milliseconds
-	interval := time.NewTicker(100 * time.Millisecond)
-	defer interval.Stop()
+	// Check FIFO until data is available, releasing the lock while waiting
 	for {
-		select {
-		case <-ctx.Done():
+		if data, err := this.readFIFO(); err != nil {
+			return nil, err
+		} else if data != nil {
+			return data, nil
+		} else if this.wait(ctx) == false {
 			// Context finished without FIFO
 			return nil, nil
-		case <-interval.C:
-			// Check FIFO
-			if fifo_empty, err := this.recvFIFOEmpty(); err != nil {
-				return nil, err
-			} else if fifo_empty {
-				continue
-			} else if data, err := this.recvFIFO(); err != nil {
-				return nil, err
-			} else {
-				return data, nil
-			}
 		}
 	}
 }
 
-func (this *rfm69) ReadPayload(ctx context.Context) ([]byte, bool, error) {
+func (this *rfm69) ReadPayload(ctx context.Context) ([]byte, bool, sensors.RFMSignal, error) {
 	this.log.Debug("<sensors.RFM69.ReadPayload>{ }")
 
-	// Mutex lock
+	// Poll more often when a packet may be larger than the FIFO,
+	// so the FIFO can be drained before it overruns
 	this.lock.Lock()
-	defer this.lock.Unlock()
-
-	// Ensure we're in RX mode or else return "OutOfOrder" message
-	if this.mode != sensors.RFM_MODE_RX {
-		this.log.Debug("Expected mode=%v, got %v", sensors.RFM_MODE_RX, this.mode)
-		return nil, false, gopi.ErrOutOfOrder
-	}
-
-	// Check FIFO every 100 milliseconds
-	interval := time.NewTicker(100 * time.Millisecond)
-	defer interval.Stop()
+	stream := this.packetMax() > RFM_FIFO_SIZE
+	this.lock.Unlock()
+
+	// Check payload until ready, releasing the lock while waiting
+	// for DIO0 or the next poll. A packet larger than the FIFO is
+	// drained into the buffer between polls
+	var buffer []byte
+	timeout := time.Now().Add(RFM_STREAM_TIMEOUT)
 	for {
-		select {
-		case <-ctx.Done():
-			// Context finished without FIFO
-			return nil, false, nil
-		case <-interval.C:
-			// Check Payload
-			if payload_ready, err := this.recvPayloadReady(); err != nil {
-				return nil, false, err
-			} else if payload_ready == false {
-				continue
-			} else if data, err := this.recvFIFO(); err != nil {
-				return nil, false, err
-			} else if crc_ok, err := this.recvCRCOk(); err != nil {
-				return nil, false, err
-			} else {
-				return data, crc_ok, nil
+		received := len(buffer)
+		if data, crc_ok, signal, err := this.readPayload(&buffer); err != nil {
+			return nil, false, sensors.RFMSignal{}, err
+		} else if data != nil {
+			return data, crc_ok, signal, nil
+		} else if len(buffer) != received {
+			timeout = time.Now().Add(RFM_STREAM_TIMEOUT)
+		} else if len(buffer) > 0 && time.Now().After(timeout) {
+			this.log.Warn("sensors.RFM69.ReadPayload: Incomplete packet, received=%v", len(buffer))
+			if err := this.discardStream(); err != nil {
+				return nil, false, sensors.RFMSignal{}, err
 			}
+			buffer = nil
+		}
+		if stream && this.wait_for(ctx, RFM_POLL_INTERVAL_STREAM) == false {
+			// Context finished without FIFO, discarding any partial packet
+			if len(buffer) > 0 {
+				return nil, false, sensors.RFMSignal{}, this.discardStream()
+			}
+			return nil, false, sensors.RFMSignal{}, nil
+		} else if stream == false && this.wait(ctx) == false {
+			// Context finished without FIFO
+			return nil, false, sensors.RFMSignal{}, nil
 		}
 	}
 }
@@ -134,18 +137,29 @@ func (this *rfm69) WritePayload(data []byte, repeat uint, delay time.Duration) e
 		return gopi.ErrOutOfOrder
 	}
 
-	// Set FIFO Threshold to length-1
-	if length := len(data); length == 0 || length > RFM_FIFO_SIZE {
-		this.log.Debug2("sensors.RFM69.WritePayload: data length is %v, expected 0 < length <= %v", length, RFM_FIFO_SIZE)
+	// Set FIFO Threshold to length-1, or when the payload is larger
+	// than the FIFO, to the level at which the FIFO is topped up
+	if length := len(data); length == 0 || length > RFM_PACKET_MAX {
+		this.log.Debug2("sensors.RFM69.WritePayload: data length is %v, expected 0 < length <= %v", length, RFM_PACKET_MAX)
 		return gopi.ErrBadParameter
+	} else if length > RFM_FIFO_SIZE {
+		if err := this.SetFIFOThreshold(RFM_FIFO_THRESHOLD_STREAM); err != nil {
+			return err
+		}
 	} else if err := this.SetFIFOThreshold(uint8(length) - 1); err != nil {
 		return err
 	}
 
 	// Send repeatedly
 	for i := uint(0); i <= repeat; i++ {
-		// Write FIFO
-		if err := this.WriteFIFO(data); err != nil {
+		if len(data) > RFM_FIFO_SIZE {
+			// Write FIFO as it empties
+			if err := this.writeStream(data); err != nil {
+				this.log.Debug("WritePayload: writeStream: %v", err)
+				return err
+			}
+		} else if err := this.WriteFIFO(data); err != nil {
+			// Write FIFO
 			this.log.Debug("WritePayload: WriteFIFO: %v", err)
 			return err
 		}
@@ -167,8 +181,8 @@ func (this *rfm69) WritePayload(data []byte, repeat uint, delay time.Duration) e
 		return err
 	}
 
-	// Wait for Packet sent
-	if err := wait_for_condition(func() (bool, error) {
+	// Wait for Packet sent, which is signalled on DIO0
+	if err := this.wait_for_interrupt(func() (bool, error) {
 		return this.recvPacketSent()
 	}, true, time.Millisecond*1000); err != nil {
 		this.log.Debug("WritePayload: recvPacketSent: %v", err)
@@ -179,6 +193,175 @@ func (this *rfm69) WritePayload(data []byte, repeat uint, delay time.Duration) e
 	return nil
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - READ FIFO
+
+// Return data from the FIFO, or nil if the FIFO is empty
+func (this *rfm69) readFIFO() ([]byte, error) {
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if fifo_empty, err := this.recvFIFOEmpty(); err != nil {
+		return nil, err
+	} else if fifo_empty {
+		return nil, nil
+	} else {
+		return this.recvFIFO()
+	}
+}
+
+// Return payload, CRC flag and signal metadata, or nil if the payload is
+// not ready. When a packet may be larger than the FIFO, the FIFO is drained
+// into the stream buffer while the packet is being received, and the
+// buffer is prepended to the payload. Returns "OutOfOrder" error if not in
+// RX mode
+func (this *rfm69) readPayload(stream *[]byte) ([]byte, bool, sensors.RFMSignal, error) {
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Ensure we're in RX mode or else return "OutOfOrder" message
+	if this.mode != sensors.RFM_MODE_RX {
+		this.log.Debug("Expected mode=%v, got %v", sensors.RFM_MODE_RX, this.mode)
+		return nil, false, sensors.RFMSignal{}, gopi.ErrOutOfOrder
+	}
+
+	// When a packet is being received, start measuring the frequency
+	// error and drain the FIFO when a packet may be larger than the FIFO
+	if payload_ready, err := this.recvPayloadReady(); err != nil {
+		return nil, false, sensors.RFMSignal{}, err
+	} else if payload_ready == false {
+		if err := this.startFEI(); err != nil {
+			return nil, false, sensors.RFMSignal{}, err
+		} else if this.packetMax() > RFM_FIFO_SIZE {
+			if err := this.recvStream(stream); err != nil {
+				return nil, false, sensors.RFMSignal{}, err
+			}
+		}
+		return nil, false, sensors.RFMSignal{}, nil
+	}
+
+	// Read the remainder of the payload. CrcOk is cleared when the
+	// FIFO is empty, so is read first
+	if signal, err := this.getSignal(); err != nil {
+		return nil, false, sensors.RFMSignal{}, err
+	} else if crc_ok, err := this.recvCRCOk(); err != nil {
+		return nil, false, sensors.RFMSignal{}, err
+	} else if data, err := this.recvFIFO(); err != nil {
+		return nil, false, sensors.RFMSignal{}, err
+	} else {
+		data = append(*stream, data...)
+		*stream = nil
+		return data, crc_ok, signal, nil
+	}
+}
+
+// recvStream drains the bytes in the FIFO into the buffer while a packet
+// larger than the FIFO is being received, reading at most a FIFO's worth
+// so the lock is held briefly. The last byte is left in the FIFO, since
+// PayloadReady and CrcOk are cleared when it's empty
+func (this *rfm69) recvStream(buffer *[]byte) error {
+	for i := 0; i < RFM_FIFO_SIZE; i++ {
+		if length := this.packetLength(*buffer); length != 0 && len(*buffer) >= length-1 {
+			return nil
+		} else if fifo_empty, err := this.recvFIFOEmpty(); err != nil {
+			return err
+		} else if fifo_empty {
+			return nil
+		} else if value, err := this.readreg_uint8(RFM_REG_FIFO); err != nil {
+			return err
+		} else {
+			*buffer = append(*buffer, value)
+		}
+	}
+	return nil
+}
+
+// discardStream clears the FIFO when a packet larger than the FIFO
+// is incomplete, or when reading it is cancelled
+func (this *rfm69) discardStream() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.setIRQFlags2()
+}
+
+// packetMax returns the maximum length of a received packet, including
+// the length byte in variable length format, or zero if unlimited
+func (this *rfm69) packetMax() int {
+	if this.packet_format == sensors.RFM_PACKET_FORMAT_VARIABLE {
+		return int(this.payload_size) + 1
+	} else {
+		return int(this.payload_size)
+	}
+}
+
+// packetLength returns the length of a packet from the first bytes of
+// the packet, or zero if the length is not yet known
+func (this *rfm69) packetLength(data []byte) int {
+	if this.packet_format != sensors.RFM_PACKET_FORMAT_VARIABLE {
+		return int(this.payload_size)
+	} else if len(data) == 0 {
+		return 0
+	} else {
+		return int(data[0]) + 1
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - WRITE FIFO
+
+// writeStream writes a payload larger than the FIFO, topping up the
+// FIFO whenever the level drops to the FIFO threshold
+func (this *rfm69) writeStream(data []byte) error {
+	for len(data) > 0 {
+		// Wait for the level to drop to the threshold
+		if err := wait_for_stream(func() (bool, error) {
+			this.lock.Lock()
+			defer this.lock.Unlock()
+			return this.irqFIFOLevel()
+		}, false); err != nil {
+			return err
+		}
+
+		// Fill the FIFO when it's empty, or else top it up
+		size := RFM_FIFO_SIZE - int(this.fifo_threshold)
+		this.lock.Lock()
+		fifo_empty, err := this.recvFIFOEmpty()
+		this.lock.Unlock()
+		if err != nil {
+			return err
+		} else if fifo_empty {
+			size = RFM_FIFO_SIZE
+		}
+		if size > len(data) {
+			size = len(data)
+		}
+		if err := this.WriteFIFO(data[:size]); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+
+	// Success
+	return nil
+}
+
+// wait_for_stream polls a condition at the stream interval
+func wait_for_stream(callback func() (bool, error), condition bool) error {
+	timeout := time.Now().Add(RFM_STREAM_TIMEOUT)
+	for {
+		if r, err := callback(); err != nil {
+			return err
+		} else if r == condition {
+			return nil
+		} else if time.Now().After(timeout) {
+			return sensors.ErrDeviceTimeout
+		}
+		time.Sleep(RFM_POLL_INTERVAL_STREAM)
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // FIFO THRESHOLD
 
@@ -215,3 +398,77 @@ func (this *rfm69) SetFIFOThreshold(fifo_threshold uint8) error {
 	// Success
 	return nil
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// FIFO FILL CONDITION
+
+func (this *rfm69) FIFOFillCondition() bool {
+	return this.fifo_fill_condition
+}
+
+func (this *rfm69) SetFIFOFillCondition(fifo_fill_condition bool) error {
+	this.log.Debug("<sensors.RFM69.SetFIFOFillCondition>{ fifo_fill_condition=%v }", fifo_fill_condition)
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Write
+	if err := this.setSyncConfig(this.sync_on, fifo_fill_condition, this.sync_size, this.sync_tol); err != nil {
+		return err
+	}
+
+	// Read
+	if _, fifo_fill_condition_read, _, _, err := this.getSyncConfig(); err != nil {
+		return err
+	} else if fifo_fill_condition_read != fifo_fill_condition {
+		this.log.Debug2("SetFIFOFillCondition expecting fifo_fill_condition=%v, got=%v", fifo_fill_condition, fifo_fill_condition_read)
+		return sensors.ErrUnexpectedResponse
+	} else {
+		this.fifo_fill_condition = fifo_fill_condition
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TX START CONDITION
+
+func (this *rfm69) TXStart() sensors.RFMTXStart {
+	return this.tx_start
+}
+
+func (this *rfm69) SetTXStart(tx_start sensors.RFMTXStart) error {
+	this.log.Debug("<sensors.RFM69.SetTXStart>{ tx_start=%v }", tx_start)
+
+	// Check parameter
+	if tx_start > sensors.RFM_TXSTART_MAX {
+		return gopi.ErrBadParameter
+	}
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Write
+	if err := this.setFIFOThreshold(tx_start, this.fifo_threshold); err != nil {
+		return err
+	}
+
+	// Read
+	if tx_start_read, fifo_threshold_read, err := this.getFIFOThreshold(); err != nil {
+		return err
+	} else if tx_start_read != tx_start {
+		this.log.Debug2("SetTXStart expecting tx_start=%v, got=%v", tx_start, tx_start_read)
+		return sensors.ErrUnexpectedResponse
+	} else if fifo_threshold_read != this.fifo_threshold {
+		this.log.Debug2("SetTXStart expecting fifo_threshold=%v, got=%v", this.fifo_threshold, fifo_threshold_read)
+		return sensors.ErrUnexpectedResponse
+	} else {
+		this.tx_start = tx_start
+	}
+
+	// Success
+	return nil
+}
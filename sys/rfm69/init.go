@@ -10,6 +10,9 @@
 package rfm69
 
 import (
+	"fmt"
+
+	// Frameworks
 	gopi "github.com/djthorpe/gopi"
 )
 
@@ -20,12 +23,28 @@ func init() {
 	// Register RFM69 communication through SPI
 	gopi.RegisterModule(gopi.Module{
 		Name:     "sensors/rfm69/spi",
-		Requires: []string{"spi"},
+		Requires: []string{"gpio", "spi"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rfm69.dio0", 0, "DIO0 Interrupt Pin (Logical), or zero to poll")
+			config.AppFlags.FlagBool("rfm69.hw", false, "High power module (RFM69HW)")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
-			return gopi.Open(RFM69{
-				SPI: app.ModuleInstance("spi").(gopi.SPI),
-			}, app.Logger)
+			high_power, _ := app.AppFlags.GetBool("rfm69.hw")
+			config := RFM69{
+				SPI:       app.ModuleInstance("spi").(gopi.SPI),
+				PinDIO0:   gopi.GPIO_PIN_NONE,
+				HighPower: high_power,
+			}
+			if dio0, _ := app.AppFlags.GetUint("rfm69.dio0"); dio0 > 0 && dio0 < uint(gopi.GPIO_PIN_NONE) {
+				if gpio, ok := app.ModuleInstance("gpio").(gopi.GPIO); ok == false || gpio == nil {
+					return nil, fmt.Errorf("Missing or invalid GPIO module for -rfm69.dio0")
+				} else {
+					config.GPIO = gpio
+					config.PinDIO0 = gopi.GPIOPin(dio0)
+				}
+			}
+			return gopi.Open(config, app.Logger)
 		},
 	})
 }
@@ -0,0 +1,144 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69
+
+import (
+	"context"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Interval between checking IRQ flags when DIO0 is not connected
+	RFM_POLL_INTERVAL = 100 * time.Millisecond
+
+	// Interval between checking IRQ flags when DIO0 is connected,
+	// in case an edge is missed
+	RFM_POLL_INTERVAL_DIO0 = 500 * time.Millisecond
+
+	// Interval between checking IRQ flags when a packet may be larger
+	// than the FIFO, so the FIFO can be drained or topped up in time
+	RFM_POLL_INTERVAL_STREAM = time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// WATCH DIO0
+
+// Watch for rising edges on DIO0, and signal waiting readers and writers
+func (this *rfm69) watchDIO0() error {
+	this.gpio.SetPinMode(this.pin_dio0, gopi.GPIO_INPUT)
+	if err := this.gpio.Watch(this.pin_dio0, gopi.GPIO_EDGE_RISING); err != nil {
+		return err
+	}
+
+	this.dio0 = make(chan struct{}, 1)
+	this.events = this.gpio.Subscribe()
+	go func(events <-chan gopi.Event) {
+		for evt := range events {
+			if evt_, ok := evt.(gopi.GPIOEvent); ok == false || evt_.Pin() != this.pin_dio0 {
+				continue
+			} else if evt_.Edge() != gopi.GPIO_EDGE_RISING {
+				continue
+			}
+			// Signal without blocking, one signal is enough to wake
+			select {
+			case this.dio0 <- struct{}{}:
+				break
+			default:
+				break
+			}
+		}
+	}(this.events)
+
+	// Success
+	return nil
+}
+
+// Stop watching for edges on DIO0
+func (this *rfm69) unwatchDIO0() error {
+	if this.events == nil {
+		return nil
+	}
+	this.gpio.Unsubscribe(this.events)
+	this.events = nil
+	return this.gpio.Watch(this.pin_dio0, gopi.GPIO_EDGE_NONE)
+}
+
+// Map DIO0 to PayloadReady in RX mode and PacketSent otherwise
+func (this *rfm69) setDIO0ForMode(mode sensors.RFMMode) error {
+	mapping := RFM_DIO0_PACKETSENT
+	if mode == sensors.RFM_MODE_RX {
+		mapping = RFM_DIO0_PAYLOADREADY
+	}
+	if err := this.setDIO0Mapping(mapping); err != nil {
+		return err
+	} else if mapping_read, err := this.getDIO0Mapping(); err != nil {
+		return err
+	} else if mapping_read != mapping {
+		this.log.Debug2("setDIO0ForMode expecting mapping=%v, got=%v", mapping, mapping_read)
+		return sensors.ErrUnexpectedResponse
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WAIT
+
+// wait blocks until DIO0 rises or the poll interval has elapsed, and
+// returns false if the context is done. The lock should not be held
+func (this *rfm69) wait(ctx context.Context) bool {
+	if this.dio0 != nil {
+		return this.wait_for(ctx, RFM_POLL_INTERVAL_DIO0)
+	} else {
+		return this.wait_for(ctx, RFM_POLL_INTERVAL)
+	}
+}
+
+// wait_for blocks until DIO0 rises or the interval has elapsed, and
+// returns false if the context is done. The lock should not be held
+func (this *rfm69) wait_for(ctx context.Context, interval time.Duration) bool {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-this.dio0:
+		return true
+	case <-timer.C:
+		return true
+	}
+}
+
+// wait_for_interrupt calls the callback with the lock held until it returns
+// the condition, waking when DIO0 rises or the poll interval has elapsed
+func (this *rfm69) wait_for_interrupt(callback func() (bool, error), condition bool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		this.lock.Lock()
+		r, err := callback()
+		this.lock.Unlock()
+		if err != nil {
+			return err
+		} else if r == condition {
+			return nil
+		} else if this.wait(ctx) == false {
+			return sensors.ErrDeviceTimeout
+		}
+	}
+}
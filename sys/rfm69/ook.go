@@ -0,0 +1,66 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// OOK Demodulator Settings
+
+package rfm69
+
+import (
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+func (this *rfm69) OOKThresholdType() sensors.RFMOOKThresholdType {
+	return this.ook_threshold_type
+}
+
+func (this *rfm69) OOKThresholdStep() sensors.RFMOOKThresholdStep {
+	return this.ook_threshold_step
+}
+
+func (this *rfm69) OOKThresholdDecrement() sensors.RFMOOKThresholdDecrement {
+	return this.ook_threshold_dec
+}
+
+func (this *rfm69) SetOOK(threshold_type sensors.RFMOOKThresholdType, threshold_step sensors.RFMOOKThresholdStep, threshold_dec sensors.RFMOOKThresholdDecrement) error {
+	this.log.Debug("<sensors.RFM69.SetOOK{ threshold_type=%v threshold_step=%v threshold_dec=%v }", threshold_type, threshold_step, threshold_dec)
+
+	// Check parameters
+	if threshold_type > sensors.RFM_OOK_THRESHOLD_MAX || threshold_step > sensors.RFM_OOK_THRESHOLD_STEP_MAX || threshold_dec > sensors.RFM_OOK_THRESHOLD_DEC_MAX {
+		return gopi.ErrBadParameter
+	}
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Write
+	if err := this.setRegOOKPeak(threshold_type, threshold_step, threshold_dec); err != nil {
+		return err
+	}
+
+	// Read
+	if type_read, step_read, dec_read, err := this.getRegOOKPeak(); err != nil {
+		return err
+	} else if type_read != threshold_type {
+		this.log.Debug2("SetOOK expecting threshold_type=%v, got=%v", threshold_type, type_read)
+		return sensors.ErrUnexpectedResponse
+	} else if step_read != threshold_step {
+		this.log.Debug2("SetOOK expecting threshold_step=%v, got=%v", threshold_step, step_read)
+		return sensors.ErrUnexpectedResponse
+	} else if dec_read != threshold_dec {
+		this.log.Debug2("SetOOK expecting threshold_dec=%v, got=%v", threshold_dec, dec_read)
+		return sensors.ErrUnexpectedResponse
+	} else {
+		this.ook_threshold_type = threshold_type
+		this.ook_threshold_step = threshold_step
+		this.ook_threshold_dec = threshold_dec
+	}
+	return nil
+}
@@ -24,20 +24,31 @@ type RFM69 struct {
 
 	// Device speed
 	Speed uint32
+
+	// Optional GPIO driver and pin connected to DIO0, which is used
+	// to wake on PayloadReady and PacketSent rather than polling
+	GPIO    gopi.GPIO
+	PinDIO0 gopi.GPIOPin
+
+	// High power module (RFM69HW) which uses PA1 and PA2 rather than PA0
+	HighPower bool
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // OPEN AND CLOSE
 
 func (config RFM69) Open(log gopi.Logger) (gopi.Driver, error) {
-	log.Debug("<sensors.RFM69.Open>{ spi=%v speed=%v }", config.SPI, config.Speed)
+	log.Debug("<sensors.RFM69.Open>{ spi=%v speed=%v gpio=%v dio0=%v high_power=%v }", config.SPI, config.Speed, config.GPIO, config.PinDIO0, config.HighPower)
 
 	this := new(rfm69)
 	this.spi = config.SPI
 	this.log = log
+	this.high_power = config.HighPower
 
 	if this.spi == nil {
 		return nil, gopi.ErrBadParameter
+	} else if config.GPIO != nil && config.PinDIO0 == gopi.GPIO_PIN_NONE {
+		return nil, gopi.ErrBadParameter
 	}
 
 	// Set SPI mode
@@ -70,6 +81,18 @@ func (config RFM69) Open(log gopi.Logger) (gopi.Driver, error) {
 		return nil, err
 	}
 
+	// Watch DIO0 and map it for the current mode
+	if config.GPIO != nil {
+		this.gpio = config.GPIO
+		this.pin_dio0 = config.PinDIO0
+		if err := this.watchDIO0(); err != nil {
+			return nil, err
+		} else if err := this.setDIO0ForMode(this.mode); err != nil {
+			this.unwatchDIO0()
+			return nil, err
+		}
+	}
+
 	// Return success
 	return this, nil
 }
@@ -81,8 +104,14 @@ func (this *rfm69) Close() error {
 	this.lock.Lock()
 	defer this.lock.Unlock()
 
-	// Blank out SPI value
+	// Stop watching DIO0
+	if err := this.unwatchDIO0(); err != nil {
+		return err
+	}
+
+	// Blank out SPI and GPIO values
 	this.spi = nil
+	this.gpio = nil
 
 	return nil
 }
@@ -157,6 +186,25 @@ func (this *rfm69) ResetRegisters() error {
 		this.rxbw_cutoff = cutoff
 	}
 
+	// Get output power
+	if pa, output_power, err := this.getRegPALevel(); err != nil {
+		return err
+	} else if boost, err := this.getRegTestPA(); err != nil {
+		return err
+	} else {
+		this.pa_boost = boost
+		this.output_power = outputPowerForPA(pa, output_power, boost)
+	}
+
+	// Get OOK parameters
+	if threshold_type, threshold_step, threshold_dec, err := this.getRegOOKPeak(); err != nil {
+		return err
+	} else {
+		this.ook_threshold_type = threshold_type
+		this.ook_threshold_step = threshold_step
+		this.ook_threshold_dec = threshold_dec
+	}
+
 	// Get Node address and Broadcast address
 	if node_address, err := this.getNodeAddress(); err != nil {
 		return err
@@ -0,0 +1,49 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69
+
+import (
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CALIBRATE RC OSCILLATOR
+
+// Calibrate the RC oscillator, which needs to be in standby mode
+func (this *rfm69) CalibrateRCOsc() error {
+	this.log.Debug("<sensors.RFM69.CalibrateRCOsc>{ }")
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Ensure we're in standby mode or else return "OutOfOrder" message
+	if this.mode != sensors.RFM_MODE_STDBY {
+		this.log.Debug("Expected mode=%v, got %v", sensors.RFM_MODE_STDBY, this.mode)
+		return gopi.ErrOutOfOrder
+	}
+
+	// Trigger calibration
+	if err := this.setRegOsc1CalStart(); err != nil {
+		return err
+	}
+
+	// Wait for done
+	if err := wait_for_condition(this.getRegOsc1CalDone, true, time.Millisecond*100); err != nil {
+		return err
+	}
+
+	// Success
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Output power range for RFM69W, which uses PA0
+	RFM_OUTPUT_POWER_MIN = -18 // dBm
+	RFM_OUTPUT_POWER_MAX = 13  // dBm
+
+	// Output power range for RFM69HW, which uses PA1 up to +13dBm,
+	// PA1 and PA2 up to +17dBm and the high power settings above that
+	RFM_OUTPUT_POWER_HW_MIN = -2 // dBm
+	RFM_OUTPUT_POWER_HW_PA1 = 13 // dBm
+	RFM_OUTPUT_POWER_HW_PA2 = 17 // dBm
+	RFM_OUTPUT_POWER_HW_MAX = 20 // dBm
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OUTPUT POWER
+
+// Return output power in dBm
+func (this *rfm69) OutputPower() int8 {
+	return this.output_power
+}
+
+// Set output power in dBm. On the RFM69HW the over current protection is
+// switched off above +17dBm and the high power settings are used only
+// in TX mode
+func (this *rfm69) SetOutputPower(dbm int8) error {
+	this.log.Debug("<sensors.RFM69.SetOutputPower>{ dbm=%v high_power=%v }", dbm, this.high_power)
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	pa, output_power, boost, err := this.paForOutputPower(dbm)
+	if err != nil {
+		return err
+	}
+
+	// Write
+	if err := this.setRegPALevel(pa, output_power); err != nil {
+		return err
+	} else if err := this.setRegOCP(boost == false); err != nil {
+		return err
+	}
+
+	// Read
+	if pa_read, output_power_read, err := this.getRegPALevel(); err != nil {
+		return err
+	} else if pa_read != pa {
+		this.log.Debug2("SetOutputPower expecting pa=0x%02X, got=0x%02X", pa, pa_read)
+		return sensors.ErrUnexpectedResponse
+	} else if output_power_read != output_power {
+		this.log.Debug2("SetOutputPower expecting output_power=%v, got=%v", output_power, output_power_read)
+		return sensors.ErrUnexpectedResponse
+	} else if ocp_on_read, err := this.getRegOCP(); err != nil {
+		return err
+	} else if ocp_on_read == boost {
+		this.log.Debug2("SetOutputPower expecting ocp_on=%v, got=%v", boost == false, ocp_on_read)
+		return sensors.ErrUnexpectedResponse
+	} else {
+		this.output_power = dbm
+		this.pa_boost = boost
+	}
+
+	// Update the high power settings when already in TX mode
+	return this.setTestPAForMode(this.mode)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// paForOutputPower returns the power amplifiers, the output power register
+// value and whether the high power settings are required for a power in dBm
+func (this *rfm69) paForOutputPower(dbm int8) (uint8, uint8, bool, error) {
+	switch {
+	case this.high_power == false:
+		if dbm < RFM_OUTPUT_POWER_MIN || dbm > RFM_OUTPUT_POWER_MAX {
+			return 0, 0, false, gopi.ErrBadParameter
+		}
+		return RFM_PALEVEL_PA0, uint8(dbm - RFM_OUTPUT_POWER_MIN), false, nil
+	case dbm < RFM_OUTPUT_POWER_HW_MIN || dbm > RFM_OUTPUT_POWER_HW_MAX:
+		return 0, 0, false, gopi.ErrBadParameter
+	case dbm <= RFM_OUTPUT_POWER_HW_PA1:
+		return RFM_PALEVEL_PA1, uint8(dbm + 18), false, nil
+	case dbm <= RFM_OUTPUT_POWER_HW_PA2:
+		return RFM_PALEVEL_PA1 | RFM_PALEVEL_PA2, uint8(dbm + 14), false, nil
+	default:
+		return RFM_PALEVEL_PA1 | RFM_PALEVEL_PA2, uint8(dbm + 11), true, nil
+	}
+}
+
+// outputPowerForPA returns the power in dBm for the power amplifiers,
+// output power register value and high power settings
+func outputPowerForPA(pa, output_power uint8, boost bool) int8 {
+	switch {
+	case pa&RFM_PALEVEL_PA2 != 0 && boost:
+		return int8(output_power) - 11
+	case pa&RFM_PALEVEL_PA2 != 0:
+		return int8(output_power) - 14
+	default:
+		return int8(output_power) - 18
+	}
+}
+
+// setTestPAForMode enables the high power settings when required in TX
+// mode, and disables them otherwise since they must not be used in RX mode
+func (this *rfm69) setTestPAForMode(mode sensors.RFMMode) error {
+	// RFM69W has no high power settings
+	if this.high_power == false {
+		return nil
+	}
+
+	boost := this.pa_boost && mode == sensors.RFM_MODE_TX
+	if err := this.setRegTestPA(boost); err != nil {
+		return err
+	} else if boost_read, err := this.getRegTestPA(); err != nil {
+		return err
+	} else if boost_read != boost {
+		this.log.Debug2("setTestPAForMode expecting boost=%v, got=%v", boost, boost_read)
+		return sensors.ErrUnexpectedResponse
+	}
+
+	// Success
+	return nil
+}
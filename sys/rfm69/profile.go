@@ -0,0 +1,199 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69
+
+import (
+	"encoding"
+	"encoding/hex"
+	"math"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PROFILE
+
+// Profile returns the current register settings
+func (this *rfm69) Profile() sensors.RFMProfile {
+	output_power := this.OutputPower()
+	return sensors.RFMProfile{
+		Modulation:            this.Modulation(),
+		DataMode:              this.DataMode(),
+		Sequencer:             this.SequencerEnabled(),
+		Bitrate:               this.Bitrate(),
+		FreqCarrier:           this.FreqCarrier(),
+		FreqDeviation:         this.FreqDeviation(),
+		OutputPower:           &output_power,
+		AFCMode:               this.AFCMode(),
+		AFCRoutine:            this.AFCRoutine(),
+		LNAImpedance:          this.LNAImpedance(),
+		LNAGain:               this.LNAGain(),
+		RXFilterFrequency:     this.RXFilterFrequency(),
+		RXFilterCutoff:        this.RXFilterCutoff(),
+		PacketFormat:          this.PacketFormat(),
+		PacketCoding:          this.PacketCoding(),
+		PacketFilter:          this.PacketFilter(),
+		PacketCRC:             this.PacketCRC(),
+		PreambleSize:          this.PreambleSize(),
+		PayloadSize:           this.PayloadSize(),
+		NodeAddress:           this.NodeAddress(),
+		BroadcastAddress:      this.BroadcastAddress(),
+		SyncWord:              strings.ToUpper(hex.EncodeToString(this.SyncWord())),
+		SyncTolerance:         this.SyncTolerance(),
+		AESKey:                strings.ToUpper(hex.EncodeToString(this.AESKey())),
+		FIFOThreshold:         this.FIFOThreshold(),
+		FIFOFillCondition:     this.FIFOFillCondition(),
+		TXStart:               this.TXStart(),
+		OOKThresholdType:      this.OOKThresholdType(),
+		OOKThresholdStep:      this.OOKThresholdStep(),
+		OOKThresholdDecrement: this.OOKThresholdDecrement(),
+	}
+}
+
+// ApplyProfile validates the profile and returns gopi.ErrBadParameter
+// without writing any registers if it's invalid, or else writes the
+// register settings
+func (this *rfm69) ApplyProfile(profile sensors.RFMProfile) error {
+	this.log.Debug("<sensors.RFM69.ApplyProfile>{ name=%v }", profile.Name)
+
+	sync_word, aes_key, err := this.validateProfile(profile)
+	if err != nil {
+		return err
+	}
+
+	// Write registers
+	if err := this.SetModulation(profile.Modulation); err != nil {
+		return err
+	} else if err := this.SetSequencer(profile.Sequencer); err != nil {
+		return err
+	} else if err := this.SetBitrate(profile.Bitrate); err != nil {
+		return err
+	} else if err := this.SetFreqCarrier(profile.FreqCarrier); err != nil {
+		return err
+	} else if err := this.SetFreqDeviation(profile.FreqDeviation); err != nil {
+		return err
+	} else if err := this.SetAFCMode(profile.AFCMode); err != nil {
+		return err
+	} else if err := this.SetAFCRoutine(profile.AFCRoutine); err != nil {
+		return err
+	} else if err := this.SetLNA(profile.LNAImpedance, profile.LNAGain); err != nil {
+		return err
+	} else if err := this.SetRXFilter(profile.RXFilterFrequency, profile.RXFilterCutoff); err != nil {
+		return err
+	} else if err := this.SetDataMode(profile.DataMode); err != nil {
+		return err
+	} else if err := this.SetPacketFormat(profile.PacketFormat); err != nil {
+		return err
+	} else if err := this.SetPacketCoding(profile.PacketCoding); err != nil {
+		return err
+	} else if err := this.SetPacketFilter(profile.PacketFilter); err != nil {
+		return err
+	} else if err := this.SetPacketCRC(profile.PacketCRC); err != nil {
+		return err
+	} else if err := this.SetPreambleSize(profile.PreambleSize); err != nil {
+		return err
+	} else if err := this.SetPayloadSize(profile.PayloadSize); err != nil {
+		return err
+	} else if err := this.SetSyncWord(sync_word); err != nil {
+		return err
+	} else if err := this.SetSyncTolerance(profile.SyncTolerance); err != nil {
+		return err
+	} else if err := this.SetFIFOFillCondition(profile.FIFOFillCondition); err != nil {
+		return err
+	} else if err := this.SetNodeAddress(profile.NodeAddress); err != nil {
+		return err
+	} else if err := this.SetBroadcastAddress(profile.BroadcastAddress); err != nil {
+		return err
+	} else if err := this.SetAESKey(aes_key); err != nil {
+		return err
+	} else if err := this.SetFIFOThreshold(profile.FIFOThreshold); err != nil {
+		return err
+	} else if err := this.SetTXStart(profile.TXStart); err != nil {
+		return err
+	} else if err := this.SetOOK(profile.OOKThresholdType, profile.OOKThresholdStep, profile.OOKThresholdDecrement); err != nil {
+		return err
+	}
+
+	// Output power is optional
+	if profile.OutputPower != nil {
+		if err := this.SetOutputPower(*profile.OutputPower); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// validateProfile checks each value in a profile and returns the
+// decoded sync word and AES key, which are nil when empty
+func (this *rfm69) validateProfile(profile sensors.RFMProfile) ([]byte, []byte, error) {
+	// Values which have names
+	for _, value := range []encoding.TextMarshaler{
+		profile.Modulation, profile.DataMode, profile.AFCMode, profile.AFCRoutine,
+		profile.LNAImpedance, profile.LNAGain, profile.RXFilterFrequency, profile.RXFilterCutoff,
+		profile.PacketFormat, profile.PacketCoding, profile.PacketFilter, profile.PacketCRC,
+		profile.TXStart, profile.OOKThresholdType, profile.OOKThresholdStep, profile.OOKThresholdDecrement,
+	} {
+		if _, err := value.MarshalText(); err != nil {
+			this.log.Debug2("ApplyProfile: %v", err)
+			return nil, nil, gopi.ErrBadParameter
+		}
+	}
+
+	// Numeric values
+	if profile.Bitrate < RFM_BITRATE_MIN || profile.Bitrate > RFM_BITRATE_MAX {
+		this.log.Debug2("ApplyProfile: Invalid bitrate=%v", profile.Bitrate)
+		return nil, nil, gopi.ErrBadParameter
+	} else if math.Ceil(float64(profile.FreqCarrier)/float64(RFM_FSTEP_HZ)) > RFM_FRF_MAX {
+		this.log.Debug2("ApplyProfile: Invalid freq_carrier=%v", profile.FreqCarrier)
+		return nil, nil, gopi.ErrBadParameter
+	} else if math.Ceil(float64(profile.FreqDeviation)/float64(RFM_FSTEP_HZ)) > RFM_FDEV_MAX {
+		this.log.Debug2("ApplyProfile: Invalid freq_deviation=%v", profile.FreqDeviation)
+		return nil, nil, gopi.ErrBadParameter
+	} else if profile.SyncTolerance > 7 {
+		this.log.Debug2("ApplyProfile: Invalid sync_tolerance=%v", profile.SyncTolerance)
+		return nil, nil, gopi.ErrBadParameter
+	} else if profile.FIFOThreshold > 0x7F {
+		this.log.Debug2("ApplyProfile: Invalid fifo_threshold=%v", profile.FIFOThreshold)
+		return nil, nil, gopi.ErrBadParameter
+	} else if profile.OutputPower != nil {
+		if _, _, _, err := this.paForOutputPower(*profile.OutputPower); err != nil {
+			this.log.Debug2("ApplyProfile: Invalid output_power=%v", *profile.OutputPower)
+			return nil, nil, err
+		}
+	}
+
+	// Sync word is up to eight non-zero bytes, and the AES key is
+	// sixteen bytes
+	sync_word, err := hex.DecodeString(profile.SyncWord)
+	if err != nil || len(sync_word) > RFM_SYNCWORD_BYTES || matches_byte(sync_word, 0x00) {
+		this.log.Debug2("ApplyProfile: Invalid sync_word=%v", profile.SyncWord)
+		return nil, nil, gopi.ErrBadParameter
+	} else if len(sync_word) == 0 {
+		sync_word = nil
+	}
+	aes_key, err := hex.DecodeString(profile.AESKey)
+	if err != nil || (len(aes_key) != 0 && len(aes_key) != RFM_AESKEY_BYTES) {
+		this.log.Debug2("ApplyProfile: Invalid aes_key")
+		return nil, nil, gopi.ErrBadParameter
+	} else if len(aes_key) == 0 {
+		aes_key = nil
+	}
+
+	// Success
+	return sync_word, aes_key, nil
+}
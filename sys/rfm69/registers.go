@@ -134,6 +134,32 @@ const (
 	RFM_IRQFLAGS2_FIFOFULL     uint8 = 0x80
 )
 
+const (
+	// Power amplifiers and output power in RFM_REG_PALEVEL
+	RFM_PALEVEL_PA0         uint8 = 0x80
+	RFM_PALEVEL_PA1         uint8 = 0x40
+	RFM_PALEVEL_PA2         uint8 = 0x20
+	RFM_PALEVEL_OUTPUTPOWER uint8 = 0x1F
+
+	// Over current protection enable and 95mA trim in RFM_REG_OCP
+	RFM_OCP_ON        uint8 = 0x10
+	RFM_OCP_TRIM_95MA uint8 = 0x0A
+
+	// Normal and +20dBm settings for RFM_REG_TESTPA1 and RFM_REG_TESTPA2
+	RFM_TESTPA1_NORMAL uint8 = 0x55
+	RFM_TESTPA1_BOOST  uint8 = 0x5D
+	RFM_TESTPA2_NORMAL uint8 = 0x70
+	RFM_TESTPA2_BOOST  uint8 = 0x7C
+)
+
+const (
+	// DIO0 mapping in packet mode, which is CrcOk in RX mode and
+	// PacketSent in TX mode for RFM_DIO0_PACKETSENT and PayloadReady
+	// in RX mode for RFM_DIO0_PAYLOADREADY
+	RFM_DIO0_PACKETSENT   uint8 = 0x00
+	RFM_DIO0_PAYLOADREADY uint8 = 0x01
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // IRQ FLAGS
 
@@ -596,6 +622,14 @@ func (this *rfm69) recvPayloadReady() (bool, error) {
 	}
 }
 
+func (this *rfm69) recvSyncAddressMatch() (bool, error) {
+	if sync_match, err := this.getIRQFlags1(RFM_IRQFLAGS1_SYNCADDRESSMATCH); err != nil {
+		return false, err
+	} else {
+		return sync_match == RFM_IRQFLAGS1_SYNCADDRESSMATCH, nil
+	}
+}
+
 func (this *rfm69) recvPacketSent() (bool, error) {
 	if packet_sent, err := this.getIRQFlags2(RFM_IRQFLAGS2_PACKETSENT); err != nil {
 		return false, err
@@ -718,3 +752,141 @@ func (this *rfm69) setRegRXBW(frequency sensors.RFMRXBWFrequency, cutoff sensors
 		uint8(frequency&sensors.RFM_RXBW_FREQUENCY_MAX) | uint8(cutoff&sensors.RFM_RXBW_CUTOFF_MAX)<<5
 	return this.writereg_uint8(RFM_REG_RXBW, value)
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM_REG_DIOMAPPING1
+
+// Read DIO0 mapping from RFM_REG_DIOMAPPING1 register
+func (this *rfm69) getDIO0Mapping() (uint8, error) {
+	if value, err := this.readreg_uint8(RFM_REG_DIOMAPPING1); err != nil {
+		return 0, err
+	} else {
+		return (value >> 6) & 0x03, nil
+	}
+}
+
+// Write DIO0 mapping to RFM_REG_DIOMAPPING1 register, leaving
+// mappings for DIO1 to DIO3 unchanged
+func (this *rfm69) setDIO0Mapping(mapping uint8) error {
+	if value, err := this.readreg_uint8(RFM_REG_DIOMAPPING1); err != nil {
+		return err
+	} else {
+		return this.writereg_uint8(RFM_REG_DIOMAPPING1, value&0x3F|(mapping&0x03)<<6)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM_REG_OOKPEAK
+
+// Read OOK threshold type, step and decrement
+func (this *rfm69) getRegOOKPeak() (sensors.RFMOOKThresholdType, sensors.RFMOOKThresholdStep, sensors.RFMOOKThresholdDecrement, error) {
+	if value, err := this.readreg_uint8(RFM_REG_OOKPEAK); err != nil {
+		return 0, 0, 0, err
+	} else {
+		threshold_type := sensors.RFMOOKThresholdType(value>>6) & 0x03
+		threshold_step := sensors.RFMOOKThresholdStep(value>>3) & sensors.RFM_OOK_THRESHOLD_STEP_MAX
+		threshold_dec := sensors.RFMOOKThresholdDecrement(value) & sensors.RFM_OOK_THRESHOLD_DEC_MAX
+		return threshold_type, threshold_step, threshold_dec, nil
+	}
+}
+
+// Write OOK threshold type, step and decrement
+func (this *rfm69) setRegOOKPeak(threshold_type sensors.RFMOOKThresholdType, threshold_step sensors.RFMOOKThresholdStep, threshold_dec sensors.RFMOOKThresholdDecrement) error {
+	value :=
+		uint8(threshold_type&0x03)<<6 |
+			uint8(threshold_step&sensors.RFM_OOK_THRESHOLD_STEP_MAX)<<3 |
+			uint8(threshold_dec&sensors.RFM_OOK_THRESHOLD_DEC_MAX)
+	return this.writereg_uint8(RFM_REG_OOKPEAK, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM_REG_FEIMSB, RFM_REG_FEILSB
+
+// Read frequency error
+func (this *rfm69) getFEI() (int16, error) {
+	return this.readreg_int16(RFM_REG_FEIMSB)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM_REG_OSC1
+
+// Get RC oscillator calibration done bit
+func (this *rfm69) getRegOsc1CalDone() (bool, error) {
+	if value, err := this.readreg_uint8(RFM_REG_OSC1); err != nil {
+		return false, err
+	} else {
+		done := to_uint8_bool(value & 0x40)
+		return done, nil
+	}
+}
+
+// Set RC oscillator calibration start bit
+func (this *rfm69) setRegOsc1CalStart() error {
+	return this.writereg_uint8(RFM_REG_OSC1, 0x80)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM_REG_PALEVEL, RFM_REG_OCP
+
+// Read enabled power amplifiers and output power
+func (this *rfm69) getRegPALevel() (uint8, uint8, error) {
+	if value, err := this.readreg_uint8(RFM_REG_PALEVEL); err != nil {
+		return 0, 0, err
+	} else {
+		pa := value & (RFM_PALEVEL_PA0 | RFM_PALEVEL_PA1 | RFM_PALEVEL_PA2)
+		output_power := value & RFM_PALEVEL_OUTPUTPOWER
+		return pa, output_power, nil
+	}
+}
+
+// Write enabled power amplifiers and output power
+func (this *rfm69) setRegPALevel(pa uint8, output_power uint8) error {
+	value :=
+		pa&(RFM_PALEVEL_PA0|RFM_PALEVEL_PA1|RFM_PALEVEL_PA2) |
+			output_power&RFM_PALEVEL_OUTPUTPOWER
+	return this.writereg_uint8(RFM_REG_PALEVEL, value)
+}
+
+// Read over current protection enabled
+func (this *rfm69) getRegOCP() (bool, error) {
+	if value, err := this.readreg_uint8(RFM_REG_OCP); err != nil {
+		return false, err
+	} else {
+		return to_uint8_bool(value & RFM_OCP_ON), nil
+	}
+}
+
+// Write over current protection enabled, with the default trim
+func (this *rfm69) setRegOCP(ocp_on bool) error {
+	value := to_bool_uint8(ocp_on)<<4 | RFM_OCP_TRIM_95MA
+	return this.writereg_uint8(RFM_REG_OCP, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM_REG_TESTPA1, RFM_REG_TESTPA2
+
+// Read whether the +20dBm settings are enabled
+func (this *rfm69) getRegTestPA() (bool, error) {
+	if testpa1, err := this.readreg_uint8(RFM_REG_TESTPA1); err != nil {
+		return false, err
+	} else if testpa2, err := this.readreg_uint8(RFM_REG_TESTPA2); err != nil {
+		return false, err
+	} else {
+		return testpa1 == RFM_TESTPA1_BOOST && testpa2 == RFM_TESTPA2_BOOST, nil
+	}
+}
+
+// Write normal or +20dBm settings
+func (this *rfm69) setRegTestPA(boost bool) error {
+	if boost {
+		if err := this.writereg_uint8(RFM_REG_TESTPA1, RFM_TESTPA1_BOOST); err != nil {
+			return err
+		}
+		return this.writereg_uint8(RFM_REG_TESTPA2, RFM_TESTPA2_BOOST)
+	} else {
+		if err := this.writereg_uint8(RFM_REG_TESTPA1, RFM_TESTPA1_NORMAL); err != nil {
+			return err
+		}
+		return this.writereg_uint8(RFM_REG_TESTPA2, RFM_TESTPA2_NORMAL)
+	}
+}
@@ -30,6 +30,12 @@ type rfm69 struct {
 	log  gopi.Logger
 	lock sync.Mutex
 
+	// DIO0 interrupt, or nil channel when polling
+	gpio     gopi.GPIO
+	pin_dio0 gopi.GPIOPin
+	events   <-chan gopi.Event
+	dio0     chan struct{}
+
 	version               uint8
 	mode                  sensors.RFMMode
 	sequencer_off         bool
@@ -66,6 +72,13 @@ type rfm69 struct {
 	lna_gain              sensors.RFMLNAGain
 	rxbw_frequency        sensors.RFMRXBWFrequency
 	rxbw_cutoff           sensors.RFMRXBWCutoff
+	ook_threshold_type    sensors.RFMOOKThresholdType
+	ook_threshold_step    sensors.RFMOOKThresholdStep
+	ook_threshold_dec     sensors.RFMOOKThresholdDecrement
+	high_power            bool
+	output_power          int8
+	pa_boost              bool
+	fei_start             bool
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -84,6 +97,7 @@ const (
 	RFM_FDEV_MAX       = 0x3FFF     // Maximum value of FDEV
 	RFM_FRF_MAX        = 0xFFFFFF   // Maximum value of FRF
 	RFM_FIFO_SIZE      = 66         // Bytes
+	RFM_PACKET_MAX     = 256        // Bytes, including the length byte
 	RFM_TEMP_COEF      = 160
 )
 
@@ -131,6 +145,11 @@ func (this *rfm69) SetMode(mode sensors.RFMMode) error {
 		}
 	}
 
+	// Enable high power settings before TX and disable them otherwise
+	if err := this.setTestPAForMode(mode); err != nil {
+		return err
+	}
+
 	// Write mode and read back again
 	if err := this.setOpMode(mode, false, false, this.sequencer_off); err != nil {
 		return err
@@ -176,6 +195,13 @@ func (this *rfm69) SetMode(mode sensors.RFMMode) error {
 		this.sequencer_off = sequencer_off_read
 	}
 
+	// Map DIO0 to the interrupt for the mode
+	if this.dio0 != nil {
+		if err := this.setDIO0ForMode(this.mode); err != nil {
+			return err
+		}
+	}
+
 	// If RX mode then read AFC value
 	if this.mode == sensors.RFM_MODE_RX {
 		if afc, err := this.getAFC(); err != nil {
@@ -11,6 +11,9 @@ package rfm69
 
 import (
 	"time"
+
+	// Frameworks
+	"github.com/djthorpe/sensors"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -40,3 +43,65 @@ func (this *rfm69) MeasureRSSI() (float32, error) {
 		return -float32(value) / 2.0, nil
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// SIGNAL
+
+// startFEI starts a frequency error measurement once the sync address
+// has matched and before the payload is ready, since the measurement
+// needs the packet to be on air. The flag is cleared when the receiver
+// restarts without a payload, so it's not carried to the next packet
+func (this *rfm69) startFEI() error {
+	if sync_match, err := this.recvSyncAddressMatch(); err != nil {
+		return err
+	} else if sync_match == false {
+		this.fei_start = false
+	} else if this.fei_start == false {
+		if err := this.setAFCControl(this.afc_mode, true, false, false); err != nil {
+			return err
+		}
+		this.fei_start = true
+	}
+	return nil
+}
+
+// getSignal reads the signal metadata when a payload is ready. The RSSI,
+// AFC correction and LNA gain are frozen at sync address match until the
+// receiver is restarted, so they relate to the received payload. The
+// frequency error is zero unless it was measured during reception
+func (this *rfm69) getSignal() (sensors.RFMSignal, error) {
+	fei_start := this.fei_start
+	this.fei_start = false
+	if rssi, err := this.getRegRSSIValue(); err != nil {
+		return sensors.RFMSignal{}, err
+	} else if afc, err := this.getAFC(); err != nil {
+		return sensors.RFMSignal{}, err
+	} else if _, _, lna_gain, err := this.getRegLNA(); err != nil {
+		return sensors.RFMSignal{}, err
+	} else if fei, err := this.getFEIDone(fei_start); err != nil {
+		return sensors.RFMSignal{}, err
+	} else {
+		return sensors.RFMSignal{
+			RSSI:    -float32(rssi) / 2.0,
+			AFC:     to_fstep_hertz(afc),
+			FEI:     fei,
+			LNAGain: lna_gain,
+		}, nil
+	}
+}
+
+// getFEIDone returns the frequency error in hertz when a measurement was
+// started and is done, or zero otherwise
+func (this *rfm69) getFEIDone(fei_start bool) (float64, error) {
+	if fei_start == false {
+		return 0, nil
+	} else if _, _, fei_done, err := this.getAFCControl(); err != nil {
+		return 0, err
+	} else if fei_done == false {
+		return 0, nil
+	} else if fei, err := this.getFEI(); err != nil {
+		return 0, err
+	} else {
+		return to_fstep_hertz(fei), nil
+	}
+}
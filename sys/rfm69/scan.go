@@ -0,0 +1,121 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69
+
+import (
+	"context"
+	"math"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Maximum number of frequencies in a scan
+	RFM_SCAN_MAX_STEPS = 10000
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// SCAN
+
+// Scan steps the carrier frequency between start and stop inclusive,
+// and samples RSSI in RX mode for the dwell time at each frequency.
+// The carrier frequency, mode and sequencer are restored afterwards
+func (this *rfm69) Scan(ctx context.Context, start, stop, step uint, dwell time.Duration) ([]sensors.RFMScanResult, error) {
+	this.log.Debug("<sensors.RFM69.Scan>{ start=%v stop=%v step=%v dwell=%v }", start, stop, step, dwell)
+
+	// Check parameters
+	if ctx == nil || step == 0 || dwell <= 0 || start > stop {
+		return nil, gopi.ErrBadParameter
+	} else if (stop-start)/step >= RFM_SCAN_MAX_STEPS {
+		return nil, gopi.ErrBadParameter
+	} else if math.Ceil(float64(stop)/float64(RFM_FSTEP_HZ)) > RFM_FRF_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Restore carrier frequency, mode and sequencer on return, which
+	// are read under the lock since the receive loop may be running
+	this.lock.Lock()
+	frf, mode, sequencer := this.frf, this.mode, !this.sequencer_off
+	this.lock.Unlock()
+	defer func() {
+		if err := this.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+			this.log.Warn("Scan: %v", err)
+		} else if err := this.SetFreqCarrierUint24(frf); err != nil {
+			this.log.Warn("Scan: %v", err)
+		} else if err := this.SetSequencer(sequencer); err != nil {
+			this.log.Warn("Scan: %v", err)
+		} else if err := this.SetMode(mode); err != nil {
+			this.log.Warn("Scan: %v", err)
+		}
+	}()
+
+	// Measure at each frequency
+	results := make([]sensors.RFMScanResult, 0, (stop-start)/step+1)
+	for hertz := start; hertz <= stop; hertz += step {
+		if result, err := this.scanFrequency(ctx, hertz, dwell); err != nil {
+			return nil, err
+		} else {
+			results = append(results, result)
+		}
+		// Prevent overflow when stop is close to the maximum
+		if hertz+step < hertz {
+			break
+		}
+	}
+
+	// Success
+	return results, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// scanFrequency sets the carrier frequency in standby mode, and then
+// samples RSSI in RX mode until the dwell time has elapsed
+func (this *rfm69) scanFrequency(ctx context.Context, hertz uint, dwell time.Duration) (sensors.RFMScanResult, error) {
+	result := sensors.RFMScanResult{
+		Frequency: hertz,
+	}
+	if err := this.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+		return result, err
+	} else if err := this.SetFreqCarrier(hertz); err != nil {
+		return result, err
+	} else if err := this.SetMode(sensors.RFM_MODE_RX); err != nil {
+		return result, err
+	}
+
+	// Sample at least once
+	sum := float64(0)
+	for start := time.Now(); result.Samples == 0 || time.Since(start) < dwell; {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+			rssi, err := this.MeasureRSSI()
+			if err != nil {
+				return result, err
+			} else if result.Samples == 0 || rssi > result.Max {
+				result.Max = rssi
+			}
+			sum += float64(rssi)
+			result.Samples++
+		}
+	}
+	result.Average = float32(sum / float64(result.Samples))
+
+	// Success
+	return result, nil
+}
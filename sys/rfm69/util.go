@@ -135,3 +135,8 @@ func matches_byte(a []byte, b byte) bool {
 	}
 	return false
 }
+
+// Convert a frequency offset in steps of FSTEP = FXOSC / 2^19 to hertz
+func to_fstep_hertz(value int16) float64 {
+	return float64(value) * RFM_FXOSC_MHZ * 1e6 / float64(1<<19)
+}
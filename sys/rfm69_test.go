@@ -0,0 +1,801 @@
+package sys_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/protocol/protobuf"
+	"github.com/djthorpe/sensors/sys/ener314rt"
+	"github.com/djthorpe/sensors/sys/rfm69"
+	"github.com/djthorpe/sensors/sys/rfm69sim"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+func Test_RFM69_000_open(t *testing.T) {
+	if sim, radio := RFM69(t, rfm69sim.RFM69{}); radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if radio.Mode() != sensors.RFM_MODE_STDBY {
+		t.Error("Unexpected mode", radio.Mode())
+	} else if sim.Register(uint8(rfm69.RFM_REG_VERSION)) != rfm69.RFM_VERSION_VALUE {
+		t.Error("Unexpected version")
+	} else if err := radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		t.Error(err)
+	} else if radio.Mode() != sensors.RFM_MODE_RX {
+		t.Error("Unexpected mode", radio.Mode())
+	}
+}
+
+func Test_RFM69_001_temperature(t *testing.T) {
+	if _, radio := RFM69(t, rfm69sim.RFM69{Temperature: 25}); radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if celcius, err := radio.MeasureTemperature(0); err != nil {
+		t.Error(err)
+	} else if celcius != 25 {
+		t.Error("Unexpected temperature", celcius)
+	} else if celcius, err := radio.MeasureTemperature(-2); err != nil {
+		t.Error(err)
+	} else if celcius != 23 {
+		t.Error("Unexpected temperature", celcius)
+	}
+}
+
+func Test_RFM69_002_readpayload(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+
+	// ReadPayload is out of order when not in RX mode
+	if _, _, _, err := radio.ReadPayload(context.Background()); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+
+	// Inject two packets and read them back in order
+	payloads := [][]byte{[]byte{0x03, 0x01, 0x02, 0x03}, []byte{0x02, 0xFF, 0xFE}}
+	for _, payload := range payloads {
+		if err := sim.Inject(payload, -60, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range payloads {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if data, _, _, err := radio.ReadPayload(ctx); err != nil {
+			t.Error(err)
+		} else if Equals(data, payload) == false {
+			t.Errorf("Expected %v, got %v", payload, data)
+		}
+	}
+
+	// Nothing more to receive
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if data, _, _, err := radio.ReadPayload(ctx); err != nil {
+		t.Error(err)
+	} else if data != nil {
+		t.Error("Unexpected payload", data)
+	}
+}
+
+func Test_RFM69_003_writepayload(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+
+	payload := []byte{0x04, 0x10, 0x20, 0x30, 0x40}
+	if err := radio.WritePayload(payload, 0, 0); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	} else if err := radio.SetMode(sensors.RFM_MODE_TX); err != nil {
+		t.Fatal(err)
+	} else if err := radio.WritePayload(payload, 2, 0); err != nil {
+		t.Fatal(err)
+	} else if tx := sim.Transmitted(); len(tx) != 3 {
+		t.Error("Expected three packets, got", len(tx))
+	} else {
+		for _, data := range tx {
+			if Equals(data, payload) == false {
+				t.Errorf("Expected %v, got %v", payload, data)
+			}
+		}
+	}
+}
+
+func Test_ENER314RT_004_mode(t *testing.T) {
+	_, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+	board, ok := ENER314RT(t, radio).(MiHomeMode)
+	if ok == false {
+		t.Fatal("Missing ENER314RT")
+	}
+
+	// Monitor mode is FSK
+	if err := board.SetMode(sensors.MIHOME_MODE_MONITOR); err != nil {
+		t.Fatal(err)
+	} else if radio.Modulation() != sensors.RFM_MODULATION_FSK {
+		t.Error("Unexpected modulation", radio.Modulation())
+	} else if radio.PacketCoding() != sensors.RFM_PACKET_CODING_MANCHESTER {
+		t.Error("Unexpected packet coding", radio.PacketCoding())
+	} else if Equals(radio.SyncWord(), []byte{0x2D, 0xD4}) == false {
+		t.Error("Unexpected sync word", radio.SyncWord())
+	}
+
+	// Control mode is OOK
+	if err := board.SetMode(sensors.MIHOME_MODE_CONTROL); err != nil {
+		t.Fatal(err)
+	} else if radio.Modulation() != sensors.RFM_MODULATION_OOK {
+		t.Error("Unexpected modulation", radio.Modulation())
+	} else if radio.PacketCoding() != sensors.RFM_PACKET_CODING_NONE {
+		t.Error("Unexpected packet coding", radio.PacketCoding())
+	} else if radio.SyncWord() != nil {
+		t.Error("Unexpected sync word", radio.SyncWord())
+	}
+}
+
+func Test_ENER314RT_005_receive(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+	board := ENER314RT(t, radio)
+	if board == nil {
+		t.Fatal("Missing ENER314RT")
+	}
+
+	payload := []byte{0x05, 0x04, 0x02, 0x01, 0x02, 0x03}
+	if err := sim.Inject(payload, -70, true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	received := make(chan sensors.MiHomePayload)
+	errors := make(chan error)
+	go func() {
+		errors <- board.Receive(ctx, sensors.MIHOME_MODE_MONITOR, received)
+	}()
+	select {
+	case data := <-received:
+		if Equals(data.Data, payload) == false {
+			t.Errorf("Expected %v, got %v", payload, data.Data)
+		} else if data.Signal == nil || data.Signal.RSSI != -70 {
+			t.Error("Unexpected signal", data.Signal)
+		}
+		cancel()
+	case <-ctx.Done():
+		t.Error("Timeout waiting for payload")
+	}
+	if err := <-errors; err != context.Canceled {
+		t.Error("Expected context.Canceled, got", err)
+	} else if board.(MiHomeMode).Mode() != sensors.MIHOME_MODE_MONITOR {
+		t.Error("Unexpected mode", board.(MiHomeMode).Mode())
+	}
+}
+
+func Test_ENER314RT_006_send(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+	board := ENER314RT(t, radio)
+	if board == nil {
+		t.Fatal("Missing ENER314RT")
+	}
+
+	payload := []byte{0x80, 0x00, 0x00, 0x00, 0x88, 0x88, 0x88, 0x88}
+	if err := board.Send(payload, 0, sensors.MIHOME_MODE_CONTROL); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := board.Send(payload, 4, sensors.MIHOME_MODE_CONTROL); err != nil {
+		t.Fatal(err)
+	} else if radio.Modulation() != sensors.RFM_MODULATION_OOK {
+		t.Error("Unexpected modulation", radio.Modulation())
+	} else if tx := sim.Transmitted(); len(tx) != 5 {
+		t.Error("Expected five packets, got", len(tx))
+	}
+}
+
+func Test_RFM69_007_dio0(t *testing.T) {
+	sim, radio := RFM69DIO0(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+
+	// DIO0 is mapped to PayloadReady in RX mode
+	if err := radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		t.Fatal(err)
+	} else if mapping := sim.Register(uint8(rfm69.RFM_REG_DIOMAPPING1)) >> 6; mapping != rfm69.RFM_DIO0_PAYLOADREADY {
+		t.Error("Unexpected DIO0 mapping", mapping)
+	}
+
+	// Read payload in the background
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	received := make(chan []byte)
+	go func() {
+		if data, _, _, err := radio.ReadPayload(ctx); err != nil {
+			t.Error(err)
+			close(received)
+		} else {
+			received <- data
+		}
+	}()
+
+	// The lock is not held while waiting
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	if err := radio.SetFIFOThreshold(10); err != nil {
+		t.Error(err)
+	} else if since := time.Since(start); since > 50*time.Millisecond {
+		t.Error("Blocked while waiting for payload", since)
+	}
+
+	// The payload is received on the interrupt, before the next poll
+	payload := []byte{0x03, 0x01, 0x02, 0x03}
+	start = time.Now()
+	if err := sim.Inject(payload, -60, true); err != nil {
+		t.Fatal(err)
+	} else if data := <-received; Equals(data, payload) == false {
+		t.Errorf("Expected %v, got %v", payload, data)
+	} else if since := time.Since(start); since >= rfm69.RFM_POLL_INTERVAL_DIO0 {
+		t.Error("Payload not received on interrupt", since)
+	}
+
+	// DIO0 is mapped to PacketSent in TX mode
+	if err := radio.SetMode(sensors.RFM_MODE_TX); err != nil {
+		t.Fatal(err)
+	} else if mapping := sim.Register(uint8(rfm69.RFM_REG_DIOMAPPING1)) >> 6; mapping != rfm69.RFM_DIO0_PACKETSENT {
+		t.Error("Unexpected DIO0 mapping", mapping)
+	} else if err := radio.WritePayload(payload, 1, 0); err != nil {
+		t.Error(err)
+	} else if tx := sim.Transmitted(); len(tx) != 2 {
+		t.Error("Expected two packets, got", len(tx))
+	}
+}
+
+func Test_RFM69_008_ook(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{FrequencyError: -1000})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+
+	// OOK thresholds are written to RegOokPeak
+	if err := radio.SetOOK(sensors.RFM_OOK_THRESHOLD_PEAK, sensors.RFM_OOK_THRESHOLD_STEP_2P0, sensors.RFM_OOK_THRESHOLD_DEC_4); err != nil {
+		t.Error(err)
+	} else if value := sim.Register(uint8(rfm69.RFM_REG_OOKPEAK)); value != 0x5D {
+		t.Errorf("Unexpected RegOokPeak value 0x%02X", value)
+	} else if radio.OOKThresholdType() != sensors.RFM_OOK_THRESHOLD_PEAK || radio.OOKThresholdStep() != sensors.RFM_OOK_THRESHOLD_STEP_2P0 || radio.OOKThresholdDecrement() != sensors.RFM_OOK_THRESHOLD_DEC_4 {
+		t.Error("Unexpected OOK parameters")
+	} else if err := radio.SetOOK(sensors.RFM_OOK_THRESHOLD_MAX+1, 0, 0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// FIFO fill condition and TX start condition
+	if err := radio.SetFIFOFillCondition(true); err != nil {
+		t.Error(err)
+	} else if radio.FIFOFillCondition() != true {
+		t.Error("Unexpected FIFO fill condition")
+	} else if err := radio.SetTXStart(sensors.RFM_TXSTART_FIFOLEVEL); err != nil {
+		t.Error(err)
+	} else if radio.TXStart() != sensors.RFM_TXSTART_FIFOLEVEL {
+		t.Error("Unexpected TX start condition", radio.TXStart())
+	} else if threshold := sim.Register(uint8(rfm69.RFM_REG_FIFOTHRESH)); threshold != radio.FIFOThreshold() {
+		t.Errorf("Unexpected RegFifoThresh value 0x%02X", threshold)
+	}
+
+	// RC oscillator is calibrated in standby, FEI is measured in RX
+	// with a resolution of one frequency step
+	if err := radio.CalibrateRCOsc(); err != nil {
+		t.Error(err)
+	} else if err := radio.ClearAFC(); err != nil {
+		t.Error(err)
+	} else if radio.AFC() != 0 {
+		t.Error("Unexpected AFC", radio.AFC())
+	} else if _, err := radio.ReadFEIHertz(); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	} else if err := radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		t.Fatal(err)
+	} else if err := radio.CalibrateRCOsc(); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	} else if hertz, err := radio.ReadFEIHertz(); err != nil {
+		t.Error(err)
+	} else if hertz > -1000+rfm69.RFM_FSTEP_HZ || hertz < -1000-rfm69.RFM_FSTEP_HZ {
+		t.Error("Unexpected frequency error", hertz)
+	}
+}
+
+func Test_RFM69_009_power(t *testing.T) {
+	// RFM69W uses PA0 between -18 and +13dBm
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if radio.OutputPower() != 13 {
+		t.Error("Unexpected power-on output power", radio.OutputPower())
+	} else if err := radio.SetOutputPower(-18); err != nil {
+		t.Error(err)
+	} else if value := sim.Register(uint8(rfm69.RFM_REG_PALEVEL)); value != 0x80 {
+		t.Errorf("Unexpected RegPaLevel value 0x%02X", value)
+	} else if err := radio.SetOutputPower(14); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if radio.OutputPower() != -18 {
+		t.Error("Unexpected output power", radio.OutputPower())
+	}
+
+	// RFM69HW uses PA1 and PA2 between -2 and +20dBm
+	sim, radio = RFM69HW(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if err := radio.SetOutputPower(-3); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := radio.SetOutputPower(15); err != nil {
+		t.Error(err)
+	} else if value := sim.Register(uint8(rfm69.RFM_REG_PALEVEL)); value != 0x7D {
+		t.Errorf("Unexpected RegPaLevel value 0x%02X", value)
+	} else if value := sim.Register(uint8(rfm69.RFM_REG_OCP)); value != 0x1A {
+		t.Errorf("Unexpected RegOcp value 0x%02X", value)
+	}
+
+	// High power settings are only used in TX mode, with over current
+	// protection off
+	if err := radio.SetOutputPower(20); err != nil {
+		t.Error(err)
+	} else if value := sim.Register(uint8(rfm69.RFM_REG_PALEVEL)); value != 0x7F {
+		t.Errorf("Unexpected RegPaLevel value 0x%02X", value)
+	} else if value := sim.Register(uint8(rfm69.RFM_REG_OCP)); value != 0x0A {
+		t.Errorf("Unexpected RegOcp value 0x%02X", value)
+	} else if value := sim.Register(uint8(rfm69.RFM_REG_TESTPA1)); value != rfm69.RFM_TESTPA1_NORMAL {
+		t.Errorf("Unexpected RegTestPa1 value 0x%02X", value)
+	}
+	for _, mode := range []sensors.RFMMode{sensors.RFM_MODE_TX, sensors.RFM_MODE_RX} {
+		testpa1, testpa2 := rfm69.RFM_TESTPA1_NORMAL, rfm69.RFM_TESTPA2_NORMAL
+		if mode == sensors.RFM_MODE_TX {
+			testpa1, testpa2 = rfm69.RFM_TESTPA1_BOOST, rfm69.RFM_TESTPA2_BOOST
+		}
+		if err := radio.SetMode(mode); err != nil {
+			t.Error(err)
+		} else if value := sim.Register(uint8(rfm69.RFM_REG_TESTPA1)); value != testpa1 {
+			t.Errorf("Unexpected RegTestPa1 value 0x%02X in mode %v", value, mode)
+		} else if value := sim.Register(uint8(rfm69.RFM_REG_TESTPA2)); value != testpa2 {
+			t.Errorf("Unexpected RegTestPa2 value 0x%02X in mode %v", value, mode)
+		}
+	}
+	if radio.OutputPower() != 20 {
+		t.Error("Unexpected output power", radio.OutputPower())
+	}
+}
+
+func Test_RFM69_010_signal(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{FrequencyError: 2000})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+
+	// Signal metadata is returned with each payload. The frequency error
+	// isn't measured when the payload is already ready
+	if err := sim.Inject([]byte{0x01, 0x02}, -72.5, true); err != nil {
+		t.Fatal(err)
+	} else if err := radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, signal, err := radio.ReadPayload(ctx); err != nil {
+		t.Fatal(err)
+	} else if signal.RSSI != -72.5 {
+		t.Error("Unexpected RSSI", signal)
+	} else if signal.FEI != 0 {
+		t.Error("Unexpected FEI", signal)
+	} else if signal.AFC != 0 {
+		t.Error("Unexpected AFC", signal)
+	} else if signal.LNAGain != sensors.RFM_LNA_GAIN_G1 {
+		t.Error("Unexpected LNA gain", signal)
+	}
+
+	// The frequency error is measured while a packet larger than
+	// the FIFO is being received
+	payload := make([]byte, 100)
+	payload[0] = byte(len(payload) - 1)
+	if err := radio.SetPacketFormat(sensors.RFM_PACKET_FORMAT_VARIABLE); err != nil {
+		t.Fatal(err)
+	} else if err := radio.SetPayloadSize(0xFF); err != nil {
+		t.Fatal(err)
+	} else if err := sim.Inject(payload, -60, true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _, signal, err := radio.ReadPayload(ctx); err != nil {
+		t.Fatal(err)
+	} else if Equals(data, payload) == false {
+		t.Errorf("Expected %v bytes, got %v", len(payload), data)
+	} else if signal.RSSI != -60 {
+		t.Error("Unexpected RSSI", signal)
+	} else if signal.FEI < 2000-rfm69.RFM_FSTEP_HZ || signal.FEI > 2000+rfm69.RFM_FSTEP_HZ {
+		t.Error("Unexpected FEI", signal)
+	}
+}
+
+func Test_RFM69_011_profile(t *testing.T) {
+	_, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if err := radio.ApplyProfile(ener314rt.PROFILE_MONITOR); err != nil {
+		t.Fatal(err)
+	} else if radio.Modulation() != sensors.RFM_MODULATION_FSK {
+		t.Error("Unexpected modulation", radio.Modulation())
+	} else if Equals(radio.SyncWord(), []byte{0x2D, 0xD4}) == false {
+		t.Error("Unexpected sync word", radio.SyncWord())
+	}
+
+	// Save as JSON, with named values
+	data, err := json.Marshal(radio.Profile())
+	if err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(data), `"modulation":"RFM_MODULATION_FSK"`) == false {
+		t.Error("Unexpected JSON", string(data))
+	} else if strings.Contains(string(data), `"sync_word":"2DD4"`) == false {
+		t.Error("Unexpected JSON", string(data))
+	}
+
+	// Load into another radio, which then has the same profile
+	var profile sensors.RFMProfile
+	_, radio2 := RFM69(t, rfm69sim.RFM69{})
+	if radio2 == nil {
+		t.Fatal("Missing RFM69")
+	} else if err := json.Unmarshal(data, &profile); err != nil {
+		t.Fatal(err)
+	} else if err := radio2.ApplyProfile(profile); err != nil {
+		t.Fatal(err)
+	} else if reflect.DeepEqual(radio.Profile(), radio2.Profile()) == false {
+		t.Errorf("Expected %v, got %v", radio.Profile(), radio2.Profile())
+	}
+
+	// Invalid names are not loaded
+	if err := json.Unmarshal([]byte(`{ "modulation": "FSK" }`), &profile); err == nil {
+		t.Error("Expected error for invalid modulation")
+	}
+
+	// Invalid profiles are not applied
+	profile = ener314rt.PROFILE_CONTROL
+	profile.SyncWord = "2D00"
+	if err := radio.ApplyProfile(profile); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if radio.Modulation() != sensors.RFM_MODULATION_FSK {
+		t.Error("Unexpected modulation", radio.Modulation())
+	} else if err := radio.ApplyProfile(ener314rt.PROFILE_CONTROL); err != nil {
+		t.Error(err)
+	} else if radio.Modulation() != sensors.RFM_MODULATION_OOK || radio.SyncWord() != nil {
+		t.Error("Unexpected profile", radio.Profile())
+	}
+}
+
+func Test_RFM69_012_scan(t *testing.T) {
+	_, radio := RFM69(t, rfm69sim.RFM69{RSSI: -95})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if err := radio.ApplyProfile(ener314rt.PROFILE_MONITOR); err != nil {
+		t.Fatal(err)
+	}
+	frf := radio.FreqCarrier()
+
+	// Scan four frequencies
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if results, err := radio.Scan(ctx, 433050000, 433125000, 25000, time.Millisecond); err != nil {
+		t.Fatal(err)
+	} else if len(results) != 4 {
+		t.Error("Unexpected number of results", results)
+	} else {
+		for i, result := range results {
+			if result.Frequency != 433050000+uint(i)*25000 {
+				t.Error("Unexpected frequency", result)
+			} else if result.Samples == 0 || result.Max != -95 || result.Average != -95 {
+				t.Error("Unexpected RSSI", result)
+			}
+		}
+	}
+
+	// Carrier frequency and mode are restored
+	if radio.FreqCarrier() != frf {
+		t.Error("Unexpected carrier frequency", radio.FreqCarrier())
+	} else if radio.Mode() != sensors.RFM_MODE_STDBY {
+		t.Error("Unexpected mode", radio.Mode())
+	}
+
+	// Bad parameters
+	if _, err := radio.Scan(ctx, 434000000, 433000000, 25000, time.Millisecond); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := radio.Scan(ctx, 433000000, 434000000, 0, time.Millisecond); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := radio.Scan(ctx, 433000000, 434000000, 25000, 0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func Test_RFM69_013_largepayload(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if err := radio.SetPacketFormat(sensors.RFM_PACKET_FORMAT_VARIABLE); err != nil {
+		t.Fatal(err)
+	} else if err := radio.SetPayloadSize(0xFF); err != nil {
+		t.Fatal(err)
+	}
+
+	// Variable length payloads larger than the FIFO, the first byte
+	// is the length
+	payloads := make([][]byte, 0, 3)
+	for _, length := range []int{10, 100, 256} {
+		payload := make([]byte, length)
+		payload[0] = byte(length - 1)
+		for i := 1; i < length; i++ {
+			payload[i] = byte(i)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	// Receive
+	for _, payload := range payloads {
+		if err := sim.Inject(payload, -60, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range payloads {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if data, _, _, err := radio.ReadPayload(ctx); err != nil {
+			t.Error(err)
+		} else if Equals(data, payload) == false {
+			t.Errorf("Expected %v bytes, got %v", len(payload), data)
+		}
+	}
+
+	// Transmit
+	if err := radio.SetMode(sensors.RFM_MODE_TX); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range payloads {
+		if err := radio.WritePayload(payload, 1, 0); err != nil {
+			t.Error(err)
+		} else if tx := sim.Transmitted(); len(tx) != 2 {
+			t.Error("Expected two packets, got", len(tx))
+		} else if Equals(tx[0], payload) == false || Equals(tx[1], payload) == false {
+			t.Errorf("Expected %v bytes, got %v", len(payload), tx)
+		}
+	}
+
+	// Payloads longer than the maximum packet length are not sent
+	if err := radio.WritePayload(make([]byte, 257), 0, 0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func Test_Protobuf_014_sender(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	}
+	log := Logger(t)
+	proto, err := gopi.Open(protobuf.Protobuf{Address: 0x01, Broadcast: 0xFF}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F}
+
+	// Invalid AES key
+	if _, err := gopi.Open(protobuf.Sender{Radio: radio, Proto: proto.(sensors.ProtobufProto), AESKey: key[:8]}, log); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Send readings to the gateway
+	sender, err := gopi.Open(protobuf.Sender{
+		Radio:   radio,
+		Proto:   proto.(sensors.ProtobufProto),
+		Product: 0x01,
+		Sensor:  0x000042,
+		Address: 0x10,
+		Gateway: 0x01,
+		AESKey:  key,
+		Repeat:  1,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	} else if radio.NodeAddress() != 0x10 {
+		t.Error("Unexpected node address", radio.NodeAddress())
+	} else if Equals(radio.AESKey(), key) == false {
+		t.Error("Unexpected AES key", radio.AESKey())
+	} else if err := sender.(sensors.ProtobufSender).Send("bme280", map[sensors.OTParameter]interface{}{
+		sensors.OT_PARAM_TEMPERATURE:       float32(20.5),
+		sensors.OT_PARAM_RELATIVE_HUMIDITY: uint(40),
+	}); err != nil {
+		t.Fatal(err)
+	} else if radio.Mode() != sensors.RFM_MODE_STDBY {
+		t.Error("Unexpected mode", radio.Mode())
+	} else if tx := sim.Transmitted(); len(tx) != 2 {
+		t.Error("Expected two packets, got", len(tx))
+	} else if msg, err := proto.(sensors.Proto).Decode(tx[0], time.Now()); err != nil {
+		t.Error(err)
+	} else if msg_ := msg.(sensors.ProtoMessage); msg_.Protocol() != "bme280" || msg_.Sensor() != 0x000042 || len(msg_.Records()) != 2 {
+		t.Error("Unexpected message", msg)
+	} else if msg_.Records()[0].Name() > msg_.Records()[1].Name() {
+		t.Error("Unexpected record order", msg)
+	}
+
+	// The gateway uses the same key in monitor mode
+	_, gateway := RFM69(t, rfm69sim.RFM69{})
+	if gpio, err := gopi.Open(rfm69sim.GPIO{}, log); err != nil {
+		t.Fatal(err)
+	} else if _, err := gopi.Open(ener314rt.ENER314RT{GPIO: gpio.(gopi.GPIO), Radio: gateway, AESKey: key[:8]}, log); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if board, err := gopi.Open(ener314rt.ENER314RT{GPIO: gpio.(gopi.GPIO), Radio: gateway, AESKey: key}, log); err != nil {
+		t.Fatal(err)
+	} else if err := board.(MiHomeMode).SetMode(sensors.MIHOME_MODE_MONITOR); err != nil {
+		t.Fatal(err)
+	} else if Equals(gateway.AESKey(), key) == false {
+		t.Error("Unexpected gateway AES key", gateway.AESKey())
+	}
+}
+
+func Test_RFM69_015_filter(t *testing.T) {
+	sim, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if err := radio.SetPacketFormat(sensors.RFM_PACKET_FORMAT_VARIABLE); err != nil {
+		t.Fatal(err)
+	} else if err := radio.SetPacketFilter(sensors.RFM_PACKET_FILTER_BROADCAST); err != nil {
+		t.Fatal(err)
+	} else if err := radio.SetNodeAddress(0x01); err != nil {
+		t.Fatal(err)
+	} else if err := radio.SetBroadcastAddress(0xFF); err != nil {
+		t.Fatal(err)
+	}
+
+	// Packets for other addresses, or with CRC errors when CRC
+	// auto-clear is on, are discarded by the radio
+	for _, packet := range []struct {
+		data   []byte
+		crc_ok bool
+	}{
+		{[]byte{0x02, 0x02, 0x10}, true},
+		{[]byte{0x02, 0x01, 0x11}, false},
+		{[]byte{0x02, 0x01, 0x12}, true},
+		{[]byte{0x02, 0xFF, 0x13}, true},
+	} {
+		if err := sim.Inject(packet.data, -60, packet.crc_ok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range [][]byte{[]byte{0x02, 0x01, 0x12}, []byte{0x02, 0xFF, 0x13}} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if data, crc_ok, _, err := radio.ReadPayload(ctx); err != nil {
+			t.Error(err)
+		} else if Equals(data, payload) == false {
+			t.Errorf("Expected %v, got %v", payload, data)
+		} else if crc_ok == false {
+			t.Error("Expected CRC to be reported as valid")
+		}
+	}
+
+	// With CRC auto-clear off, the packet is received with the CRC error
+	if err := radio.SetPacketCRC(sensors.RFM_PACKET_CRC_AUTOCLEAR_OFF); err != nil {
+		t.Fatal(err)
+	} else if err := sim.Inject([]byte{0x02, 0x01, 0x14}, -60, false); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if data, crc_ok, _, err := radio.ReadPayload(ctx); err != nil {
+		t.Error(err)
+	} else if Equals(data, []byte{0x02, 0x01, 0x14}) == false {
+		t.Error("Unexpected payload", data)
+	} else if crc_ok {
+		t.Error("Expected CRC to be reported as invalid")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RFM69 AND ENER314RT
+
+// MiHomeMode is implemented by the ENER314RT driver
+type MiHomeMode interface {
+	Mode() sensors.MiHomeMode
+	SetMode(sensors.MiHomeMode) error
+}
+
+func Logger(t *testing.T) gopi.Logger {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return app.Logger
+	}
+}
+
+func RFM69(t *testing.T, config rfm69sim.RFM69) (rfm69sim.Simulator, sensors.RFM69) {
+	log := Logger(t)
+	if sim, err := gopi.Open(config, log); err != nil {
+		t.Fatal(err)
+	} else if radio, err := gopi.Open(rfm69.RFM69{SPI: sim.(gopi.SPI)}, log); err != nil {
+		t.Fatal(err)
+	} else {
+		return sim.(rfm69sim.Simulator), radio.(sensors.RFM69)
+	}
+	return nil, nil
+}
+
+// RFM69HW returns a high power radio
+func RFM69HW(t *testing.T, config rfm69sim.RFM69) (rfm69sim.Simulator, sensors.RFM69) {
+	log := Logger(t)
+	if sim, err := gopi.Open(config, log); err != nil {
+		t.Fatal(err)
+	} else if radio, err := gopi.Open(rfm69.RFM69{SPI: sim.(gopi.SPI), HighPower: true}, log); err != nil {
+		t.Fatal(err)
+	} else {
+		return sim.(rfm69sim.Simulator), radio.(sensors.RFM69)
+	}
+	return nil, nil
+}
+
+// RFM69DIO0 returns a radio with DIO0 connected through simulated GPIO
+func RFM69DIO0(t *testing.T, config rfm69sim.RFM69) (rfm69sim.Simulator, sensors.RFM69) {
+	log := Logger(t)
+	pin := gopi.GPIOPin(24)
+	if sim, err := gopi.Open(config, log); err != nil {
+		t.Fatal(err)
+	} else if gpio, err := gopi.Open(rfm69sim.GPIO{Radio: sim.(rfm69sim.Simulator), PinDIO0: pin}, log); err != nil {
+		t.Fatal(err)
+	} else if radio, err := gopi.Open(rfm69.RFM69{SPI: sim.(gopi.SPI), GPIO: gpio.(gopi.GPIO), PinDIO0: pin}, log); err != nil {
+		t.Fatal(err)
+	} else {
+		return sim.(rfm69sim.Simulator), radio.(sensors.RFM69)
+	}
+	return nil, nil
+}
+
+func ENER314RT(t *testing.T, radio sensors.RFM69) sensors.ENER314RT {
+	log := Logger(t)
+	if gpio, err := gopi.Open(rfm69sim.GPIO{}, log); err != nil {
+		t.Fatal(err)
+	} else if board, err := gopi.Open(ener314rt.ENER314RT{
+		GPIO:     gpio.(gopi.GPIO),
+		Radio:    radio,
+		PinReset: gopi.GPIO_PIN_NONE,
+		PinLED1:  gopi.GPIOPin(27),
+		PinLED2:  gopi.GPIOPin(22),
+	}, log); err != nil {
+		t.Fatal(err)
+	} else {
+		return board.(sensors.ENER314RT)
+	}
+	return nil
+}
+
+func Equals(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69sim
+
+import (
+	"fmt"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// GPIO configuration, for a set of logical pins which hold their
+// state, so that boards wired to the radio can be opened without
+// hardware. When Radio is set, the pin PinDIO0 follows the DIO0
+// output of the simulator and edges are emitted when watched
+type GPIO struct {
+	Radio   Simulator
+	PinDIO0 gopi.GPIOPin
+}
+
+type gpio struct {
+	log   gopi.Logger
+	lock  sync.Mutex
+	state map[gopi.GPIOPin]gopi.GPIOState
+	mode  map[gopi.GPIOPin]gopi.GPIOMode
+	edge  map[gopi.GPIOPin]gopi.GPIOEdge
+
+	event.Publisher
+}
+
+type gpio_event struct {
+	source gopi.Driver
+	pin    gopi.GPIOPin
+	edge   gopi.GPIOEdge
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config GPIO) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.RFM69Sim.GPIO.Open>{ radio=%v dio0=%v }", config.Radio, config.PinDIO0)
+
+	this := new(gpio)
+	this.log = log
+	this.state = make(map[gopi.GPIOPin]gopi.GPIOState)
+	this.mode = make(map[gopi.GPIOPin]gopi.GPIOMode)
+	this.edge = make(map[gopi.GPIOPin]gopi.GPIOEdge)
+
+	// Connect DIO0
+	if config.Radio != nil {
+		if radio, ok := config.Radio.(*sim); ok == false || config.PinDIO0 == gopi.GPIO_PIN_NONE {
+			return nil, gopi.ErrBadParameter
+		} else {
+			pin := config.PinDIO0
+			radio.connect(func(state bool) {
+				this.set_dio0(pin, state)
+			})
+		}
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *gpio) Close() error {
+	this.log.Debug("<sensors.RFM69Sim.GPIO.Close>{ }")
+
+	// Close subscribers
+	this.Publisher.Close()
+
+	// Release resources
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.state = nil
+	this.mode = nil
+	this.edge = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *gpio) String() string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return fmt.Sprintf("<sensors.RFM69Sim.GPIO>{ state=%v }", this.state)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PINS
+
+func (this *gpio) NumberOfPhysicalPins() uint {
+	return 0
+}
+
+func (this *gpio) Pins() []gopi.GPIOPin {
+	return nil
+}
+
+func (this *gpio) PhysicalPin(uint) gopi.GPIOPin {
+	return gopi.GPIO_PIN_NONE
+}
+
+func (this *gpio) PhysicalPinForPin(gopi.GPIOPin) uint {
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE
+
+func (this *gpio) ReadPin(pin gopi.GPIOPin) gopi.GPIOState {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.state[pin]
+}
+
+func (this *gpio) WritePin(pin gopi.GPIOPin, state gopi.GPIOState) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.state[pin] = state
+}
+
+func (this *gpio) GetPinMode(pin gopi.GPIOPin) gopi.GPIOMode {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.mode[pin]
+}
+
+func (this *gpio) SetPinMode(pin gopi.GPIOPin, mode gopi.GPIOMode) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.mode[pin] = mode
+}
+
+func (this *gpio) SetPullMode(gopi.GPIOPin, gopi.GPIOPull) error {
+	return nil
+}
+
+func (this *gpio) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.edge[pin] = edge
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EDGES
+
+// Set the level of a pin and emit an event if the edge is watched
+func (this *gpio) set_dio0(pin gopi.GPIOPin, state bool) {
+	this.lock.Lock()
+	if this.state == nil {
+		this.lock.Unlock()
+		return
+	}
+	edge := gopi.GPIO_EDGE_FALLING
+	this.state[pin] = gopi.GPIO_LOW
+	if state {
+		edge = gopi.GPIO_EDGE_RISING
+		this.state[pin] = gopi.GPIO_HIGH
+	}
+	watch := this.edge[pin]
+	this.lock.Unlock()
+
+	// Emit event outside of the lock
+	if watch == gopi.GPIO_EDGE_BOTH || watch == edge {
+		this.Emit(&gpio_event{this, pin, edge})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENT
+
+func (this *gpio_event) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *gpio_event) Name() string {
+	return "GPIOEvent"
+}
+
+func (this *gpio_event) Pin() gopi.GPIOPin {
+	return this.pin
+}
+
+func (this *gpio_event) Edge() gopi.GPIOEdge {
+	return this.edge
+}
+
+func (this *gpio_event) String() string {
+	return fmt.Sprintf("<sensors.RFM69Sim.GPIOEvent>{ pin=%v edge=%v }", this.pin, this.edge)
+}
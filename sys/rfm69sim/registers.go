@@ -0,0 +1,436 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69sim
+
+import (
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/rfm69"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Registers which have behaviour in the simulator, mirrored
+	// from the driver register map
+	RFM_REG_FIFO          = uint8(rfm69.RFM_REG_FIFO)
+	RFM_REG_OPMODE        = uint8(rfm69.RFM_REG_OPMODE)
+	RFM_REG_OSC1          = uint8(rfm69.RFM_REG_OSC1)
+	RFM_REG_VERSION       = uint8(rfm69.RFM_REG_VERSION)
+	RFM_REG_LNA           = uint8(rfm69.RFM_REG_LNA)
+	RFM_REG_AFCFEI        = uint8(rfm69.RFM_REG_AFCFEI)
+	RFM_REG_AFCMSB        = uint8(rfm69.RFM_REG_AFCMSB)
+	RFM_REG_AFCLSB        = uint8(rfm69.RFM_REG_AFCLSB)
+	RFM_REG_FEIMSB        = uint8(rfm69.RFM_REG_FEIMSB)
+	RFM_REG_FEILSB        = uint8(rfm69.RFM_REG_FEILSB)
+	RFM_REG_RSSICONFIG    = uint8(rfm69.RFM_REG_RSSICONFIG)
+	RFM_REG_RSSIVALUE     = uint8(rfm69.RFM_REG_RSSIVALUE)
+	RFM_REG_IRQFLAGS1     = uint8(rfm69.RFM_REG_IRQFLAGS1)
+	RFM_REG_IRQFLAGS2     = uint8(rfm69.RFM_REG_IRQFLAGS2)
+	RFM_REG_FIFOTHRESH    = uint8(rfm69.RFM_REG_FIFOTHRESH)
+	RFM_REG_PACKETCONFIG1 = uint8(rfm69.RFM_REG_PACKETCONFIG1)
+	RFM_REG_PAYLOADLENGTH = uint8(rfm69.RFM_REG_PAYLOADLENGTH)
+	RFM_REG_NODEADRS      = uint8(rfm69.RFM_REG_NODEADRS)
+	RFM_REG_BROADCASTADRS = uint8(rfm69.RFM_REG_BROADCASTADRS)
+	RFM_REG_DIOMAPPING1   = uint8(rfm69.RFM_REG_DIOMAPPING1)
+	RFM_REG_TEMP1         = uint8(rfm69.RFM_REG_TEMP1)
+	RFM_REG_TEMP2         = uint8(rfm69.RFM_REG_TEMP2)
+	RFM_REG_MAX           = uint8(rfm69.RFM_REG_MAX)
+	RFM_REG_WRITE         = uint8(rfm69.RFM_REG_WRITE)
+	RFM_REG_COUNT         = int(RFM_REG_MAX) + 1
+	RFM_OPMODE_LISTENABRT = uint8(0x20)
+	RFM_AFCFEI_RW         = uint8(0x0C)
+	RFM_AFCFEI_DONE       = uint8(0x50)
+	RFM_AFCFEI_CLEAR      = uint8(0x02)
+	RFM_AFCFEI_FEISTART   = uint8(0x20)
+	RFM_OSC1_RCCALDONE    = uint8(0x40)
+	RFM_RSSICONFIG_START  = uint8(0x01)
+	RFM_TEMP1_START       = uint8(0x08)
+)
+
+var (
+	// Power-on register values, from the RFM69 datasheet
+	power_on = map[uint8]uint8{
+		0x01: 0x04, 0x02: 0x00, 0x03: 0x1A, 0x04: 0x0B, 0x05: 0x00, 0x06: 0x52,
+		0x07: 0xE4, 0x08: 0xC0, 0x09: 0x00, 0x0A: 0x41, 0x0B: 0x00, 0x0C: 0x02,
+		0x0D: 0x92, 0x0E: 0xF5, 0x0F: 0x20, 0x10: 0x24, 0x11: 0x9F, 0x12: 0x09,
+		0x13: 0x1A, 0x14: 0x40, 0x15: 0xB0, 0x16: 0x7B, 0x17: 0x9B, 0x18: 0x88,
+		0x19: 0x55, 0x1A: 0x8B, 0x1B: 0x40, 0x1C: 0x80, 0x1D: 0x06, 0x1E: 0x10,
+		0x23: 0x02, 0x24: 0xFF, 0x26: 0x07, 0x27: 0x80, 0x29: 0xE4, 0x2D: 0x03,
+		0x2E: 0x98, 0x2F: 0x01, 0x30: 0x01, 0x31: 0x01, 0x32: 0x01, 0x33: 0x01,
+		0x34: 0x01, 0x35: 0x01, 0x36: 0x01, 0x37: 0x10, 0x38: 0x40, 0x3C: 0x8F,
+		0x3D: 0x02, 0x4E: 0x01, 0x58: 0x1B, 0x5A: 0x55, 0x5C: 0x70, 0x6F: 0x30,
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// RESET
+
+func (this *sim) reset() {
+	for i := range this.regs {
+		this.regs[i] = power_on[uint8(i)]
+	}
+	this.fifo = make([]byte, 0, rfm69.RFM_FIFO_SIZE)
+	this.fifo_overrun = false
+	this.payload_ready = false
+	this.crc_ok = false
+	this.packet_sent = false
+	this.drained = time.Time{}
+	this.rx = nil
+	this.tx = nil
+	this.receiving = nil
+	this.sending = nil
+	this.remaining = 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ REGISTERS
+
+func (this *sim) read(reg uint8) uint8 {
+	switch reg {
+	case RFM_REG_FIFO:
+		if len(this.fifo) == 0 {
+			return 0
+		}
+		value := this.fifo[0]
+		this.fifo = this.fifo[1:]
+		if len(this.fifo) == 0 && this.payload_ready {
+			// PayloadReady and CrcOk are cleared when the FIFO is empty
+			this.payload_ready = false
+			this.crc_ok = false
+			this.drained = time.Now()
+		}
+		return value
+	case RFM_REG_IRQFLAGS1:
+		return this.irqflags1()
+	case RFM_REG_IRQFLAGS2:
+		return this.irqflags2()
+	default:
+		return this.regs[reg]
+	}
+}
+
+func (this *sim) irqflags1() uint8 {
+	value := rfm69.RFM_IRQFLAGS1_MODEREADY
+	switch this.mode() {
+	case sensors.RFM_MODE_RX:
+		value |= rfm69.RFM_IRQFLAGS1_RXREADY | rfm69.RFM_IRQFLAGS1_PLLLOCK
+		if this.payload_ready || this.receiving != nil {
+			value |= rfm69.RFM_IRQFLAGS1_SYNCADDRESSMATCH
+		}
+	case sensors.RFM_MODE_TX:
+		value |= rfm69.RFM_IRQFLAGS1_TXREADY | rfm69.RFM_IRQFLAGS1_PLLLOCK
+	case sensors.RFM_MODE_FS:
+		value |= rfm69.RFM_IRQFLAGS1_PLLLOCK
+	}
+	return value
+}
+
+func (this *sim) irqflags2() uint8 {
+	value := uint8(0)
+	if len(this.fifo) >= rfm69.RFM_FIFO_SIZE {
+		value |= rfm69.RFM_IRQFLAGS2_FIFOFULL
+	}
+	if len(this.fifo) > 0 {
+		value |= rfm69.RFM_IRQFLAGS2_FIFONOTEMPTY
+	}
+	if len(this.fifo) > int(this.fifo_threshold()) {
+		value |= rfm69.RFM_IRQFLAGS2_FIFOLEVEL
+	}
+	if this.fifo_overrun {
+		value |= rfm69.RFM_IRQFLAGS2_FIFOOVERRUN
+	}
+	if this.packet_sent {
+		value |= rfm69.RFM_IRQFLAGS2_PACKETSENT
+	}
+	if this.payload_ready {
+		value |= rfm69.RFM_IRQFLAGS2_PAYLOADREADY
+		if this.crc_ok {
+			value |= rfm69.RFM_IRQFLAGS2_CRCOK
+		}
+	}
+	return value
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WRITE REGISTERS
+
+func (this *sim) write(reg, value uint8) {
+	switch reg {
+	case RFM_REG_FIFO:
+		if len(this.fifo) >= rfm69.RFM_FIFO_SIZE {
+			this.fifo_overrun = true
+		} else {
+			this.fifo = append(this.fifo, value)
+		}
+	case RFM_REG_OPMODE:
+		mode := this.mode()
+		this.regs[reg] = value &^ RFM_OPMODE_LISTENABRT
+		if mode != this.mode() {
+			this.set_mode(mode)
+		}
+	case RFM_REG_IRQFLAGS2:
+		// Writing FifoOverrun clears the FIFO
+		if value&rfm69.RFM_IRQFLAGS2_FIFOOVERRUN != 0 {
+			this.clear_fifo()
+		}
+	case RFM_REG_LNA:
+		// Current gain is read-only, and follows the gain setting
+		gain := value & uint8(sensors.RFM_LNA_GAIN_MAX)
+		if gain == uint8(sensors.RFM_LNA_GAIN_AUTO) {
+			gain = uint8(sensors.RFM_LNA_GAIN_G1)
+		}
+		this.regs[reg] = value&0xC7 | gain<<3
+	case RFM_REG_AFCFEI:
+		// Measurements complete immediately, correction is always zero
+		// and the frequency error is the configured value
+		this.regs[reg] = value&RFM_AFCFEI_RW | this.regs[reg]&RFM_AFCFEI_DONE
+		if value&RFM_AFCFEI_CLEAR != 0 {
+			this.regs[RFM_REG_AFCMSB] = 0
+			this.regs[RFM_REG_AFCLSB] = 0
+		}
+		if value&^(RFM_AFCFEI_RW|RFM_AFCFEI_CLEAR) != 0 {
+			this.regs[reg] |= RFM_AFCFEI_DONE
+			this.regs[RFM_REG_FEIMSB] = 0
+			this.regs[RFM_REG_FEILSB] = 0
+		}
+		if value&RFM_AFCFEI_FEISTART != 0 {
+			this.regs[RFM_REG_FEIMSB] = uint8(uint16(this.fei) >> 8)
+			this.regs[RFM_REG_FEILSB] = uint8(uint16(this.fei))
+		}
+	case RFM_REG_OSC1:
+		// Calibration completes immediately
+		this.regs[reg] = power_on[reg] | RFM_OSC1_RCCALDONE
+	case RFM_REG_RSSICONFIG:
+		if value&RFM_RSSICONFIG_START != 0 {
+			this.regs[RFM_REG_RSSIVALUE] = rssi_value(this.rssi)
+		}
+	case RFM_REG_TEMP1:
+		if value&RFM_TEMP1_START != 0 {
+			this.regs[RFM_REG_TEMP2] = uint8(rfm69.RFM_TEMP_COEF - this.temperature)
+		}
+	case RFM_REG_VERSION, RFM_REG_IRQFLAGS1, RFM_REG_RSSIVALUE, RFM_REG_TEMP2:
+		// Read-only registers
+	case RFM_REG_AFCMSB, RFM_REG_AFCLSB, RFM_REG_FEIMSB, RFM_REG_FEILSB:
+		// Read-only registers
+	default:
+		this.regs[reg] = value
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RADIO
+
+// Return the current device mode
+func (this *sim) mode() sensors.RFMMode {
+	return sensors.RFMMode(this.regs[RFM_REG_OPMODE]>>2) & sensors.RFM_MODE_MAX
+}
+
+// Return the FIFO threshold
+func (this *sim) fifo_threshold() uint8 {
+	return this.regs[RFM_REG_FIFOTHRESH] & 0x7F
+}
+
+// Return the TX start condition
+func (this *sim) tx_start() sensors.RFMTXStart {
+	return sensors.RFMTXStart(this.regs[RFM_REG_FIFOTHRESH]>>7) & sensors.RFM_TXSTART_MAX
+}
+
+// Return the length of the packet at the start of the FIFO, which is
+// read from the length byte in variable length format, or zero if
+// the length is unlimited
+func (this *sim) packet_length() int {
+	if this.regs[RFM_REG_PACKETCONFIG1]&0x80 == 0 {
+		return int(this.regs[RFM_REG_PAYLOADLENGTH])
+	} else if len(this.fifo) == 0 {
+		return 0
+	} else {
+		return int(this.fifo[0]) + 1
+	}
+}
+
+// Transition between modes. The FIFO is cleared when leaving RX
+// or TX mode and PacketSent is cleared when leaving TX mode
+func (this *sim) set_mode(from sensors.RFMMode) {
+	if from == sensors.RFM_MODE_RX || from == sensors.RFM_MODE_TX {
+		this.clear_fifo()
+	}
+	if from == sensors.RFM_MODE_TX {
+		this.packet_sent = false
+	}
+}
+
+// Empty the FIFO and clear associated flags, discarding any packet
+// larger than the FIFO which is being sent or received
+func (this *sim) clear_fifo() {
+	this.fifo = this.fifo[:0]
+	this.fifo_overrun = false
+	this.receiving = nil
+	this.sending = nil
+	this.remaining = 0
+	if this.payload_ready {
+		this.payload_ready = false
+		this.crc_ok = false
+		this.drained = time.Now()
+	}
+}
+
+// Called after every SPI transaction to move packets in and out of the FIFO
+func (this *sim) update() {
+	switch this.mode() {
+	case sensors.RFM_MODE_TX:
+		this.transmit()
+	case sensors.RFM_MODE_RX:
+		this.receive()
+	}
+}
+
+// In TX mode, once the start condition is met the FIFO is sent
+// immediately as a single packet. When the FIFO is full and the packet
+// length is larger than the FIFO, the packet is sent as the FIFO is
+// topped up until the whole packet has been sent
+func (this *sim) transmit() {
+	if len(this.fifo) == 0 {
+		return
+	} else if this.sending != nil {
+		size := len(this.fifo)
+		if size > this.remaining {
+			size = this.remaining
+		}
+		this.sending = append(this.sending, this.fifo[:size]...)
+		this.fifo = append(this.fifo[:0], this.fifo[size:]...)
+		if this.remaining -= size; this.remaining == 0 {
+			this.tx = append(this.tx, this.sending)
+			this.sending = nil
+			this.packet_sent = true
+		}
+		return
+	} else if this.tx_start() == sensors.RFM_TXSTART_FIFOLEVEL && len(this.fifo) <= int(this.fifo_threshold()) {
+		return
+	} else if length := this.packet_length(); len(this.fifo) == rfm69.RFM_FIFO_SIZE && length > rfm69.RFM_FIFO_SIZE {
+		this.sending = append([]byte(nil), this.fifo...)
+		this.remaining = length - len(this.fifo)
+		this.fifo = this.fifo[:0]
+		return
+	}
+	this.tx = append(this.tx, append([]byte(nil), this.fifo...))
+	this.fifo = this.fifo[:0]
+	this.packet_sent = true
+}
+
+// In RX mode, the next queued packet is placed in the FIFO once the
+// previous payload has been read and the inter-packet gap has elapsed.
+// A packet larger than the FIFO is placed in the FIFO as it's drained.
+// The RSSI is set for the packet
+func (this *sim) receive() {
+	if this.receiving != nil {
+		size := rfm69.RFM_FIFO_SIZE - len(this.fifo)
+		if size > len(this.receiving.data) {
+			size = len(this.receiving.data)
+		}
+		this.fifo = append(this.fifo, this.receiving.data[:size]...)
+		if this.receiving.data = this.receiving.data[size:]; len(this.receiving.data) == 0 {
+			this.payload_ready_for(this.receiving)
+			this.receiving = nil
+		}
+		return
+	} else if len(this.rx) == 0 || this.payload_ready || len(this.fifo) > 0 {
+		return
+	} else if this.drained.IsZero() == false && time.Since(this.drained) < this.gap {
+		return
+	}
+	next := this.rx[0]
+	this.rx = this.rx[1:]
+	if this.discard(next) {
+		return
+	} else if len(next.data) > rfm69.RFM_FIFO_SIZE {
+		this.fifo = append(this.fifo[:0], next.data[:rfm69.RFM_FIFO_SIZE]...)
+		this.receiving = &packet{
+			data:   next.data[rfm69.RFM_FIFO_SIZE:],
+			rssi:   next.rssi,
+			crc_ok: next.crc_ok,
+		}
+		return
+	}
+	this.fifo = append(this.fifo[:0], next.data...)
+	this.payload_ready_for(next)
+}
+
+// Return true if a packet is discarded by the radio, when the address
+// byte doesn't pass the address filter, or when the CRC is wrong and
+// the FIFO is cleared automatically
+func (this *sim) discard(next *packet) bool {
+	config := this.regs[RFM_REG_PACKETCONFIG1]
+	if config&0x10 != 0 && config&0x08 == 0 && next.crc_ok == false {
+		return true
+	}
+
+	// The address follows the length byte in variable length format
+	offset := 0
+	if config&0x80 != 0 {
+		offset = 1
+	}
+	if len(next.data) <= offset {
+		return false
+	}
+	address := next.data[offset]
+	switch sensors.RFMPacketFilter(config) & sensors.RFM_PACKET_FILTER_MAX {
+	case sensors.RFM_PACKET_FILTER_NODE:
+		return address != this.regs[RFM_REG_NODEADRS]
+	case sensors.RFM_PACKET_FILTER_BROADCAST:
+		return address != this.regs[RFM_REG_NODEADRS] && address != this.regs[RFM_REG_BROADCASTADRS]
+	default:
+		return false
+	}
+}
+
+// Set PayloadReady, and the CRC and RSSI for a packet
+func (this *sim) payload_ready_for(next *packet) {
+	this.payload_ready = true
+	this.crc_ok = next.crc_ok
+	this.regs[RFM_REG_RSSIVALUE] = rssi_value(next.rssi)
+}
+
+// Return the level of DIO0 in packet mode, which depends on the mode
+// and the mapping in RFM_REG_DIOMAPPING1
+func (this *sim) dio0() bool {
+	mapping := this.regs[RFM_REG_DIOMAPPING1] >> 6
+	switch this.mode() {
+	case sensors.RFM_MODE_RX:
+		switch mapping {
+		case rfm69.RFM_DIO0_PACKETSENT:
+			return this.payload_ready && this.crc_ok
+		case rfm69.RFM_DIO0_PAYLOADREADY:
+			return this.payload_ready
+		}
+	case sensors.RFM_MODE_TX:
+		if mapping == rfm69.RFM_DIO0_PACKETSENT {
+			return this.packet_sent
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DATA CONVERSIONS
+
+// Convert dBm to RSSIVALUE register
+func rssi_value(rssi float32) uint8 {
+	if rssi >= 0 {
+		return 0
+	} else if rssi <= -127.5 {
+		return 0xFF
+	} else {
+		return uint8(-rssi * 2)
+	}
+}
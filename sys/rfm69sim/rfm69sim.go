@@ -0,0 +1,308 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69sim
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors/sys/rfm69"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Configuration
+type RFM69 struct {
+	// Die temperature in celcius returned by the temperature sensor
+	Temperature int
+
+	// Signal strength in dBm measured when no packet is being received
+	RSSI float32
+
+	// Minimum time between one payload being read from the FIFO
+	// and the next received packet being delivered
+	Gap time.Duration
+
+	// Frequency error in Hertz returned by the FEI measurement
+	FrequencyError float64
+}
+
+// Simulator emulates the register map of a HopeRF RFM69 radio
+// behind a gopi.SPI interface
+type Simulator interface {
+	gopi.SPI
+
+	// Reset registers to power-on values and discard packets
+	Reset()
+
+	// Return the current value of a register
+	Register(reg uint8) uint8
+
+	// Queue a packet for reception, with signal strength in dBm and
+	// whether the CRC is reported as valid. Packets are delivered to
+	// the FIFO in order once the radio is in RX mode, and packets
+	// larger than the FIFO are delivered as the FIFO is drained. Packets
+	// which don't pass the address filter, or which fail the CRC when
+	// CRC auto-clear is on, are discarded
+	Inject(data []byte, rssi float32, crc_ok bool) error
+
+	// Return packets transmitted since the last call
+	Transmitted() [][]byte
+
+	// Set die temperature in celcius
+	SetTemperature(celcius int)
+}
+
+// sim driver
+type sim struct {
+	log  gopi.Logger
+	lock sync.Mutex
+
+	spi_mode      gopi.SPIMode
+	speed         uint32
+	bits_per_word uint8
+	temperature   int
+	rssi          float32
+	gap           time.Duration
+	fei           int16
+
+	regs          [RFM_REG_COUNT]uint8
+	fifo          []byte
+	fifo_overrun  bool
+	payload_ready bool
+	crc_ok        bool
+	packet_sent   bool
+	drained       time.Time
+	rx            []*packet
+	tx            [][]byte
+
+	// Packets larger than the FIFO, which are moved in and out
+	// of the FIFO as it's drained or topped up
+	receiving *packet
+	sending   []byte
+	remaining int
+
+	// DIO0 level and function called when it changes
+	dio0_state bool
+	dio0_func  func(bool)
+	done       chan struct{}
+}
+
+type packet struct {
+	data   []byte
+	rssi   float32
+	crc_ok bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	RFM_DEFAULT_RSSI  = -110.0
+	RFM_DEFAULT_TEMP  = 20
+	RFM_DEFAULT_SPEED = 4000000
+	RFM_DEFAULT_GAP   = 10 * time.Millisecond
+
+	// Interval between updates when DIO0 is connected
+	RFM_DIO0_INTERVAL = time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config RFM69) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.RFM69Sim.Open>{ temperature=%v rssi=%v gap=%v frequency_error=%v }", config.Temperature, config.RSSI, config.Gap, config.FrequencyError)
+
+	this := new(sim)
+	this.log = log
+	this.spi_mode = gopi.SPI_MODE_0
+	this.speed = RFM_DEFAULT_SPEED
+	this.bits_per_word = 8
+
+	if config.Temperature == 0 {
+		this.temperature = RFM_DEFAULT_TEMP
+	} else {
+		this.temperature = config.Temperature
+	}
+	if config.RSSI == 0 {
+		this.rssi = RFM_DEFAULT_RSSI
+	} else if config.RSSI > 0 {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.rssi = config.RSSI
+	}
+
+	if config.Gap == 0 {
+		this.gap = RFM_DEFAULT_GAP
+	} else {
+		this.gap = config.Gap
+	}
+
+	// Frequency error is measured in steps of FXOSC / 2^19
+	if fei := math.Round(config.FrequencyError * float64(1<<19) / (rfm69.RFM_FXOSC_MHZ * 1e6)); fei < math.MinInt16 || fei > math.MaxInt16 {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.fei = int16(fei)
+	}
+
+	// Set power-on register values
+	this.reset()
+
+	// Return success
+	return this, nil
+}
+
+func (this *sim) Close() error {
+	this.log.Debug("<sensors.RFM69Sim.Close>{ }")
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Stop updating DIO0
+	if this.done != nil {
+		close(this.done)
+		this.done = nil
+	}
+
+	// Release resources
+	this.fifo = nil
+	this.rx = nil
+	this.tx = nil
+	this.dio0_func = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *sim) String() string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return fmt.Sprintf("<sensors.RFM69Sim>{ opmode=0x%02X fifo=%v rx_queued=%v tx_sent=%v }", this.regs[RFM_REG_OPMODE], strings.ToUpper(hex.EncodeToString(this.fifo)), len(this.rx), len(this.tx))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SIMULATOR
+
+func (this *sim) Reset() {
+	this.log.Debug("<sensors.RFM69Sim.Reset>{ }")
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.reset()
+}
+
+func (this *sim) Register(reg uint8) uint8 {
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.regs[reg&RFM_REG_MAX]
+}
+
+func (this *sim) Inject(data []byte, rssi float32, crc_ok bool) error {
+	this.log.Debug("<sensors.RFM69Sim.Inject>{ data=%v rssi=%v crc_ok=%v }", strings.ToUpper(hex.EncodeToString(data)), rssi, crc_ok)
+
+	// Check parameters
+	if len(data) == 0 || len(data) > rfm69.RFM_PACKET_MAX || rssi > 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Queue the packet
+	this.rx = append(this.rx, &packet{
+		data:   append([]byte(nil), data...),
+		rssi:   rssi,
+		crc_ok: crc_ok,
+	})
+
+	// Success
+	return nil
+}
+
+func (this *sim) Transmitted() [][]byte {
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	tx := this.tx
+	this.tx = nil
+	return tx
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DIO0
+
+// connect calls a function whenever the level of DIO0 changes. Since the
+// radio may not be accessed while waiting for an interrupt, packets are
+// moved in and out of the FIFO in the background
+func (this *sim) connect(fn func(bool)) {
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.done != nil {
+		close(this.done)
+	}
+	this.dio0_func = fn
+	this.done = make(chan struct{})
+
+	go func(done <-chan struct{}) {
+		ticker := time.NewTicker(RFM_DIO0_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				this.update_dio0()
+			}
+		}
+	}(this.done)
+}
+
+// update_dio0 moves packets in and out of the FIFO and calls the
+// connected function without holding the lock when DIO0 changes
+func (this *sim) update_dio0() {
+	this.lock.Lock()
+	this.update()
+	state, fn := this.dio0(), this.dio0_func
+	changed := state != this.dio0_state
+	this.dio0_state = state
+	this.lock.Unlock()
+
+	if changed && fn != nil {
+		fn(state)
+	}
+}
+
+func (this *sim) SetTemperature(celcius int) {
+	this.log.Debug("<sensors.RFM69Sim.SetTemperature>{ celcius=%v }", celcius)
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.temperature = celcius
+}
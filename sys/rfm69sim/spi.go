@@ -0,0 +1,105 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rfm69sim
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// SPI PARAMETERS
+
+func (this *sim) Mode() gopi.SPIMode {
+	return this.spi_mode
+}
+
+func (this *sim) MaxSpeedHz() uint32 {
+	return this.speed
+}
+
+func (this *sim) BitsPerWord() uint8 {
+	return this.bits_per_word
+}
+
+func (this *sim) SetMode(mode gopi.SPIMode) error {
+	this.spi_mode = mode
+	return nil
+}
+
+func (this *sim) SetMaxSpeedHz(speed uint32) error {
+	if speed == 0 {
+		return gopi.ErrBadParameter
+	}
+	this.speed = speed
+	return nil
+}
+
+func (this *sim) SetBitsPerWord(bits uint8) error {
+	if bits != 8 {
+		return gopi.ErrBadParameter
+	}
+	this.bits_per_word = bits
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE
+
+// Transfer reads registers when the first byte is a register address,
+// or writes registers when the write bit is set. The address is
+// incremented for every byte except for FIFO access
+func (this *sim) Transfer(send []byte) ([]byte, error) {
+	if len(send) == 0 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Mutex lock
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Move packets in and out of the FIFO
+	this.update()
+
+	recv := make([]byte, len(send))
+	reg := send[0] & RFM_REG_MAX
+	for i := 1; i < len(send); i++ {
+		if send[0]&RFM_REG_WRITE != 0 {
+			this.write(reg, send[i])
+		} else {
+			recv[i] = this.read(reg)
+		}
+		if reg != RFM_REG_FIFO {
+			reg = (reg + 1) & RFM_REG_MAX
+		}
+	}
+
+	// Move packets in and out of the FIFO
+	this.update()
+
+	return recv, nil
+}
+
+func (this *sim) Read(length uint32) ([]byte, error) {
+	return nil, gopi.ErrNotImplemented
+}
+
+func (this *sim) Write(send []byte) error {
+	if len(send) == 0 {
+		return gopi.ErrBadParameter
+	} else if send[0]&RFM_REG_WRITE == 0 {
+		return gopi.ErrBadParameter
+	} else if _, err := this.Transfer(send); err != nil {
+		return err
+	}
+
+	// Success
+	return nil
+}
@@ -0,0 +1,35 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package schedule
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register heating schedules
+	gopi.RegisterModule(gopi.Module{
+		Name: "sensors/schedule",
+		Type: gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagString("schedule.path", FILENAME_DEFAULT, "Path to heating schedules file, relative to home directory")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			path, _ := app.AppFlags.GetString("schedule.path")
+			return gopi.Open(Schedule{
+				Path: path,
+			}, app.Logger)
+		},
+	})
+}
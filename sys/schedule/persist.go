@@ -0,0 +1,236 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	sensors "github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+/*
+ The schedules file has days as names, times of day as hours and
+ minutes and override types as names, so that it can be edited by hand:
+
+ { "schedules": [ {
+     "name": "lounge", "sensors": [ 1234 ],
+     "periods": [ { "day": "monday", "start": "07:00", "celcius": 20 }, ... ],
+     "overrides": [ { "type": "holiday", "start": "...", "end": "...", "celcius": 12 } ]
+ } ] }
+*/
+
+type json_file struct {
+	Schedules []*json_schedule `json:"schedules"`
+}
+
+type json_schedule struct {
+	Name      string          `json:"name"`
+	Sensors   []uint32        `json:"sensors"`
+	Periods   []json_period   `json:"periods"`
+	Overrides []json_override `json:"overrides,omitempty"`
+}
+
+type json_period struct {
+	Day     string  `json:"day"`
+	Start   string  `json:"start"`
+	Celcius float64 `json:"celcius"`
+}
+
+type json_override struct {
+	Type    string    `json:"type"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Celcius float64   `json:"celcius"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE
+
+// read reads schedules from a path, or creates an empty file if it
+// doesn't exist
+func (this *schedule) read(path string) error {
+	this.log.Debug2("<sensors.schedule>read{ path=%v }", path)
+
+	fh, err := os.Open(path)
+	if os.IsNotExist(err) {
+		if fh, err := os.Create(path); err != nil {
+			return err
+		} else {
+			return fh.Close()
+		}
+	} else if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	// An empty file has no schedules
+	var file json_file
+	if stat, err := fh.Stat(); err != nil {
+		return err
+	} else if stat.Size() == 0 {
+		return nil
+	} else if err := json.NewDecoder(fh).Decode(&file); err != nil {
+		return err
+	}
+	for _, value := range file.Schedules {
+		if value_, err := from_json(value); err != nil {
+			return fmt.Errorf("Schedule %v: %v", value.Name, err)
+		} else if _, exists := this.schedules[value_.Name]; exists {
+			return fmt.Errorf("Duplicate schedule: %v", value.Name)
+		} else {
+			for _, other := range this.schedules {
+				for _, sensor := range value_.Sensors {
+					if has_sensor(other, sensor) {
+						return fmt.Errorf("Schedule %v: Duplicate sensor: 0x%06X", value.Name, sensor)
+					}
+				}
+			}
+			sort_periods(value_)
+			this.schedules[value_.Name] = value_
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// write writes schedules to the path, which is called with the lock
+// held. The schedules are written to a temporary file which then
+// replaces the file, so that the file isn't left partly written
+func (this *schedule) write(schedules map[string]*sensors.MiHomeSchedule) error {
+	if this.path == "" {
+		return nil
+	}
+	this.log.Debug2("<sensors.schedule>write{ path=%v }", this.path)
+
+	names := make([]string, 0, len(schedules))
+	for name := range schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	file := json_file{make([]*json_schedule, 0, len(names))}
+	for _, name := range names {
+		file.Schedules = append(file.Schedules, to_json(schedules[name]))
+	}
+	fh, err := ioutil.TempFile(filepath.Dir(this.path), filepath.Base(this.path)+".")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fh)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(file); err != nil {
+		fh.Close()
+		os.Remove(fh.Name())
+		return err
+	} else if err := fh.Close(); err != nil {
+		os.Remove(fh.Name())
+		return err
+	} else if err := os.Rename(fh.Name(), this.path); err != nil {
+		os.Remove(fh.Name())
+		return err
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONVERT
+
+func to_json(value *sensors.MiHomeSchedule) *json_schedule {
+	other := &json_schedule{
+		Name:    value.Name,
+		Sensors: value.Sensors,
+		Periods: make([]json_period, len(value.Periods)),
+	}
+	for i, period := range value.Periods {
+		other.Periods[i] = json_period{
+			Day:     strings.ToLower(period.Day.String()),
+			Start:   fmt.Sprintf("%02d:%02d", int(period.Start/time.Hour), int(period.Start%time.Hour/time.Minute)),
+			Celcius: period.Celcius,
+		}
+	}
+	for _, override := range value.Overrides {
+		other.Overrides = append(other.Overrides, json_override{
+			Type:    strings.ToLower(strings.TrimPrefix(override.Type.String(), "MIHOME_OVERRIDE_")),
+			Start:   override.Start,
+			End:     override.End,
+			Celcius: override.Celcius,
+		})
+	}
+	return other
+}
+
+func from_json(value *json_schedule) (*sensors.MiHomeSchedule, error) {
+	other := &sensors.MiHomeSchedule{
+		Name:    value.Name,
+		Sensors: value.Sensors,
+		Periods: make([]sensors.MiHomePeriod, len(value.Periods)),
+	}
+	for i, period := range value.Periods {
+		if day, err := weekday_from_string(period.Day); err != nil {
+			return nil, err
+		} else if start, err := time.Parse("15:04", period.Start); err != nil {
+			return nil, err
+		} else {
+			other.Periods[i] = sensors.MiHomePeriod{
+				Day:     day,
+				Start:   time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+				Celcius: period.Celcius,
+			}
+		}
+	}
+	for _, override := range value.Overrides {
+		if type_, err := override_from_string(override.Type); err != nil {
+			return nil, err
+		} else {
+			other.Overrides = append(other.Overrides, sensors.MiHomeOverridePeriod{
+				Type:    type_,
+				Start:   override.Start,
+				End:     override.End,
+				Celcius: override.Celcius,
+			})
+		}
+	}
+	if err := check_schedule(other); err != nil {
+		return nil, err
+	}
+	return other, nil
+}
+
+func weekday_from_string(value string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(value, day.String()) {
+			return day, nil
+		}
+	}
+	return 0, gopi.ErrBadParameter
+}
+
+func override_from_string(value string) (sensors.MiHomeOverride, error) {
+	for type_ := sensors.MIHOME_OVERRIDE_HOLIDAY; type_ <= sensors.MIHOME_OVERRIDE_MAX; type_++ {
+		if strings.EqualFold("MIHOME_OVERRIDE_"+value, type_.String()) {
+			return type_, nil
+		}
+	}
+	return sensors.MIHOME_OVERRIDE_NONE, gopi.ErrBadParameter
+}
@@ -0,0 +1,364 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	sensors "github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Schedule is the configuration for heating schedules, which are
+// written to the path when changed, or not written if the path is empty
+type Schedule struct {
+	Path string
+}
+
+type schedule struct {
+	log       gopi.Logger
+	path      string
+	schedules map[string]*sensors.MiHomeSchedule
+
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	FILENAME_DEFAULT = "schedules.json"
+
+	// Range of target temperatures
+	CELCIUS_MIN = 0.0
+	CELCIUS_MAX = 30.0
+
+	// Duration of a day
+	DAY = 24 * time.Hour
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Schedule) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.schedule>Open{ path=%v }", strconv.Quote(config.Path))
+
+	this := new(schedule)
+	this.log = log
+	this.schedules = make(map[string]*sensors.MiHomeSchedule)
+
+	// Read the schedules, or create the file if it doesn't exist
+	if config.Path != "" {
+		if path, err := schedule_path(config.Path); err != nil {
+			return nil, err
+		} else if err := this.read(path); err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		} else {
+			this.path = path
+		}
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *schedule) Close() error {
+	this.log.Debug("<sensors.schedule>Close{ path=%v }", strconv.Quote(this.path))
+
+	// Release resources
+	this.schedules = nil
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *schedule) String() string {
+	this.Lock()
+	defer this.Unlock()
+	return fmt.Sprintf("<sensors.schedule>{ path=%v schedules=%v }", strconv.Quote(this.path), len(this.schedules))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (this *schedule) Schedules() []*sensors.MiHomeSchedule {
+	this.Lock()
+	defer this.Unlock()
+
+	names := make([]string, 0, len(this.schedules))
+	for name := range this.schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schedules := make([]*sensors.MiHomeSchedule, 0, len(names))
+	for _, name := range names {
+		schedules = append(schedules, schedule_copy(this.schedules[name]))
+	}
+	return schedules
+}
+
+func (this *schedule) SetSchedule(value *sensors.MiHomeSchedule) error {
+	this.log.Debug2("<sensors.schedule>SetSchedule{ schedule=%v }", value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	// Check the schedule, and that the sensors aren't in other schedules
+	if err := check_schedule(value); err != nil {
+		return err
+	}
+	for _, other := range this.schedules {
+		if other.Name == value.Name {
+			continue
+		}
+		for _, sensor := range value.Sensors {
+			if has_sensor(other, sensor) {
+				return gopi.ErrBadParameter
+			}
+		}
+	}
+
+	// Set the schedule, with the periods in order
+	value = schedule_copy(value)
+	sort_periods(value)
+	return this.update(value.Name, value)
+}
+
+func (this *schedule) DeleteSchedule(name string) error {
+	this.log.Debug2("<sensors.schedule>DeleteSchedule{ name=%v }", strconv.Quote(name))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if _, exists := this.schedules[name]; exists == false {
+		return gopi.ErrNotFound
+	} else {
+		return this.update(name, nil)
+	}
+}
+
+func (this *schedule) SetOverride(name string, override sensors.MiHomeOverridePeriod) error {
+	this.log.Debug2("<sensors.schedule>SetOverride{ name=%v override=%v }", strconv.Quote(name), override)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if value, exists := this.schedules[name]; exists == false {
+		return gopi.ErrNotFound
+	} else if err := check_override(override); err != nil {
+		return err
+	} else {
+		// Remove overrides which have ended, then append
+		now := time.Now()
+		value = schedule_copy(value)
+		overrides := make([]sensors.MiHomeOverridePeriod, 0, len(value.Overrides)+1)
+		for _, other := range value.Overrides {
+			if other.End.After(now) {
+				overrides = append(overrides, other)
+			}
+		}
+		value.Overrides = append(overrides, override)
+		return this.update(name, value)
+	}
+}
+
+func (this *schedule) ClearOverrides(name string) error {
+	this.log.Debug2("<sensors.schedule>ClearOverrides{ name=%v }", strconv.Quote(name))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if value, exists := this.schedules[name]; exists == false {
+		return gopi.ErrNotFound
+	} else {
+		value = schedule_copy(value)
+		value.Overrides = nil
+		return this.update(name, value)
+	}
+}
+
+func (this *schedule) Targets(ts time.Time) map[uint32]float64 {
+	this.Lock()
+	defer this.Unlock()
+
+	targets := make(map[uint32]float64)
+	for _, value := range this.schedules {
+		celcius := target(value, ts)
+		for _, sensor := range value.Sensors {
+			targets[sensor] = celcius
+		}
+	}
+	return targets
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// update sets a schedule, or deletes it when the value is nil, once the
+// schedules have been written. It is called with the lock held
+func (this *schedule) update(name string, value *sensors.MiHomeSchedule) error {
+	schedules := make(map[string]*sensors.MiHomeSchedule, len(this.schedules)+1)
+	for key, other := range this.schedules {
+		schedules[key] = other
+	}
+	if value == nil {
+		delete(schedules, name)
+	} else {
+		schedules[name] = value
+	}
+	if err := this.write(schedules); err != nil {
+		return err
+	}
+	this.schedules = schedules
+	return nil
+}
+
+// target returns the target temperature for a schedule at a time. A boost
+// is used in preference to a holiday, and later overrides are used in
+// preference to earlier ones of the same type
+func target(value *sensors.MiHomeSchedule, ts time.Time) float64 {
+	var override *sensors.MiHomeOverridePeriod
+	for i := range value.Overrides {
+		other := &value.Overrides[i]
+		if ts.Before(other.Start) || ts.Before(other.End) == false {
+			continue
+		} else if override == nil || other.Type >= override.Type {
+			override = other
+		}
+	}
+	if override != nil {
+		return override.Celcius
+	}
+
+	// The period is the last one which started before the time in the
+	// week, or the last one in the week before
+	hour, min, sec := ts.Clock()
+	offset := time.Duration(ts.Weekday())*DAY + time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+	period := value.Periods[len(value.Periods)-1]
+	for _, other := range value.Periods {
+		if period_offset(other) <= offset {
+			period = other
+		}
+	}
+	return period.Celcius
+}
+
+// check_schedule returns an error if a schedule isn't valid
+func check_schedule(value *sensors.MiHomeSchedule) error {
+	if value == nil || value.Name == "" || len(value.Sensors) == 0 || len(value.Periods) == 0 {
+		return gopi.ErrBadParameter
+	}
+	for i, sensor := range value.Sensors {
+		if sensor == 0 || sensor&0xFFFFFF != sensor {
+			return gopi.ErrBadParameter
+		}
+		for _, other := range value.Sensors[:i] {
+			if other == sensor {
+				return gopi.ErrBadParameter
+			}
+		}
+	}
+	for i, period := range value.Periods {
+		if period.Day < time.Sunday || period.Day > time.Saturday {
+			return gopi.ErrBadParameter
+		} else if period.Start < 0 || period.Start >= DAY || period.Start%time.Minute != 0 {
+			return gopi.ErrBadParameter
+		} else if period.Celcius < CELCIUS_MIN || period.Celcius > CELCIUS_MAX {
+			return gopi.ErrBadParameter
+		}
+		for _, other := range value.Periods[:i] {
+			if period_offset(other) == period_offset(period) {
+				return gopi.ErrBadParameter
+			}
+		}
+	}
+	for _, override := range value.Overrides {
+		if err := check_override(override); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check_override returns an error if an override isn't valid
+func check_override(override sensors.MiHomeOverridePeriod) error {
+	if override.Type == sensors.MIHOME_OVERRIDE_NONE || override.Type > sensors.MIHOME_OVERRIDE_MAX {
+		return gopi.ErrBadParameter
+	} else if override.Start.IsZero() || override.End.After(override.Start) == false {
+		return gopi.ErrBadParameter
+	} else if override.Celcius < CELCIUS_MIN || override.Celcius > CELCIUS_MAX {
+		return gopi.ErrBadParameter
+	}
+	return nil
+}
+
+// sort_periods puts the periods of a schedule in order from the
+// start of the week
+func sort_periods(value *sensors.MiHomeSchedule) {
+	sort.Slice(value.Periods, func(i, j int) bool {
+		return period_offset(value.Periods[i]) < period_offset(value.Periods[j])
+	})
+}
+
+// period_offset returns the time from the start of the week
+// for a period
+func period_offset(period sensors.MiHomePeriod) time.Duration {
+	return time.Duration(period.Day)*DAY + period.Start
+}
+
+func has_sensor(value *sensors.MiHomeSchedule, sensor uint32) bool {
+	for _, other := range value.Sensors {
+		if other == sensor {
+			return true
+		}
+	}
+	return false
+}
+
+// schedule_copy returns a copy of a schedule, so that it can be used
+// without the lock held
+func schedule_copy(value *sensors.MiHomeSchedule) *sensors.MiHomeSchedule {
+	return &sensors.MiHomeSchedule{
+		Name:      value.Name,
+		Sensors:   append([]uint32(nil), value.Sensors...),
+		Periods:   append([]sensors.MiHomePeriod(nil), value.Periods...),
+		Overrides: append([]sensors.MiHomeOverridePeriod(nil), value.Overrides...),
+	}
+}
+
+// schedule_path returns the path to the schedules file, which is relative
+// to the home directory, with the default filename appended if the path
+// is a directory
+func schedule_path(path string) (string, error) {
+	if filepath.IsAbs(path) == false {
+		if homedir, err := os.UserHomeDir(); err != nil {
+			return "", err
+		} else {
+			path = filepath.Join(homedir, path)
+		}
+	}
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		path = filepath.Join(path, FILENAME_DEFAULT)
+	}
+	return path, nil
+}
@@ -0,0 +1,238 @@
+package sys_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/schedule"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+func Test_Schedule_000_open(t *testing.T) {
+	if driver, err := gopi.Open(schedule.Schedule{}, Logger(t)); err != nil {
+		t.Fatal(err)
+	} else if schedules := driver.(sensors.MiHomeScheduler).Schedules(); len(schedules) != 0 {
+		t.Error("Unexpected schedules", schedules)
+	} else if err := driver.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Schedule_001_targets(t *testing.T) {
+	driver, err := gopi.Open(schedule.Schedule{}, Logger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	scheduler := driver.(sensors.MiHomeScheduler)
+
+	// Warm on weekday mornings and all weekend, cool otherwise
+	if err := scheduler.SetSchedule(WeekdaySchedule("lounge", 0x1234, 0x1235)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2019-01-07 is a Monday
+	for _, test := range []struct {
+		ts      time.Time
+		celcius float64
+	}{
+		{time.Date(2019, 1, 7, 6, 59, 0, 0, time.Local), 21},  // Sunday period continues
+		{time.Date(2019, 1, 7, 7, 0, 0, 0, time.Local), 20},   // Monday morning
+		{time.Date(2019, 1, 7, 9, 0, 0, 0, time.Local), 16},   // Monday day
+		{time.Date(2019, 1, 11, 23, 0, 0, 0, time.Local), 16}, // Friday night
+		{time.Date(2019, 1, 12, 8, 0, 0, 0, time.Local), 21},  // Saturday
+		{time.Date(2019, 1, 13, 23, 0, 0, 0, time.Local), 21}, // Sunday, which wraps to Monday
+	} {
+		targets := scheduler.Targets(test.ts)
+		if len(targets) != 2 {
+			t.Error("Expected two targets, got", targets)
+		} else if targets[0x1234] != test.celcius || targets[0x1235] != test.celcius {
+			t.Errorf("At %v, expected %v, got %v", test.ts, test.celcius, targets)
+		}
+	}
+}
+
+func Test_Schedule_002_overrides(t *testing.T) {
+	driver, err := gopi.Open(schedule.Schedule{}, Logger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	scheduler := driver.(sensors.MiHomeScheduler)
+	if err := scheduler.SetSchedule(WeekdaySchedule("lounge", 0x1234)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A holiday, with a boost in the middle
+	now := time.Now()
+	if err := scheduler.SetOverride("lounge", sensors.MiHomeOverridePeriod{
+		Type: sensors.MIHOME_OVERRIDE_HOLIDAY, Start: now, End: now.Add(48 * time.Hour), Celcius: 10,
+	}); err != nil {
+		t.Fatal(err)
+	} else if err := scheduler.SetOverride("lounge", sensors.MiHomeOverridePeriod{
+		Type: sensors.MIHOME_OVERRIDE_BOOST, Start: now.Add(time.Hour), End: now.Add(2 * time.Hour), Celcius: 25,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if celcius := scheduler.Targets(now)[0x1234]; celcius != 10 {
+		t.Error("Expected holiday, got", celcius)
+	} else if celcius := scheduler.Targets(now.Add(90 * time.Minute))[0x1234]; celcius != 25 {
+		t.Error("Expected boost, got", celcius)
+	} else if celcius := scheduler.Targets(now.Add(3 * time.Hour))[0x1234]; celcius != 10 {
+		t.Error("Expected holiday, got", celcius)
+	}
+
+	// Clearing overrides returns to the timetable
+	if err := scheduler.ClearOverrides("lounge"); err != nil {
+		t.Error(err)
+	} else if celcius := scheduler.Targets(now)[0x1234]; celcius == 10 {
+		t.Error("Unexpected holiday")
+	}
+
+	// Invalid overrides, and schedules which don't exist
+	if err := scheduler.SetOverride("lounge", sensors.MiHomeOverridePeriod{
+		Type: sensors.MIHOME_OVERRIDE_BOOST, Start: now, End: now, Celcius: 25,
+	}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := scheduler.SetOverride("lounge", sensors.MiHomeOverridePeriod{
+		Start: now, End: now.Add(time.Hour), Celcius: 25,
+	}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := scheduler.ClearOverrides("kitchen"); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	} else if err := scheduler.DeleteSchedule("kitchen"); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+}
+
+func Test_Schedule_003_invalid(t *testing.T) {
+	driver, err := gopi.Open(schedule.Schedule{}, Logger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	scheduler := driver.(sensors.MiHomeScheduler)
+
+	// A sensor can only be in one schedule
+	if err := scheduler.SetSchedule(WeekdaySchedule("lounge", 0x1234)); err != nil {
+		t.Fatal(err)
+	} else if err := scheduler.SetSchedule(WeekdaySchedule("kitchen", 0x1234)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := scheduler.SetSchedule(WeekdaySchedule("lounge", 0x1234, 0x1235)); err != nil {
+		t.Error(err)
+	}
+
+	// Invalid schedules
+	for _, value := range []*sensors.MiHomeSchedule{
+		nil,
+		{Name: "kitchen", Periods: []sensors.MiHomePeriod{{Day: time.Monday, Start: 0, Celcius: 20}}},
+		{Name: "kitchen", Sensors: []uint32{0x1236}},
+		{Name: "kitchen", Sensors: []uint32{0x1000000}, Periods: []sensors.MiHomePeriod{{Day: time.Monday, Start: 0, Celcius: 20}}},
+		{Name: "kitchen", Sensors: []uint32{0x1236}, Periods: []sensors.MiHomePeriod{{Day: time.Monday, Start: 24 * time.Hour, Celcius: 20}}},
+		{Name: "kitchen", Sensors: []uint32{0x1236}, Periods: []sensors.MiHomePeriod{{Day: time.Monday, Start: 0, Celcius: 31}}},
+		{Name: "kitchen", Sensors: []uint32{0x1236}, Periods: []sensors.MiHomePeriod{{Day: time.Monday, Start: 0, Celcius: 20}, {Day: time.Monday, Start: 0, Celcius: 18}}},
+	} {
+		if err := scheduler.SetSchedule(value); err != gopi.ErrBadParameter {
+			t.Error("Expected ErrBadParameter, got", err, "for", value)
+		}
+	}
+	if schedules := scheduler.Schedules(); len(schedules) != 1 || schedules[0].Name != "lounge" {
+		t.Error("Unexpected schedules", schedules)
+	} else if err := scheduler.DeleteSchedule("lounge"); err != nil {
+		t.Error(err)
+	} else if schedules := scheduler.Schedules(); len(schedules) != 0 {
+		t.Error("Unexpected schedules", schedules)
+	}
+}
+
+func Test_Schedule_004_persist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write schedules, then read them back
+	now := time.Now().Round(time.Second)
+	override := sensors.MiHomeOverridePeriod{Type: sensors.MIHOME_OVERRIDE_BOOST, Start: now, End: now.Add(time.Hour), Celcius: 25}
+	if driver, err := gopi.Open(schedule.Schedule{Path: dir}, Logger(t)); err != nil {
+		t.Fatal(err)
+	} else if err := driver.(sensors.MiHomeScheduler).SetSchedule(WeekdaySchedule("lounge", 0x1234)); err != nil {
+		t.Fatal(err)
+	} else if err := driver.(sensors.MiHomeScheduler).SetSchedule(WeekdaySchedule("kitchen", 0x1235)); err != nil {
+		t.Fatal(err)
+	} else if err := driver.(sensors.MiHomeScheduler).SetOverride("kitchen", override); err != nil {
+		t.Fatal(err)
+	} else if err := driver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Schedules are written in name order
+	if data, err := ioutil.ReadFile(filepath.Join(dir, schedule.FILENAME_DEFAULT)); err != nil {
+		t.Fatal(err)
+	} else if kitchen, lounge := bytes.Index(data, []byte(`"kitchen"`)), bytes.Index(data, []byte(`"lounge"`)); kitchen < 0 || lounge < kitchen {
+		t.Error("Unexpected order", string(data))
+	}
+	driver, err := gopi.Open(schedule.Schedule{Path: dir}, Logger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	schedules := driver.(sensors.MiHomeScheduler).Schedules()
+	if len(schedules) != 2 || schedules[0].Name != "kitchen" || schedules[1].Name != "lounge" {
+		t.Fatal("Unexpected schedules", schedules)
+	}
+	expected := WeekdaySchedule("lounge", 0x1234)
+	if len(schedules[1].Periods) != len(expected.Periods) {
+		t.Error("Unexpected periods", schedules[1].Periods)
+	} else {
+		for i := range expected.Periods {
+			if schedules[1].Periods[i] != expected.Periods[i] {
+				t.Error("Unexpected period", schedules[1].Periods[i])
+			}
+		}
+	}
+	if overrides := schedules[0].Overrides; len(overrides) != 1 {
+		t.Error("Unexpected overrides", overrides)
+	} else if overrides[0].Type != override.Type || overrides[0].Celcius != override.Celcius {
+		t.Error("Unexpected override", overrides[0])
+	} else if overrides[0].Start.Equal(override.Start) == false || overrides[0].End.Equal(override.End) == false {
+		t.Error("Unexpected override", overrides[0])
+	}
+
+	// Invalid files are not read
+	path := filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(path, []byte(`{ "schedules": [ { "name": "lounge", "sensors": [ 1 ], "periods": [ { "day": "someday", "start": "07:00", "celcius": 20 } ] } ] }`), 0644); err != nil {
+		t.Fatal(err)
+	} else if _, err := gopi.Open(schedule.Schedule{Path: path}, Logger(t)); err == nil {
+		t.Error("Expected error reading invalid file")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SCHEDULES
+
+// WeekdaySchedule returns a schedule which is warm on weekday mornings and
+// evenings and all weekend, with the periods in order from Sunday
+func WeekdaySchedule(name string, sensors_ ...uint32) *sensors.MiHomeSchedule {
+	value := &sensors.MiHomeSchedule{Name: name, Sensors: sensors_}
+	value.Periods = append(value.Periods, sensors.MiHomePeriod{Day: time.Sunday, Start: 8 * time.Hour, Celcius: 21})
+	for day := time.Monday; day <= time.Friday; day++ {
+		value.Periods = append(value.Periods,
+			sensors.MiHomePeriod{Day: day, Start: 7 * time.Hour, Celcius: 20},
+			sensors.MiHomePeriod{Day: day, Start: 8*time.Hour + 30*time.Minute, Celcius: 16},
+			sensors.MiHomePeriod{Day: day, Start: 17 * time.Hour, Celcius: 20},
+			sensors.MiHomePeriod{Day: day, Start: 22 * time.Hour, Celcius: 16},
+		)
+	}
+	value.Periods = append(value.Periods, sensors.MiHomePeriod{Day: time.Saturday, Start: 8 * time.Hour, Celcius: 21})
+	return value
+}
@@ -111,6 +111,24 @@ func (this *influxdb) Write(sensor sensors.Sensor, message sensors.Message) erro
 		if err := this.client.Write(batch); err != nil {
 			return err
 		}
+	} else if message_, ok := message.(sensors.OregonMessage); ok {
+		if point, err := this.PointForOregonMessage(sensor, message_); err != nil {
+			return err
+		} else {
+			batch.AddPoint(point)
+		}
+		if err := this.client.Write(batch); err != nil {
+			return err
+		}
+	} else if message_, ok := message.(sensors.ProtoMessage); ok {
+		if point, err := this.PointForProtoMessage(sensor, message_); err != nil {
+			return err
+		} else {
+			batch.AddPoint(point)
+		}
+		if err := this.client.Write(batch); err != nil {
+			return err
+		}
 	} else {
 		return fmt.Errorf("Don't know how to generate data for: %v", message.Name())
 	}
@@ -139,8 +157,71 @@ func (this *influxdb) PointForOTMessage(sensor sensors.Sensor, message sensors.O
 		tags["source"] = src.Addr()
 	}
 
-	// Set fields
-	for _, record := range message.Records() {
+	// Set fields from records and signal
+	set_record_fields(fields, message.Records())
+	set_signal_fields(fields, message.Signal())
+
+	// Return point
+	return influx.NewPoint(message.Name(), tags, fields, message.Timestamp())
+}
+
+func (this *influxdb) PointForOregonMessage(sensor sensors.Sensor, message sensors.OregonMessage) (*influx.Point, error) {
+	// Check parameters
+	if sensor == nil || message == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Create point
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	tags["data"] = strings.ToUpper(hex.EncodeToString(message.Data()))
+	tags["sensor_type"] = strings.TrimPrefix(fmt.Sprint(message.SensorType()), "OREGON_SENSOR_")
+	tags["channel"] = fmt.Sprint(message.Channel())
+	tags["code"] = fmt.Sprintf("0x%02X", message.RollingCode())
+	tags["ns"] = sensor.Namespace()
+	tags["key"] = sensor.Key()
+	tags["description"] = sensor.Description()
+
+	// Set fields from records, battery flag and signal
+	set_record_fields(fields, message.Records())
+	fields["battery_low"] = message.BatteryLow()
+	set_signal_fields(fields, message.Signal())
+
+	// Return point
+	return influx.NewPoint(message.Name(), tags, fields, message.Timestamp())
+}
+
+func (this *influxdb) PointForProtoMessage(sensor sensors.Sensor, message sensors.ProtoMessage) (*influx.Point, error) {
+	// Check parameters
+	if sensor == nil || message == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Create point
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	tags["data"] = strings.ToUpper(hex.EncodeToString(message.Data()))
+	tags["protocol"] = message.Protocol()
+	tags["product"] = fmt.Sprintf("0x%02X", message.Product())
+	tags["sensor"] = fmt.Sprintf("0x%06X", message.Sensor())
+	tags["ns"] = sensor.Namespace()
+	tags["key"] = sensor.Key()
+	tags["description"] = sensor.Description()
+
+	// Set fields from records and signal
+	set_record_fields(fields, message.Records())
+	set_signal_fields(fields, message.Signal())
+
+	// Return point
+	return influx.NewPoint(message.Name(), tags, fields, message.Timestamp())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// set_record_fields sets a field for each record, named after the parameter
+func set_record_fields(fields map[string]interface{}, records []sensors.OTRecord) {
+	for _, record := range records {
 		name := strings.ToLower(strings.TrimPrefix(fmt.Sprint(record.Name()), "OT_PARAM_"))
 		// Convert integers and unsigned integers into floats
 		v := record.Value()
@@ -169,7 +250,14 @@ func (this *influxdb) PointForOTMessage(sensor sensors.Sensor, message sensors.O
 			fields[name] = v
 		}
 	}
+}
 
-	// Return point
-	return influx.NewPoint(message.Name(), tags, fields, message.Timestamp())
+// set_signal_fields sets the signal fields for received messages
+func set_signal_fields(fields map[string]interface{}, signal *sensors.RFMSignal) {
+	if signal != nil {
+		fields["signal_rssi"] = float64(signal.RSSI)
+		fields["signal_afc"] = signal.AFC
+		fields["signal_fei"] = signal.FEI
+		fields["signal_lna_gain"] = float64(signal.LNAGain)
+	}
 }
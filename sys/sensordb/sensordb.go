@@ -160,6 +160,13 @@ func decode_sensor(message sensors.Message) (string, string, string, error) {
 			product = strings.TrimPrefix(product, "MIHOME_PRODUCT_")
 		}
 		return message_.Name(), fmt.Sprintf("%02X:%06X", message_.Product(), message_.Sensor()), product, nil
+	} else if message_, ok := message.(sensors.OregonMessage); ok {
+		// Key is the channel, and the sensor type with the rolling code
+		sensor_type := strings.TrimPrefix(fmt.Sprint(message_.SensorType()), "OREGON_SENSOR_")
+		return message_.Name(), fmt.Sprintf("%02X:%06X", message_.Channel(), uint32(message_.SensorType())<<8|uint32(message_.RollingCode())), sensor_type, nil
+	} else if message_, ok := message.(sensors.ProtoMessage); ok {
+		// Key is the product and sensor, and the description is the protocol
+		return message_.Name(), fmt.Sprintf("%02X:%06X", message_.Product(), message_.Sensor()), message_.Protocol(), nil
 	} else {
 		return "", "", "", sensors.ErrUnexpectedResponse
 	}
@@ -0,0 +1,57 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package transport
+
+import (
+	"fmt"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register transport using the radio
+	gopi.RegisterModule(gopi.Module{
+		Name:     "sensors/transport",
+		Requires: []string{"sensors/rfm69/spi"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("transport.addr", 0, "Node address")
+			config.AppFlags.FlagUint("transport.broadcast", TRANSPORT_BROADCAST, "Broadcast address")
+			config.AppFlags.FlagUint("transport.retries", TRANSPORT_RETRIES, "Number of retransmissions")
+			config.AppFlags.FlagDuration("transport.timeout", TRANSPORT_TIMEOUT, "Acknowledgement timeout, which doubles on each retransmission")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			addr, _ := app.AppFlags.GetUint("transport.addr")
+			broadcast, _ := app.AppFlags.GetUint("transport.broadcast")
+			retries, _ := app.AppFlags.GetUint("transport.retries")
+			timeout, _ := app.AppFlags.GetDuration("transport.timeout")
+			if radio, ok := app.ModuleInstance("sensors/rfm69/spi").(sensors.RFM69); !ok {
+				return nil, fmt.Errorf("Missing or invalid Radio module")
+			} else if addr == 0 || addr > 0xFF {
+				return nil, fmt.Errorf("Invalid or missing -transport.addr flag")
+			} else if broadcast > 0xFF {
+				return nil, fmt.Errorf("Invalid -transport.broadcast flag")
+			} else {
+				return gopi.Open(Transport{
+					Radio:     radio,
+					Address:   uint8(addr),
+					Broadcast: uint8(broadcast),
+					Retries:   retries,
+					Timeout:   timeout,
+				}, app.Logger)
+			}
+		},
+	})
+}
@@ -0,0 +1,148 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package transport
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/sensors"
+)
+
+/*
+ Each frame is the length byte, the destination address (which is
+ filtered by the radio), the source address, flags, a sequence number
+ and the data. An acknowledgement has the sequence number of the frame
+ acknowledged and no data
+*/
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+// run transmits queued frames and receives frames until stopped. The
+// radio is kept in RX mode except when transmitting
+func (this *transport) run() {
+	defer this.wait.Done()
+	for {
+		select {
+		case <-this.stop:
+			return
+		case f := <-this.tx:
+			f.done <- this.send(f)
+		default:
+			this.recv()
+		}
+	}
+}
+
+// send transmits a frame and returns to RX mode
+func (this *transport) send(f *frame) error {
+	this.log.Debug2("<sensors.Transport>send{ frame=%v }", f)
+	if err := this.radio.SetMode(sensors.RFM_MODE_TX); err != nil {
+		return err
+	} else if err := this.radio.WritePayload(encode(f), 0, 0); err != nil {
+		this.radio.SetMode(sensors.RFM_MODE_RX)
+		return err
+	} else if err := this.radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		return err
+	}
+
+	// Success
+	return nil
+}
+
+// recv waits for a frame for the poll interval, then acknowledges
+// and queues data frames and signals senders waiting for
+// acknowledgements
+func (this *transport) recv() {
+	ctx, cancel := context.WithTimeout(context.Background(), TRANSPORT_POLL_INTERVAL)
+	defer cancel()
+	payload, crc_ok, _, err := this.radio.ReadPayload(ctx)
+	if err != nil {
+		this.log.Error("<sensors.Transport>recv: %v", err)
+		return
+	} else if payload == nil {
+		return
+	} else if crc_ok == false {
+		this.log.Debug("<sensors.Transport>recv: Ignoring payload with CRC error")
+		return
+	}
+
+	// Decode the frame and ignore frames for other nodes
+	f := decode(payload)
+	if f == nil {
+		this.log.Debug("<sensors.Transport>recv: Ignoring payload=%v", strings.ToUpper(hex.EncodeToString(payload)))
+		return
+	} else if f.dest != this.address && f.dest != this.broadcast {
+		return
+	}
+	this.log.Debug2("<sensors.Transport>recv{ frame=%v }", f)
+
+	// Signal acknowledgements
+	if f.flags&TRANSPORT_FLAG_ACK != 0 {
+		this.acknowledged(f.src, f.seq)
+		return
+	}
+
+	// Queue data which isn't a duplicate, or drop it without
+	// acknowledgement if the queue is full so that it's sent again
+	if this.is_duplicate(f.src, f.seq) {
+		this.log.Debug("<sensors.Transport>recv: Duplicate from 0x%02X seq=%v", f.src, f.seq)
+	} else {
+		select {
+		case this.rx <- f:
+			this.set_received(f.src, f.seq)
+		default:
+			this.log.Warn("<sensors.Transport>recv: Receive queue is full, dropping frame from 0x%02X", f.src)
+			return
+		}
+	}
+
+	// Acknowledge, including duplicates since the previous
+	// acknowledgement may have been lost
+	if f.flags&TRANSPORT_FLAG_ACK_REQ != 0 && f.dest == this.address {
+		if err := this.send(&frame{dest: f.src, src: this.address, flags: TRANSPORT_FLAG_ACK, seq: f.seq}); err != nil {
+			this.log.Error("<sensors.Transport>recv: %v", err)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE AND DECODE
+
+func encode(f *frame) []byte {
+	payload := make([]byte, 0, TRANSPORT_HEADER+len(f.data)+1)
+	payload = append(payload, uint8(TRANSPORT_HEADER+len(f.data)), f.dest, f.src, f.flags, f.seq)
+	return append(payload, f.data...)
+}
+
+// decode returns a frame, or nil if the payload is not a frame
+func decode(payload []byte) *frame {
+	if len(payload) < TRANSPORT_HEADER+1 || int(payload[0]) != len(payload)-1 {
+		return nil
+	}
+	f := &frame{
+		dest:  payload[1],
+		src:   payload[2],
+		flags: payload[3],
+		seq:   payload[4],
+	}
+	if len(payload) > TRANSPORT_HEADER+1 {
+		f.data = append([]byte(nil), payload[TRANSPORT_HEADER+1:]...)
+	}
+	if f.flags&TRANSPORT_FLAG_ACK != 0 && f.data != nil {
+		return nil
+	} else if f.flags&TRANSPORT_FLAG_ACK == 0 && f.data == nil {
+		return nil
+	}
+	return f
+}
@@ -0,0 +1,363 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2018
+	All Rights Reserved
+
+    Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package transport
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/ener314rt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Transport is the configuration for reliable messaging between
+// nodes using a radio in packet mode
+type Transport struct {
+	Radio     sensors.RFM69 // Radio, which is used only by the transport
+	Address   uint8         // Node address, which cannot be zero
+	Broadcast uint8         // Broadcast address
+	Retries   uint          // Number of retransmissions, or zero for the default
+	Timeout   time.Duration // Acknowledgement timeout, or zero for the default
+}
+
+type transport struct {
+	log       gopi.Logger
+	radio     sensors.RFM69
+	address   uint8
+	broadcast uint8
+	retries   uint
+	timeout   time.Duration
+
+	// Sequence numbers for each destination, acknowledgements
+	// waited for, and the last sequence number received from each
+	// source for duplicate suppression
+	seq      map[uint8]uint8
+	pending  map[uint16]chan struct{}
+	received map[uint8]*received
+
+	// Frames to transmit, received data and background task
+	tx     chan *frame
+	rx     chan *frame
+	stop   chan struct{}
+	wait   sync.WaitGroup
+	closed bool
+
+	sync.Mutex
+}
+
+type frame struct {
+	dest  uint8
+	src   uint8
+	flags uint8
+	seq   uint8
+	data  []byte
+	done  chan error
+}
+
+type received struct {
+	seq uint8
+	ts  time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	TRANSPORT_BROADCAST = 0xFF
+	TRANSPORT_RETRIES   = 3
+	TRANSPORT_TIMEOUT   = 500 * time.Millisecond
+
+	// Maximum size of a frame after the length byte, and the maximum
+	// size of the data in a frame after the header
+	TRANSPORT_FRAME_MAX  = 0x40
+	TRANSPORT_HEADER     = 4
+	TRANSPORT_DATA_MAX   = TRANSPORT_FRAME_MAX - TRANSPORT_HEADER
+	TRANSPORT_QUEUE_SIZE = 16
+
+	// Interval between checking for frames to transmit while receiving,
+	// and the time after which a repeated sequence number from a node
+	// is no longer a duplicate
+	TRANSPORT_POLL_INTERVAL    = 20 * time.Millisecond
+	TRANSPORT_DUPLICATE_WINDOW = 30 * time.Second
+)
+
+const (
+	// Frame flags
+	TRANSPORT_FLAG_ACK     uint8 = 0x80 // Frame is an acknowledgement
+	TRANSPORT_FLAG_ACK_REQ uint8 = 0x40 // Frame is to be acknowledged
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Transport) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sensors.Transport>Open{ address=0x%02X broadcast=0x%02X retries=%v timeout=%v }", config.Address, config.Broadcast, config.Retries, config.Timeout)
+
+	// Check parameters
+	if config.Radio == nil || config.Address == 0 || config.Address == config.Broadcast {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(transport)
+	this.log = log
+	this.radio = config.Radio
+	this.address = config.Address
+	this.broadcast = config.Broadcast
+	this.retries = config.Retries
+	this.timeout = config.Timeout
+	if this.retries == 0 {
+		this.retries = TRANSPORT_RETRIES
+	}
+	if this.timeout == 0 {
+		this.timeout = TRANSPORT_TIMEOUT
+	}
+	this.seq = make(map[uint8]uint8)
+	this.pending = make(map[uint16]chan struct{})
+	this.received = make(map[uint8]*received)
+	this.tx = make(chan *frame, TRANSPORT_QUEUE_SIZE)
+	this.rx = make(chan *frame, TRANSPORT_QUEUE_SIZE)
+	this.stop = make(chan struct{})
+
+	// The radio uses the monitor mode packet settings, with hardware
+	// address filtering and CRC
+	profile := ener314rt.PROFILE_MONITOR
+	profile.Name = "transport"
+	profile.PacketFilter = sensors.RFM_PACKET_FILTER_BROADCAST
+	profile.PacketCRC = sensors.RFM_PACKET_CRC_AUTOCLEAR_ON
+	profile.PayloadSize = TRANSPORT_FRAME_MAX
+	profile.NodeAddress = this.address
+	profile.BroadcastAddress = this.broadcast
+	if err := this.radio.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+		return nil, err
+	} else if err := this.radio.ApplyProfile(profile); err != nil {
+		return nil, err
+	} else if err := this.radio.SetMode(sensors.RFM_MODE_RX); err != nil {
+		return nil, err
+	}
+
+	// Start background task which transmits and receives frames
+	this.wait.Add(1)
+	go this.run()
+
+	// Return success
+	return this, nil
+}
+
+func (this *transport) Close() error {
+	this.log.Debug("<sensors.Transport>Close{ }")
+
+	// Stop background task, once only
+	this.Lock()
+	if this.closed {
+		this.Unlock()
+		return nil
+	}
+	this.closed = true
+	close(this.stop)
+	this.Unlock()
+	this.wait.Wait()
+
+	// Put the radio into standby
+	if err := this.radio.SetMode(sensors.RFM_MODE_STDBY); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.radio = nil
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *transport) String() string {
+	return fmt.Sprintf("<sensors.Transport>{ address=0x%02X broadcast=0x%02X retries=%v timeout=%v }", this.address, this.broadcast, this.retries, this.timeout)
+}
+
+func (this *frame) String() string {
+	params := fmt.Sprintf("dest=0x%02X src=0x%02X seq=%v", this.dest, this.src, this.seq)
+	if this.flags&TRANSPORT_FLAG_ACK != 0 {
+		params += " ack=true"
+	}
+	if this.flags&TRANSPORT_FLAG_ACK_REQ != 0 {
+		params += " ack_req=true"
+	}
+	params += " data=" + strings.ToUpper(hex.EncodeToString(this.data))
+	return fmt.Sprintf("<sensors.Transport.Frame>{ %v }", params)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SEND AND RECEIVE
+
+func (this *transport) Address() uint8 {
+	return this.address
+}
+
+func (this *transport) Send(ctx context.Context, node uint8, data []byte) error {
+	this.log.Debug2("<sensors.Transport>Send{ node=0x%02X data=%v }", node, strings.ToUpper(hex.EncodeToString(data)))
+
+	// Check parameters
+	if len(data) == 0 || len(data) > TRANSPORT_DATA_MAX || node == this.address {
+		return gopi.ErrBadParameter
+	} else if this.is_closed() {
+		return gopi.ErrOutOfOrder
+	}
+
+	// Broadcast frames are sent once and not acknowledged
+	if node == this.broadcast {
+		return this.transmit(ctx, &frame{
+			dest: node, src: this.address, seq: this.next_seq(node), data: data,
+		})
+	}
+
+	// Wait for the acknowledgement for the sequence number
+	f := &frame{
+		dest: node, src: this.address, flags: TRANSPORT_FLAG_ACK_REQ, seq: this.next_seq(node), data: data,
+	}
+	ack := this.expect(f.dest, f.seq)
+	defer this.unexpect(f.dest, f.seq)
+
+	// Retransmit with backoff until acknowledged
+	timeout := this.timeout
+	for attempt := uint(0); attempt <= this.retries; attempt++ {
+		if err := this.transmit(ctx, f); err != nil {
+			return err
+		}
+		timer := time.NewTimer(timeout + time.Duration(rand.Int63n(int64(timeout/2)+1)))
+		select {
+		case <-ack:
+			timer.Stop()
+			return nil
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-this.stop:
+			timer.Stop()
+			return gopi.ErrOutOfOrder
+		case <-timer.C:
+			this.log.Debug("<sensors.Transport>Send: No acknowledgement from 0x%02X (attempt %v)", node, attempt+1)
+			timeout *= 2
+		}
+	}
+
+	// Retries exhausted
+	return sensors.ErrDeviceTimeout
+}
+
+func (this *transport) Receive(ctx context.Context) (uint8, []byte, error) {
+	if this.is_closed() {
+		return 0, nil, gopi.ErrOutOfOrder
+	}
+	select {
+	case f := <-this.rx:
+		return f.src, f.data, nil
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	case <-this.stop:
+		return 0, nil, gopi.ErrOutOfOrder
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// transmit queues a frame and waits until it has been sent
+func (this *transport) transmit(ctx context.Context, f *frame) error {
+	f.done = make(chan error, 1)
+	select {
+	case this.tx <- f:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-this.stop:
+		return gopi.ErrOutOfOrder
+	}
+	select {
+	case err := <-f.done:
+		return err
+	case <-this.stop:
+		return gopi.ErrOutOfOrder
+	}
+}
+
+// is_closed returns true after the transport has been closed
+func (this *transport) is_closed() bool {
+	this.Lock()
+	defer this.Unlock()
+	return this.closed
+}
+
+// next_seq returns the next sequence number for a destination, which
+// starts at a random value so that a restarted node isn't mistaken
+// for a duplicate
+func (this *transport) next_seq(node uint8) uint8 {
+	this.Lock()
+	defer this.Unlock()
+	seq, exists := this.seq[node]
+	if exists == false {
+		seq = uint8(rand.Intn(0x100))
+	}
+	this.seq[node] = seq + 1
+	return seq
+}
+
+// expect returns a channel which is signalled when an acknowledgement
+// is received
+func (this *transport) expect(node, seq uint8) <-chan struct{} {
+	this.Lock()
+	defer this.Unlock()
+	ack := make(chan struct{}, 1)
+	this.pending[uint16(node)<<8|uint16(seq)] = ack
+	return ack
+}
+
+func (this *transport) unexpect(node, seq uint8) {
+	this.Lock()
+	defer this.Unlock()
+	delete(this.pending, uint16(node)<<8|uint16(seq))
+}
+
+// acknowledged signals the sender waiting for an acknowledgement
+func (this *transport) acknowledged(node, seq uint8) {
+	this.Lock()
+	defer this.Unlock()
+	if ack, exists := this.pending[uint16(node)<<8|uint16(seq)]; exists {
+		select {
+		case ack <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// is_duplicate returns true if the sequence number was the last one
+// received from a node
+func (this *transport) is_duplicate(node, seq uint8) bool {
+	this.Lock()
+	defer this.Unlock()
+	last, exists := this.received[node]
+	return exists && last.seq == seq && time.Since(last.ts) < TRANSPORT_DUPLICATE_WINDOW
+}
+
+func (this *transport) set_received(node, seq uint8) {
+	this.Lock()
+	defer this.Unlock()
+	this.received[node] = &received{seq, time.Now()}
+}
@@ -0,0 +1,211 @@
+package sys_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/sensors"
+	"github.com/djthorpe/sensors/sys/rfm69sim"
+	"github.com/djthorpe/sensors/sys/transport"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+func Test_Transport_000_open(t *testing.T) {
+	_, radio := RFM69(t, rfm69sim.RFM69{})
+	if radio == nil {
+		t.Fatal("Missing RFM69")
+	} else if _, err := gopi.Open(transport.Transport{Radio: radio}, Logger(t)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := gopi.Open(transport.Transport{Radio: radio, Address: 0xFF, Broadcast: 0xFF}, Logger(t)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if node, err := gopi.Open(transport.Transport{Radio: radio, Address: 0x01, Broadcast: 0xFF}, Logger(t)); err != nil {
+		t.Fatal(err)
+	} else if radio.NodeAddress() != 0x01 || radio.PacketFilter() != sensors.RFM_PACKET_FILTER_BROADCAST {
+		t.Error("Unexpected profile", radio.Profile())
+	} else if err := node.(sensors.RFMTransport).Send(context.Background(), 0x01, []byte{0x00}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := node.(sensors.RFMTransport).Send(context.Background(), 0x02, make([]byte, transport.TRANSPORT_DATA_MAX+1)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := node.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Transport_001_send(t *testing.T) {
+	link := Link(t, nil)
+	defer link.Close()
+	a, b := link.nodes[0], link.nodes[1]
+
+	// Send in both directions
+	for i := 0; i < 3; i++ {
+		data := []byte{0x10, byte(i)}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := a.Send(ctx, b.Address(), data); err != nil {
+			t.Fatal(err)
+		} else if src, received, err := b.Receive(ctx); err != nil {
+			t.Fatal(err)
+		} else if src != a.Address() || Equals(received, data) == false {
+			t.Errorf("Unexpected data from 0x%02X: %v", src, received)
+		} else if err := b.Send(ctx, a.Address(), received); err != nil {
+			t.Fatal(err)
+		} else if src, received, err := a.Receive(ctx); err != nil {
+			t.Fatal(err)
+		} else if src != b.Address() || Equals(received, data) == false {
+			t.Errorf("Unexpected data from 0x%02X: %v", src, received)
+		}
+	}
+}
+
+func Test_Transport_002_retry(t *testing.T) {
+	// Lose the first two data frames and the first acknowledgement
+	var lock sync.Mutex
+	lost_data, lost_ack := 0, 0
+	link := Link(t, func(data []byte) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		if data[3]&transport.TRANSPORT_FLAG_ACK == 0 && lost_data < 2 {
+			lost_data++
+			return true
+		} else if data[3]&transport.TRANSPORT_FLAG_ACK != 0 && lost_ack < 1 {
+			lost_ack++
+			return true
+		}
+		return false
+	})
+	defer link.Close()
+	a, b := link.nodes[0], link.nodes[1]
+
+	// Data is received once, although sent four times
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.Send(ctx, b.Address(), []byte("hello")); err != nil {
+		t.Fatal(err)
+	} else if _, data, err := b.Receive(ctx); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "hello" {
+		t.Error("Unexpected data", data)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel2()
+	if _, data, err := b.Receive(ctx2); err != context.DeadlineExceeded {
+		t.Error("Expected no duplicate, got", data, err)
+	}
+}
+
+func Test_Transport_003_timeout(t *testing.T) {
+	link := Link(t, nil)
+	defer link.Close()
+	a, b := link.nodes[0], link.nodes[1]
+
+	// Nothing at address 0x03, so the retries are exhausted
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.Send(ctx, 0x03, []byte{0x01}); err != sensors.ErrDeviceTimeout {
+		t.Error("Expected ErrDeviceTimeout, got", err)
+	}
+
+	// The context finishes before the retries are exhausted
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	if err := a.Send(ctx2, 0x03, []byte{0x01}); err != context.DeadlineExceeded {
+		t.Error("Expected DeadlineExceeded, got", err)
+	}
+
+	// Broadcast is received without acknowledgement
+	if err := a.Send(ctx, 0xFF, []byte{0x02}); err != nil {
+		t.Error(err)
+	} else if src, data, err := b.Receive(ctx); err != nil {
+		t.Error(err)
+	} else if src != a.Address() || Equals(data, []byte{0x02}) == false {
+		t.Errorf("Unexpected data from 0x%02X: %v", src, data)
+	}
+}
+
+func Test_Transport_004_close(t *testing.T) {
+	_, radio := RFM69(t, rfm69sim.RFM69{})
+	node, err := gopi.Open(transport.Transport{Radio: radio, Address: 0x01, Broadcast: 0xFF}, Logger(t))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := node.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing again is ignored, and sending or receiving is out of order
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := node.Close(); err != nil {
+		t.Error(err)
+	} else if err := node.(sensors.RFMTransport).Send(ctx, 0x02, []byte{0x01}); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	} else if _, _, err := node.(sensors.RFMTransport).Receive(ctx); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LINK
+
+// link connects two transports through simulated radios, delivering
+// packets transmitted by one radio to the other
+type link struct {
+	sims  []rfm69sim.Simulator
+	nodes []sensors.RFMTransport
+	lose  func([]byte) bool
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Link returns two transports at addresses 0x01 and 0x02, where the
+// lose function returns true for packets which are lost
+func Link(t *testing.T, lose func([]byte) bool) *link {
+	this := &link{lose: lose, stop: make(chan struct{}), done: make(chan struct{})}
+	for addr := uint8(1); addr <= 2; addr++ {
+		sim, radio := RFM69(t, rfm69sim.RFM69{Gap: time.Millisecond})
+		if node, err := gopi.Open(transport.Transport{
+			Radio:     radio,
+			Address:   addr,
+			Broadcast: 0xFF,
+			Retries:   3,
+			Timeout:   50 * time.Millisecond,
+		}, Logger(t)); err != nil {
+			t.Fatal(err)
+		} else {
+			this.sims = append(this.sims, sim)
+			this.nodes = append(this.nodes, node.(sensors.RFMTransport))
+		}
+	}
+	go func() {
+		defer close(this.done)
+		for {
+			select {
+			case <-this.stop:
+				return
+			case <-time.After(time.Millisecond):
+				for i, sim := range this.sims {
+					for _, data := range sim.Transmitted() {
+						if this.lose != nil && this.lose(data) {
+							continue
+						}
+						this.sims[1-i].Inject(data, -60, true)
+					}
+				}
+			}
+		}
+	}()
+	return this
+}
+
+func (this *link) Close() {
+	for _, node := range this.nodes {
+		node.Close()
+	}
+	close(this.stop)
+	<-this.done
+}